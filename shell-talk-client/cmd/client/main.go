@@ -1,8 +1,9 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"shell-talk-client/internal/config"
@@ -13,6 +14,9 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// authTimeout is how long to wait for the server to answer a login.
+const authTimeout = 10 * time.Second
+
 func main() {
 	var rootCmd = &cobra.Command{
 		Use:   "shell-talk-client",
@@ -29,63 +33,90 @@ func main() {
 }
 
 func runClient(cmd *cobra.Command, args []string) {
-	serverURL := config.Cfg.Server.URL
-	netClient := network.NewClient()
+	serverCfg := config.Cfg.Server
+	netClient := network.NewClient(network.Heartbeat{
+		PingInterval:   serverCfg.PingInterval,
+		PongWait:       serverCfg.PongWait,
+		WriteWait:      serverCfg.WriteWait,
+		MaxMessageSize: serverCfg.MaxMessageSize,
+	})
+	console := netClient.Console
+	defer console.Close()
+	log.SetOutput(console)
 
-	if err := netClient.Connect(serverURL); err != nil {
+	if err := netClient.Connect(serverCfg.URL); err != nil {
+		console.Close()
 		log.Fatalf("Failed to connect to server: %v", err)
 	}
 
-	// Start the authentication flow
-	authenticated := false
-	go func() {
-		for auth := range netClient.AuthCh {
-			if auth {
-				authenticated = true
-				// Once authenticated, the main input handler will take over
-				return
-			}
-		}
-	}()
-
-	reader := bufio.NewReader(os.Stdin)
+	if !authenticate(netClient, console) {
+		return
+	}
 
-	for !authenticated {
-		fmt.Println("\nPlease login or register.")
-		fmt.Println("Usage: /login <nickname> <password> | /register <nickname> <password>")
-		fmt.Print("> ")
+	// Once authenticated, start the main input handler
+	netClient.HandleStdin()
+}
 
-		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(input)
-		parts := strings.Split(input, " ")
+// authenticate prompts for credentials until the server accepts a login or
+// registration. It returns false if input ends first.
+func authenticate(netClient *network.Client, console *network.Console) bool {
+	for {
+		fmt.Fprintln(console, "\nPlease login or register.")
+		fmt.Fprintln(console, "Usage: /login <nickname> | /register <nickname>")
+		console.SetPrompt("> ")
 
-		if len(parts) != 3 {
-			fmt.Println("[ERROR] Invalid command format.")
+		input, err := console.ReadLine()
+		if err != nil {
+			return false
+		}
+		parts := strings.Fields(input)
+		if len(parts) < 2 || len(parts) > 3 {
+			fmt.Fprintln(console, "[ERROR] Invalid command format.")
 			continue
 		}
 
-		command, nickname, password := parts[0], parts[1], parts[2]
+		command, nickname := parts[0], parts[1]
+		if command != "/login" && command != "/register" {
+			fmt.Fprintln(console, "[ERROR] Invalid command. Use /login or /register.")
+			continue
+		}
 
-		switch command {
-		case "/login":
-			netClient.Send <- network.WebSocketMessage{
-				Type:    "login",
-				Payload: network.LoginPayload{Nickname: nickname, Password: password},
+		// The password may still be given inline, e.g. for scripted input.
+		var password string
+		if len(parts) == 3 {
+			password = parts[2]
+		} else if password, err = promptPassword(console, command == "/register"); err != nil {
+			if err == io.EOF {
+				return false
 			}
-		case "/register":
-			netClient.Send <- network.WebSocketMessage{
-				Type:    "register",
-				Payload: network.RegisterPayload{Nickname: nickname, Password: password},
-			}
-		default:
-			fmt.Println("[ERROR] Invalid command. Use /login or /register.")
+			fmt.Fprintf(console, "[ERROR] %v\n", err)
+			continue
+		}
+
+		if err := netClient.Authenticate(strings.TrimPrefix(command, "/"), nickname, password, authTimeout); err != nil {
+			fmt.Fprintf(console, "[ERROR] %v\n", err)
 			continue
 		}
-		// Wait a moment for the server to respond
-		// In a real app, you'd handle this more gracefully
-		time.Sleep(1 * time.Second)
+		return true
 	}
+}
 
-	// Once authenticated, start the main input handler
-	netClient.HandleStdin()
+// promptPassword reads a password without echo, asking for it twice when
+// registering.
+func promptPassword(console *network.Console, confirm bool) (string, error) {
+	password, err := console.ReadPassword("Password: ")
+	if err != nil {
+		return "", err
+	}
+	if !confirm {
+		return password, nil
+	}
+	again, err := console.ReadPassword("Confirm password: ")
+	if err != nil {
+		return "", err
+	}
+	if again != password {
+		return "", errors.New("passwords do not match")
+	}
+	return password, nil
 }
@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -9,6 +10,12 @@ import (
 type Config struct {
 	Server struct {
 		URL string `mapstructure:"url"`
+		// Keepalive settings; the connection is considered dead if nothing,
+		// not even a pong, arrives within PongWait.
+		PingInterval   time.Duration `mapstructure:"ping_interval"`
+		PongWait       time.Duration `mapstructure:"pong_wait"`
+		WriteWait      time.Duration `mapstructure:"write_wait"`
+		MaxMessageSize int64         `mapstructure:"max_message_size"`
 	} `mapstructure:"server"`
 }
 
@@ -21,6 +28,11 @@ func LoadConfig() {
 
 	viper.AutomaticEnv() // 환경 변수도 읽기
 
+	viper.SetDefault("server.ping_interval", 30*time.Second)
+	viper.SetDefault("server.pong_wait", 60*time.Second)
+	viper.SetDefault("server.write_wait", 10*time.Second)
+	viper.SetDefault("server.max_message_size", 4<<20)
+
 	if err := viper.ReadInConfig(); err != nil {
 		log.Fatalf("Error reading config file: %s", err)
 	}
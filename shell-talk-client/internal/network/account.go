@@ -0,0 +1,64 @@
+package network
+
+import (
+	"fmt"
+)
+
+// handleAccountCommand handles /passwd, /nick and /deleteaccount. Passwords
+// are prompted for without echo rather than taken from the command line.
+func (c *Client) handleAccountCommand(command string, parts []string) {
+	switch command {
+	case "/nick":
+		if len(parts) != 2 {
+			c.printToScreen("[ERROR] Usage: /nick <new_nickname>")
+			return
+		}
+		c.Send <- WebSocketMessage{Type: "change_nickname", Payload: ChangeNicknamePayload{NewNickname: parts[1]}}
+
+	case "/passwd":
+		oldPassword, err := c.Console.ReadPassword("Current password: ")
+		if err != nil {
+			return
+		}
+		newPassword, err := c.Console.ReadPassword("New password: ")
+		if err != nil {
+			return
+		}
+		again, err := c.Console.ReadPassword("Confirm new password: ")
+		if err != nil {
+			return
+		}
+		if again != newPassword {
+			c.printToScreen("[ERROR] Passwords do not match.")
+			return
+		}
+		c.Send <- WebSocketMessage{Type: "change_password", Payload: ChangePasswordPayload{OldPassword: oldPassword, NewPassword: newPassword}}
+
+	case "/deleteaccount":
+		c.printToScreen("[SYSTEM] This permanently deletes your account. Rooms you own pass to another member, or are deleted if you are alone.")
+		password, err := c.Console.ReadPassword("Password to confirm: ")
+		if err != nil || password == "" {
+			c.printToScreen("[SYSTEM] Account deletion cancelled.")
+			return
+		}
+		c.Send <- WebSocketMessage{Type: "delete_account", Payload: DeleteAccountPayload{Password: password}}
+	}
+}
+
+// handleNicknameChanged applies a rename of this user or of someone they
+// talk to. A DM conversation follows its peer to the new name.
+func (c *Client) handleNicknameChanged(payload NicknameChangedPayload) {
+	c.mu.Lock()
+	self := c.AuthInfo != nil && c.AuthInfo.Nickname == payload.OldNickname
+	if self {
+		c.AuthInfo.Nickname = payload.NewNickname
+	}
+	c.mu.Unlock()
+
+	if self {
+		c.printToScreen(fmt.Sprintf("[SYSTEM] You are now known as %s.", payload.NewNickname))
+		return
+	}
+	c.renameConversation("DM", payload.OldNickname, payload.NewNickname)
+	c.printToScreen(fmt.Sprintf("[SYSTEM] %s is now known as %s.", payload.OldNickname, payload.NewNickname))
+}
@@ -0,0 +1,87 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// authState tracks where the client is in the login flow.
+type authState int
+
+const (
+	authIdle    authState = iota // Not logged in and no attempt in flight
+	authPending                  // Waiting for the server's answer to a login or register
+	authDone                     // Logged in; later logins are reconnects
+)
+
+// Authenticate sends a login or register request ("login" or "register")
+// and waits for the server's answer. It returns nil once the user is logged
+// in, the server's reason if the attempt was refused, or an error if no
+// answer arrives within timeout.
+func (c *Client) Authenticate(msgType, nickname, password string, timeout time.Duration) error {
+	var payload interface{}
+	switch msgType {
+	case "login":
+		payload = LoginPayload{Nickname: nickname, Password: password}
+	case "register":
+		payload = RegisterPayload{Nickname: nickname, Password: password}
+	default:
+		return fmt.Errorf("unknown auth request %q", msgType)
+	}
+
+	c.mu.Lock()
+	switch c.authState {
+	case authDone:
+		// A login that timed out earlier may have succeeded since.
+		c.mu.Unlock()
+		return nil
+	case authPending:
+		c.mu.Unlock()
+		return errors.New("a login attempt is already in progress")
+	}
+	c.authState = authPending
+	c.mu.Unlock()
+
+	c.Send <- WebSocketMessage{Type: msgType, Payload: payload}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-c.authResult:
+		return err
+	case <-timer.C:
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// An answer that raced with the timer has already moved the state on and
+	// is about to be delivered; take it so it does not leak into a retry.
+	if c.authState != authPending {
+		return <-c.authResult
+	}
+	c.authState = authIdle
+	return fmt.Errorf("no response from server after %s", timeout)
+}
+
+// handleAuthFailed handles login_failed and register_failed. The reason goes
+// to a waiting Authenticate call, or is printed for an in-session /login.
+func (c *Client) handleAuthFailed(payloadBytes []byte) {
+	var payload map[string]interface{}
+	_ = json.Unmarshal(payloadBytes, &payload)
+	reason, _ := payload["content"].(string)
+
+	c.mu.Lock()
+	waiting := c.authState == authPending
+	if waiting {
+		c.authState = authIdle
+	}
+	c.mu.Unlock()
+
+	if waiting {
+		c.authResult <- errors.New(reason)
+		return
+	}
+	c.printToScreen(fmt.Sprintf("[ERROR] %s", reason))
+}
@@ -1,18 +1,17 @@
 package network
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
-	"log"
-	"net/url"
+	"math/rand/v2"
 	"os"
-	"os/exec"
-	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
@@ -21,8 +20,16 @@ type Conversation struct {
 	ID      string // Nickname for DMs, Room Name for rooms
 	Type    string // "DM" or "ROOM"
 	Joined  bool   // Only relevant for rooms
-	History []string
+	History []*HistoryEntry
+	Unread  int64 // Messages received while not viewing this conversation
 	mu      sync.RWMutex
+
+	historyLoaded  bool   // Initial page of server history has been merged
+	historyPending bool   // A fetch_history request is in flight
+	nextCursor     string // Cursor for the next older page
+	hasMore        bool
+
+	typing map[string]time.Time // Nicknames currently typing, with when to stop showing them
 }
 
 // Client manages the WebSocket connection and the user interface state.
@@ -30,73 +37,44 @@ type Client struct {
 	Conn                *websocket.Conn
 	Send                chan WebSocketMessage
 	AuthInfo            *LoginSuccessPayload // Populated after successful login
-	AuthCh              chan bool            // Signals successful authentication
+	Console             *Console
+	serverURL           string
+	reconnectHint       time.Duration // Wait before the next reconnect, as asked by the server
+	heartbeat           Heartbeat
+	authState           authState
+	authResult          chan error // Outcome of the login attempt in flight
 	conversations       map[string]*Conversation
 	currentConversation *Conversation
+	pending             map[string]*pendingSend // Keyed by request ID
+	typingConv          *Conversation           // Conversation we last sent typing_start to
+	typingSentAt        time.Time
 	mu                  sync.RWMutex
 }
 
 // NewClient creates a new network client.
-func NewClient() *Client {
+func NewClient(heartbeat Heartbeat) *Client {
 	return &Client{
+		heartbeat:     heartbeat,
 		Send:          make(chan WebSocketMessage, 256),
-		AuthCh:        make(chan bool),
+		authResult:    make(chan error, 1),
 		conversations: make(map[string]*Conversation),
-	}
-}
-
-// Connect establishes a WebSocket connection to the server.
-func (c *Client) Connect(serverURL string) error {
-	u, err := url.Parse(serverURL)
-	if err != nil {
-		return err
-	}
-
-	log.Printf("Connecting to %s...", u.String())
-	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
-	if err != nil {
-		return err
-	}
-	c.Conn = conn
-
-	go c.readPump()
-	go c.writePump()
-
-	return nil
-}
-
-func (c *Client) readPump() {
-	defer c.Conn.Close()
-	for {
-		var msg WebSocketMessage
-		err := c.Conn.ReadJSON(&msg)
-		if err != nil {
-			clearScreen()
-			log.Printf("Connection to server lost: %v", err)
-			os.Exit(0)
-		}
-		c.handleServerMessage(msg)
-	}
-}
-
-func (c *Client) writePump() {
-	defer c.Conn.Close()
-	for msg := range c.Send {
-		err := c.Conn.WriteJSON(msg)
-		if err != nil {
-			log.Printf("Write error: %v", err)
-			return
-		}
+		pending:       make(map[string]*pendingSend),
+		Console:       NewConsole(),
 	}
 }
 
 // HandleStdin is the main loop for reading user input post-authentication.
 func (c *Client) HandleStdin() {
-	reader := bufio.NewReader(os.Stdin)
+	c.Console.OnKeypress(c.handleKeypress)
 	c.redrawView()
 
 	for {
-		input, _ := reader.ReadString('\n')
+		input, err := c.Console.ReadLine()
+		if err != nil {
+			c.Console.Close()
+			os.Exit(0)
+		}
+		c.stopTyping()
 		input = strings.TrimSpace(input)
 
 		if input == "" {
@@ -121,29 +99,98 @@ func (c *Client) handleCommand(input string) {
 		c.redrawView()
 		return
 	case "/create":
-		if len(parts) < 3 {
-			c.printToScreen("[ERROR] Usage: /create <room_name> <password>")
+		if len(parts) < 2 {
+			c.printToScreen("[ERROR] Usage: /create <room_name> [password | --invite-only]")
 		} else {
-			c.Send <- WebSocketMessage{Type: "create_room", Payload: CreateRoomPayload{Name: parts[1], Password: strings.Join(parts[2:], " ")}}
+			// No password makes a public room.
+			payload := CreateRoomPayload{Name: parts[1], Visibility: "public"}
+			if len(parts) == 3 && parts[2] == "--invite-only" {
+				payload.Visibility = "invite_only"
+			} else if len(parts) > 2 {
+				payload.Visibility = "password"
+				payload.Password = strings.Join(parts[2:], " ")
+			}
+			c.Send <- WebSocketMessage{Type: "create_room", Payload: payload}
 		}
 	case "/join":
-		if len(parts) < 3 {
-			c.printToScreen("[ERROR] Usage: /join <room_name> <password>")
+		if len(parts) < 2 {
+			c.printToScreen("[ERROR] Usage: /join <room_name> [password]")
 		} else {
 			c.Send <- WebSocketMessage{Type: "join_room", Payload: JoinRoomPayload{RoomName: parts[1], Password: strings.Join(parts[2:], " ")}}
 		}
+	case "/visibility":
+		if len(parts) < 3 || (parts[2] == "password" && len(parts) < 4) {
+			c.printToScreen("[ERROR] Usage: /visibility <room_name> <public|invite_only|password <password>>")
+		} else {
+			c.Send <- WebSocketMessage{Type: "set_visibility", Payload: SetVisibilityPayload{RoomName: parts[1], Visibility: parts[2], Password: strings.Join(parts[3:], " ")}}
+		}
 	case "/leave":
 		if len(parts) < 2 {
 			c.printToScreen("[ERROR] Usage: /leave <room_name>")
 		} else {
 			c.Send <- WebSocketMessage{Type: "leave_room", Payload: LeaveRoomPayload{RoomName: parts[1]}}
 		}
+	case "/login", "/register":
+		// Only needed when a session could not be resumed after reconnecting.
+		if len(parts) != 3 {
+			c.printToScreen(fmt.Sprintf("[ERROR] Usage: %s <nickname> <password>", command))
+		} else if command == "/login" {
+			c.Send <- WebSocketMessage{Type: "login", Payload: LoginPayload{Nickname: parts[1], Password: parts[2]}}
+		} else {
+			c.Send <- WebSocketMessage{Type: "register", Payload: RegisterPayload{Nickname: parts[1], Password: parts[2]}}
+		}
 	case "/members":
 		if len(parts) < 2 {
 			c.printToScreen("[ERROR] Usage: /members <room_name>")
 		} else {
 			c.Send <- WebSocketMessage{Type: "list_members", Payload: ListMembersPayload{RoomName: parts[1]}}
 		}
+	case "/deleteroom":
+		if len(parts) != 2 {
+			c.printToScreen("[ERROR] Usage: /deleteroom <room_name>")
+		} else {
+			c.Send <- WebSocketMessage{Type: "delete_room", Payload: DeleteRoomPayload{RoomName: parts[1]}}
+		}
+	case "/rename":
+		if len(parts) != 3 {
+			c.printToScreen("[ERROR] Usage: /rename <room_name> <new_name>")
+		} else {
+			c.Send <- WebSocketMessage{Type: "rename_room", Payload: RenameRoomPayload{RoomName: parts[1], NewName: parts[2]}}
+		}
+	case "/roompass":
+		if len(parts) < 3 {
+			c.printToScreen("[ERROR] Usage: /roompass <room_name> <new_password>")
+		} else {
+			c.Send <- WebSocketMessage{Type: "change_room_password", Payload: ChangeRoomPasswordPayload{RoomName: parts[1], NewPassword: strings.Join(parts[2:], " ")}}
+		}
+	case "/topic":
+		if len(parts) < 2 {
+			c.printToScreen("[ERROR] Usage: /topic <room_name> [topic]")
+		} else {
+			c.Send <- WebSocketMessage{Type: "set_topic", Payload: SetTopicPayload{RoomName: parts[1], Topic: strings.Join(parts[2:], " ")}}
+		}
+	case "/passwd", "/nick", "/deleteaccount":
+		c.handleAccountCommand(command, parts)
+	case "/invite", "/invitecode", "/accept", "/invites", "/revoke":
+		c.handleInviteCommand(command, parts)
+	case "/kick", "/ban", "/unban", "/mute", "/unmute":
+		c.handleModerationCommand(command, parts)
+	case "/webhook":
+		c.handleWebhookCommand(parts)
+	case "/incoming":
+		c.handleIncomingWebhookCommand(parts)
+	case "/role":
+		if len(parts) != 4 {
+			c.printToScreen("[ERROR] Usage: /role <room_name> <nickname> <admin|member|read_only>")
+		} else {
+			c.Send <- WebSocketMessage{Type: "set_role", Payload: SetRolePayload{RoomName: parts[1], Nickname: parts[2], Role: parts[3]}}
+		}
+	case "/transfer":
+		if len(parts) != 3 {
+			c.printToScreen("[ERROR] Usage: /transfer <room_name> <nickname>")
+		} else {
+			c.Send <- WebSocketMessage{Type: "transfer_ownership", Payload: TransferOwnershipPayload{RoomName: parts[1], Nickname: parts[2]}}
+		}
 	case "/list":
 		c.Send <- WebSocketMessage{Type: "list_rooms"}
 	case "/myrooms":
@@ -161,10 +208,81 @@ func (c *Client) handleCommand(input string) {
 			c.redrawView()
 		}
 		return
+	case "/more":
+		c.mu.RLock()
+		conv := c.currentConversation
+		c.mu.RUnlock()
+		if conv == nil {
+			c.printToScreen("[ERROR] Not in a conversation. Use /switch <dm|room> <name>.")
+			return
+		}
+		conv.mu.RLock()
+		cursor, hasMore := conv.nextCursor, conv.hasMore
+		conv.mu.RUnlock()
+		if !hasMore {
+			c.printToScreen("[SYSTEM] No older messages.")
+			return
+		}
+		c.requestHistory(conv, cursor)
+		return
+	case "/edit", "/delete":
+		c.mu.RLock()
+		conv := c.currentConversation
+		c.mu.RUnlock()
+		if conv == nil {
+			c.printToScreen("[ERROR] Not in a conversation. Use /switch <dm|room> <name>.")
+			return
+		}
+		if (command == "/edit" && len(parts) < 3) || len(parts) < 2 {
+			c.printToScreen("[ERROR] Usage: /edit <n> <new text> | /delete <n>  (n = 1 for the latest message)")
+			return
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n < 1 {
+			c.printToScreen("[ERROR] <n> must be a positive number (1 = latest message).")
+			return
+		}
+		entry := conv.nthFromEnd(n, command == "/edit")
+		if entry == nil {
+			c.printToScreen("[ERROR] No such message.")
+			return
+		}
+		if command == "/edit" {
+			c.Send <- WebSocketMessage{Type: "edit_message", Payload: EditMessagePayload{MessageID: entry.ID, Content: strings.Join(parts[2:], " ")}}
+		} else {
+			c.Send <- WebSocketMessage{Type: "delete_message", Payload: DeleteMessagePayload{MessageID: entry.ID}}
+		}
+		return
+	case "/away":
+		c.Send <- WebSocketMessage{Type: "set_presence", Payload: SetPresencePayload{Status: "away"}}
+		c.printToScreen("[SYSTEM] You are now away.")
+		return
+	case "/back":
+		c.Send <- WebSocketMessage{Type: "set_presence", Payload: SetPresencePayload{Status: "online"}}
+		c.printToScreen("[SYSTEM] You are now online.")
+		return
+	case "/retry":
+		c.mu.RLock()
+		conv := c.currentConversation
+		c.mu.RUnlock()
+		if conv == nil {
+			c.printToScreen("[ERROR] Not in a conversation. Use /switch <dm|room> <name>.")
+			return
+		}
+		if n := c.retryFailed(conv); n == 0 {
+			c.printToScreen("[SYSTEM] No failed messages to resend.")
+		} else {
+			c.redrawView()
+		}
+		return
 	case "/exit":
 		c.mu.Lock()
+		previous := c.currentConversation
 		c.currentConversation = nil
 		c.mu.Unlock()
+		if previous != nil {
+			c.sendMarkRead(previous)
+		}
 		c.redrawView()
 		return
 	default:
@@ -184,19 +302,87 @@ func (c *Client) handleChatMessage(input string) {
 		return
 	}
 
-	var msgType string
+	requestID := uuid.NewString()
+	var msg WebSocketMessage
 	switch conv.Type {
 	case "DM":
-		msgType = "send_direct_message"
-		c.Send <- WebSocketMessage{Type: msgType, Payload: SendDirectMessagePayload{RecipientNickname: conv.ID, Content: input}}
+		msg = WebSocketMessage{Type: "send_direct_message", Payload: SendDirectMessagePayload{RequestID: requestID, RecipientNickname: conv.ID, Content: input}}
 	case "ROOM":
-		msgType = "send_room_message"
-		c.Send <- WebSocketMessage{Type: msgType, Payload: SendRoomMessagePayload{RoomName: conv.ID, Content: input}}
+		msg = WebSocketMessage{Type: "send_room_message", Payload: SendRoomMessagePayload{RequestID: requestID, RoomName: conv.ID, Content: input}}
+	}
+
+	entry := &HistoryEntry{RequestID: requestID, Sender: "Me", Content: input, Timestamp: time.Now(), Status: statusPending}
+	c.mu.Lock()
+	c.pending[requestID] = &pendingSend{conv: conv, entry: entry, msg: msg}
+	c.mu.Unlock()
+
+	c.Send <- msg
+	conv.addHistory(entry)
+	c.printToScreen(entry.String())
+}
+
+// retryFailed resends every failed message in conv under its original
+// request ID, so the server stores each one at most once.
+func (c *Client) retryFailed(conv *Conversation) int {
+	c.mu.RLock()
+	var retries []*pendingSend
+	for _, p := range c.pending {
+		if p.conv == conv {
+			conv.mu.RLock()
+			failed := p.entry.Status == statusFailed
+			conv.mu.RUnlock()
+			if failed {
+				retries = append(retries, p)
+			}
+		}
+	}
+	c.mu.RUnlock()
+
+	for _, p := range retries {
+		conv.mu.Lock()
+		p.entry.Status = statusPending
+		conv.mu.Unlock()
+		c.Send <- p.msg
+	}
+	return len(retries)
+}
+
+// updateEntry rewrites a message already in a conversation's history and
+// redraws the view if that conversation is on screen.
+func (c *Client) updateEntry(convType, name, messageID string, apply func(entry *HistoryEntry)) {
+	key := strings.ToUpper(convType) + "_" + name
+	c.mu.RLock()
+	conv, ok := c.conversations[key]
+	isCurrent := ok && c.currentConversation == conv
+	c.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	conv.mu.Lock()
+	entry := conv.findByID(messageID)
+	if entry != nil {
+		apply(entry)
 	}
+	conv.mu.Unlock()
 
-	formattedMsg := fmt.Sprintf("[%s] [Me]: %s", time.Now().Format("15:04:05"), input)
-	conv.addHistory(formattedMsg)
-	c.printToScreen(formattedMsg)
+	if entry != nil && isCurrent {
+		c.redrawView()
+	}
+}
+
+// resolvePending applies a server ack or rejection to a sent message.
+func (c *Client) resolvePending(requestID string, apply func(entry *HistoryEntry)) *Conversation {
+	c.mu.RLock()
+	p, ok := c.pending[requestID]
+	c.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	p.conv.mu.Lock()
+	apply(p.entry)
+	p.conv.mu.Unlock()
+	return p.conv
 }
 
 func (c *Client) handleServerMessage(msg WebSocketMessage) {
@@ -207,46 +393,216 @@ func (c *Client) handleServerMessage(msg WebSocketMessage) {
 	case "login_success":
 		var payload LoginSuccessPayload
 		_ = json.Unmarshal(payloadBytes, &payload)
+		c.mu.Lock()
+		resumed := c.authState == authDone
+		c.authState = authDone
 		c.AuthInfo = &payload
-		fmt.Printf("\r[SYSTEM] Welcome, %s! Login successful.\n", payload.Nickname)
-		c.AuthCh <- true
+		c.mu.Unlock()
+		if resumed {
+			c.printToScreen("[SYSTEM] Reconnected.")
+			c.catchUp()
+			return
+		}
+		fmt.Fprintf(c.Console, "\r[SYSTEM] Welcome, %s! Login successful.\n", payload.Nickname)
+		c.authResult <- nil
 		return
 
-	case "new_direct_message":
+	case "password_changed":
+		var payload PasswordChangedPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		c.mu.Lock()
+		if c.AuthInfo != nil {
+			c.AuthInfo.SessionToken = payload.SessionToken
+			c.AuthInfo.SessionExpiresAt = payload.SessionExpiresAt
+		}
+		c.mu.Unlock()
+		c.printToScreen("[SYSTEM] Your password has been changed. Other sessions will need to log in again.")
+
+	case "nickname_changed":
+		var payload NicknameChangedPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		c.handleNicknameChanged(payload)
+
+	case "account_deleted":
+		c.Console.Close()
+		fmt.Println("\r[SYSTEM] Your account has been deleted. Goodbye.")
+		os.Exit(0)
+
+	case "login_failed", "register_failed":
+		c.handleAuthFailed(payloadBytes)
+		return
+
+	case "resume_failed":
 		var payload map[string]interface{}
 		_ = json.Unmarshal(payloadBytes, &payload)
-		sender := payload["sender"].(string)
-		content := payload["content"].(string)
-		conv := c.getOrCreateConversation(sender, "DM")
-		formattedMsg := fmt.Sprintf("[%s] [%s]: %s", timestamp, sender, content)
-		conv.addHistory(formattedMsg)
-		c.notifyOrUpdate(sender, "DM", formattedMsg)
+		c.mu.Lock()
+		if c.AuthInfo != nil {
+			c.AuthInfo.SessionToken = ""
+		}
+		c.mu.Unlock()
+		c.printToScreen(fmt.Sprintf("[SYSTEM] %v Use /login <nickname> <password>.", payload["content"]))
+
+	case "new_direct_message":
+		var payload DirectMessagePayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		conv := c.getOrCreateConversation(payload.Sender, "DM")
+		entry := &HistoryEntry{ID: payload.ID, Sender: payload.Sender, Content: payload.Content, Timestamp: payload.Timestamp}
+		conv.addHistory(entry)
+		c.notifyOrUpdate(payload.Sender, "DM", entry.String())
 
 	case "room_message":
-		var payload map[string]interface{}
+		var payload RoomMessagePayload
 		_ = json.Unmarshal(payloadBytes, &payload)
-		roomName := payload["room_name"].(string)
-		sender := payload["sender_nickname"].(string)
-		content := payload["content"].(string)
-		conv := c.getOrCreateConversation(roomName, "ROOM")
-		formattedMsg := fmt.Sprintf("[%s] [%s]: %s", timestamp, sender, content)
-		conv.addHistory(formattedMsg)
-		c.notifyOrUpdate(roomName, "ROOM", formattedMsg)
+		conv := c.getOrCreateConversation(payload.RoomName, "ROOM")
+		entry := &HistoryEntry{ID: payload.ID, Sender: displaySender(payload.SenderNickname, payload.Bot, ""), Content: payload.Content, Timestamp: payload.Timestamp}
+		conv.addHistory(entry)
+		c.notifyOrUpdate(payload.RoomName, "ROOM", entry.String())
+
+	case "message_ack":
+		var payload MessageAckPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		conv := c.resolvePending(payload.RequestID, func(entry *HistoryEntry) {
+			entry.ID = payload.MessageID
+			entry.Timestamp = payload.Timestamp
+			entry.Status = ""
+		})
+		c.mu.Lock()
+		delete(c.pending, payload.RequestID)
+		isCurrent := conv != nil && c.currentConversation == conv
+		c.mu.Unlock()
+		// A resent message may already have arrived through catch-up history.
+		if conv != nil && conv.removeDuplicates(payload.MessageID) && isCurrent {
+			c.redrawView()
+		}
+
+	case "message_edited":
+		var payload MessageEditedPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		c.updateEntry(payload.ConversationType, payload.Name, payload.MessageID, func(entry *HistoryEntry) {
+			entry.Content = payload.Content
+			entry.Edited = true
+		})
+
+	case "message_deleted":
+		var payload MessageDeletedPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		c.updateEntry(payload.ConversationType, payload.Name, payload.MessageID, func(entry *HistoryEntry) {
+			entry.Content = ""
+			entry.Deleted = true
+		})
+
+	case "typing_update":
+		var payload TypingUpdatePayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		key := strings.ToUpper(payload.ConversationType) + "_" + payload.Name
+		c.mu.RLock()
+		conv, ok := c.conversations[key]
+		isCurrent := ok && c.currentConversation == conv
+		c.mu.RUnlock()
+		if !ok {
+			return
+		}
+		conv.setTyping(payload.Nickname, payload.Typing)
+		if isCurrent && c.Console.Interactive() {
+			c.prompt()
+		}
+
+	case "message_rejected":
+		var payload MessageRejectedPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		conv := c.resolvePending(payload.RequestID, func(entry *HistoryEntry) {
+			entry.Status = statusFailed
+		})
+		if conv != nil {
+			c.printToScreen(fmt.Sprintf("[ERROR] Message to %s not delivered: %s", conv.ID, payload.Reason))
+		}
+
+	case "rate_limited":
+		var payload RateLimitedPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		wait := time.Duration(payload.RetryAfterMs) * time.Millisecond
+		conv := c.resolvePending(payload.RequestID, func(entry *HistoryEntry) {
+			entry.Status = statusFailed
+		})
+		if conv != nil {
+			c.printToScreen(fmt.Sprintf("[ERROR] Sending too fast; message to %s not delivered. Use /retry in %s.", conv.ID, wait.Round(100*time.Millisecond)))
+		} else {
+			c.printToScreen(fmt.Sprintf("[ERROR] Too many '%s' requests; try again in %s.", payload.MessageType, wait.Round(100*time.Millisecond)))
+		}
 
 	case "join_success":
 		var payload JoinSuccessPayload
 		_ = json.Unmarshal(payloadBytes, &payload)
 		conv := c.getOrCreateConversation(payload.RoomName, "ROOM")
 		conv.mu.Lock()
+		wasJoined := conv.Joined
 		conv.Joined = true
 		conv.mu.Unlock()
-		c.printToScreen(fmt.Sprintf("[SYSTEM] Successfully joined room: %s", payload.RoomName))
+		// Rooms are re-announced on every resume; only report new ones.
+		if !wasJoined {
+			c.printToScreen(fmt.Sprintf("[SYSTEM] Successfully joined room: %s", payload.RoomName))
+		}
 
 	case "leave_success":
 		var payload LeaveSuccessPayload
 		_ = json.Unmarshal(payloadBytes, &payload)
 		c.printToScreen(fmt.Sprintf("[SYSTEM] Successfully left a room."))
 
+	case "room_deleted":
+		var payload RoomDeletedPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		c.removeConversation(payload.RoomName, "ROOM")
+		c.printToScreen(fmt.Sprintf("[SYSTEM] Room '%s' was deleted by %s.", payload.RoomName, payload.DeletedBy))
+
+	case "room_renamed":
+		var payload RoomRenamedPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		c.renameConversation("ROOM", payload.OldName, payload.NewName)
+		c.printToScreen(fmt.Sprintf("[SYSTEM] Room '%s' was renamed to '%s' by %s.", payload.OldName, payload.NewName, payload.RenamedBy))
+
+	case "invite_created":
+		var payload InviteCreatedPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		if payload.Invitee != "" {
+			c.printToScreen(fmt.Sprintf("[SYSTEM] Invited %s to '%s' with code %s (%s).", payload.Invitee, payload.RoomName, payload.Code, describeInvite(payload.MaxUses, 0, payload.ExpiresAt)))
+		} else {
+			c.printToScreen(fmt.Sprintf("[SYSTEM] Invite code for '%s': %s (%s). Share it and have them type /accept %s", payload.RoomName, payload.Code, describeInvite(payload.MaxUses, 0, payload.ExpiresAt), payload.Code))
+		}
+
+	case "invite_list":
+		var payload InviteListPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		var builder strings.Builder
+		builder.WriteString(fmt.Sprintf("\r[%s] [Invites for %s]:\n", timestamp, payload.RoomName))
+		if len(payload.Invites) == 0 {
+			builder.WriteString("  No active invites.")
+		}
+		for _, invite := range payload.Invites {
+			target := "anyone"
+			if invite.Invitee != "" {
+				target = invite.Invitee
+			}
+			builder.WriteString(fmt.Sprintf("  - %s for %s by %s (%s)\n", invite.Code, target, invite.CreatedBy, describeInvite(invite.MaxUses, invite.Uses, invite.ExpiresAt)))
+		}
+		c.printToScreen(builder.String())
+
+	case "webhook_created", "webhook_list", "webhook_failures", "incoming_webhook_created", "incoming_webhook_list":
+		c.handleWebhookMessage(msg.Type, payloadBytes, timestamp)
+
+	case "room_notice":
+		var payload RoomNoticePayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		conv := c.getOrCreateConversation(payload.RoomName, "ROOM")
+		entry := &HistoryEntry{Sender: "SYSTEM", Content: payload.Content, Timestamp: payload.Timestamp}
+		conv.addHistory(entry)
+		c.notifyOrUpdate(payload.RoomName, "ROOM", entry.String())
+
+	case "room_removed":
+		var payload RoomRemovedPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		c.dropRoom(payload.RoomName)
+		c.printToScreen(fmt.Sprintf("[SYSTEM] You were removed from room '%s'. %s", payload.RoomName, payload.Reason))
+
 	case "room_list":
 		var payload RoomListPayload
 		_ = json.Unmarshal(payloadBytes, &payload)
@@ -256,22 +612,71 @@ func (c *Client) handleServerMessage(msg WebSocketMessage) {
 			builder.WriteString("  No rooms available.")
 		} else {
 			for _, room := range payload.Rooms {
-				builder.WriteString(fmt.Sprintf("  - %s\n", room.Name))
+				line := fmt.Sprintf("  - %s (%d members)", room.Name, room.MemberCount)
+				if room.Visibility == "password" {
+					line += " [password]"
+				}
+				if room.Topic != "" {
+					line += " - " + room.Topic
+				}
+				builder.WriteString(line + "\n")
 				c.getOrCreateConversation(room.Name, "ROOM")
 			}
 		}
 		c.printToScreen(builder.String())
 
+	case "unread_summary":
+		var payload UnreadSummaryPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		for _, u := range payload.Conversations {
+			conv := c.getOrCreateConversation(u.Name, strings.ToUpper(u.ConversationType))
+			conv.mu.Lock()
+			conv.Unread = u.UnreadCount
+			conv.mu.Unlock()
+		}
+		if len(payload.Conversations) > 0 {
+			c.printToScreen(c.unreadSummary())
+		}
+
+	case "history":
+		var payload HistoryPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		c.mergeHistory(payload)
+
 	case "room_members":
 		var payload RoomMembersPayload
 		_ = json.Unmarshal(payloadBytes, &payload)
 		var builder strings.Builder
 		builder.WriteString(fmt.Sprintf("\r[%s] [Members of %s]:\n", timestamp, payload.RoomName))
 		for _, member := range payload.Members {
-			builder.WriteString(fmt.Sprintf("  - %s\n", member))
+			builder.WriteString(fmt.Sprintf("  - %s%s (%s)\n", roleBadge(member.Role), member.Nickname, describePresence(member.Status, member.LastSeenAt)))
 		}
 		c.printToScreen(builder.String())
 
+	case "presence_update":
+		var payload PresenceUpdatePayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		// Only announce changes for the DM on screen or while in the lobby.
+		c.mu.RLock()
+		conv := c.currentConversation
+		relevant := conv == nil || (conv.Type == "DM" && conv.ID == payload.Nickname)
+		c.mu.RUnlock()
+		if relevant {
+			c.printToScreen(fmt.Sprintf("[SYSTEM] %s is now %s", payload.Nickname, describePresence(payload.Status, &payload.LastSeenAt)))
+		}
+
+	case "server_shutdown":
+		var payload ServerShutdownPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		c.printToScreen(fmt.Sprintf("[SYSTEM] %s", payload.Content))
+		if payload.ReconnectAfterMs > 0 {
+			// Spread reconnects out so the restarted server is not hit by every client at once.
+			hint := time.Duration(payload.ReconnectAfterMs) * time.Millisecond
+			c.mu.Lock()
+			c.reconnectHint = (hint + rand.N(hint)).Round(100 * time.Millisecond)
+			c.mu.Unlock()
+		}
+
 	case "error_message", "system_message":
 		var payload map[string]interface{}
 		_ = json.Unmarshal(payloadBytes, &payload)
@@ -294,15 +699,39 @@ func (c *Client) getOrCreateConversation(name string, convType string) *Conversa
 	if conv, exists := c.conversations[key]; exists {
 		return conv
 	}
-	conv := &Conversation{ID: name, Type: convType, History: []string{}}
+	conv := &Conversation{ID: name, Type: convType, History: []*HistoryEntry{}}
 	c.conversations[key] = conv
 	return conv
 }
 
 func (c *Client) switchConversation(convType, name string) error {
+	conv, previous, err := c.selectConversation(convType, name)
+	if err != nil {
+		return err
+	}
+
+	// Requests are sent once c.mu is released: Send blocks while disconnected
+	// with a full buffer, and reconnecting needs c.mu.
+	if previous != nil && previous != conv {
+		c.sendMarkRead(previous)
+	}
+	c.sendMarkRead(conv)
+
+	conv.mu.RLock()
+	needsHistory := !conv.historyLoaded && !conv.historyPending
+	conv.mu.RUnlock()
+	if needsHistory {
+		c.requestHistory(conv, "")
+	}
+	return nil
+}
+
+// selectConversation makes a conversation the current one, returning it and
+// the one it replaces.
+func (c *Client) selectConversation(convType, name string) (conv, previous *Conversation, err error) {
 	convType = strings.ToUpper(convType)
 	if convType != "DM" && convType != "ROOM" {
-		return fmt.Errorf("invalid switch type: must be 'dm' or 'room'")
+		return nil, nil, fmt.Errorf("invalid switch type: must be 'dm' or 'room'")
 	}
 
 	key := convType + "_" + name
@@ -312,13 +741,13 @@ func (c *Client) switchConversation(convType, name string) error {
 	conv, exists := c.conversations[key]
 	if convType == "ROOM" {
 		if !exists {
-			return fmt.Errorf("unknown room: %s. Use /list to see available rooms", name)
+			return nil, nil, fmt.Errorf("unknown room: %s. Use /list to see available rooms", name)
 		}
 		conv.mu.RLock()
 		joined := conv.Joined
 		conv.mu.RUnlock()
 		if !joined {
-			return fmt.Errorf("you have not joined room '%s'. Use /join first", name)
+			return nil, nil, fmt.Errorf("you have not joined room '%s'. Use /join first", name)
 		}
 	}
 
@@ -326,8 +755,78 @@ func (c *Client) switchConversation(convType, name string) error {
 		conv = c.getOrCreateConversation_internal(name, convType)
 	}
 
+	previous = c.currentConversation
 	c.currentConversation = conv
-	return nil
+	conv.mu.Lock()
+	conv.Unread = 0
+	conv.mu.Unlock()
+	return conv, previous, nil
+}
+
+// sendMarkRead tells the server everything in conv has been seen.
+func (c *Client) sendMarkRead(conv *Conversation) {
+	c.Send <- WebSocketMessage{Type: "mark_read", Payload: MarkReadPayload{ConversationType: strings.ToLower(conv.Type), Name: conv.ID}}
+}
+
+// requestHistory asks the server for the page of messages preceding cursor.
+// An empty cursor requests the most recent page.
+func (c *Client) requestHistory(conv *Conversation, cursor string) {
+	conv.mu.Lock()
+	if conv.historyPending {
+		conv.mu.Unlock()
+		return
+	}
+	conv.historyPending = true
+	convType := strings.ToLower(conv.Type)
+	conv.mu.Unlock()
+
+	c.Send <- WebSocketMessage{Type: "fetch_history", Payload: FetchHistoryPayload{ConversationType: convType, Name: conv.ID, Before: cursor}}
+}
+
+// mergeHistory prepends a page of server history to the conversation.
+func (c *Client) mergeHistory(payload HistoryPayload) {
+	conv := c.getOrCreateConversation(payload.Name, strings.ToUpper(payload.ConversationType))
+
+	c.mu.RLock()
+	me := ""
+	if c.AuthInfo != nil {
+		me = c.AuthInfo.Nickname
+	}
+	isCurrent := c.currentConversation == conv
+	c.mu.RUnlock()
+
+	if payload.AfterID != "" {
+		c.appendNewer(conv, payload, me, isCurrent)
+		return
+	}
+
+	page := make([]*HistoryEntry, 0, len(payload.Messages))
+	seen := make(map[string]bool, len(payload.Messages))
+	for _, m := range payload.Messages {
+		page = append(page, &HistoryEntry{ID: m.ID, Sender: displaySender(m.SenderNickname, m.Bot, me), Content: m.Content, Timestamp: m.Timestamp, Edited: m.Edited, Deleted: m.Deleted})
+		seen[m.ID] = true
+	}
+
+	conv.mu.Lock()
+	// Messages received live may already be part of the fetched page.
+	kept := make([]*HistoryEntry, 0, len(conv.History))
+	for _, entry := range conv.History {
+		if entry.ID == "" || !seen[entry.ID] {
+			kept = append(kept, entry)
+		}
+	}
+	conv.History = append(page, kept...)
+	if payload.Before == "" {
+		conv.historyLoaded = true
+	}
+	conv.nextCursor = payload.NextCursor
+	conv.hasMore = payload.HasMore
+	conv.historyPending = false
+	conv.mu.Unlock()
+
+	if isCurrent {
+		c.redrawView()
+	}
 }
 
 func (c *Client) getOrCreateConversation_internal(name, convType string) *Conversation {
@@ -335,24 +834,27 @@ func (c *Client) getOrCreateConversation_internal(name, convType string) *Conver
 	if conv, exists := c.conversations[key]; exists {
 		return conv
 	}
-	conv := &Conversation{ID: name, Type: convType, History: []string{}}
+	conv := &Conversation{ID: name, Type: convType, History: []*HistoryEntry{}}
 	c.conversations[key] = conv
 	return conv
 }
 
 func (c *Client) redrawView() {
-	clearScreen()
+	c.Console.ClearScreen()
 	c.mu.RLock()
 	conv := c.currentConversation
 	c.mu.RUnlock()
 
 	if conv == nil {
 		c.printHelp()
+		if summary := c.unreadSummary(); summary != "" {
+			fmt.Fprintln(c.Console, summary)
+		}
 	} else {
-		fmt.Printf("--- Conversation with %s (%s) ---\n", conv.ID, conv.Type)
+		fmt.Fprintf(c.Console, "--- Conversation with %s (%s) ---\n", conv.ID, conv.Type)
 		conv.mu.RLock()
-		for _, msg := range conv.History {
-			fmt.Println(msg)
+		for _, entry := range conv.History {
+			fmt.Fprintln(c.Console, entry.String())
 		}
 		conv.mu.RUnlock()
 	}
@@ -385,8 +887,52 @@ func (c *Client) listMyRooms() {
 	c.printToScreen(builder.String())
 }
 
+// unreadSummary lists conversations with unread messages, or returns an
+// empty string if there are none.
+func (c *Client) unreadSummary() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	keys := make([]string, 0, len(c.conversations))
+	for key := range c.conversations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	for _, key := range keys {
+		conv := c.conversations[key]
+		conv.mu.RLock()
+		unread := conv.Unread
+		conv.mu.RUnlock()
+		if unread > 0 {
+			builder.WriteString(fmt.Sprintf("  - %s (%s): %d unread\n", conv.ID, conv.Type, unread))
+		}
+	}
+	if builder.Len() == 0 {
+		return ""
+	}
+	return "--- Unread ---\n" + strings.TrimSuffix(builder.String(), "\n")
+}
+
+// describePresence formats a presence status, adding the last-seen time for
+// users who are offline.
+func describePresence(status string, lastSeenAt *time.Time) string {
+	if status != "offline" {
+		return status
+	}
+	if lastSeenAt == nil {
+		return "offline"
+	}
+	seen := lastSeenAt.Local()
+	if seen.YearDay() == time.Now().YearDay() && seen.Year() == time.Now().Year() {
+		return "offline, last seen " + seen.Format("15:04")
+	}
+	return "offline, last seen " + seen.Format("2006-01-02 15:04")
+}
+
 func (c *Client) printToScreen(msg string) {
-	fmt.Printf("\r%s\n", msg)
+	fmt.Fprintf(c.Console, "\r%s\n", msg)
 	c.prompt()
 }
 
@@ -396,12 +942,15 @@ func (c *Client) prompt() {
 	if c.AuthInfo != nil {
 		if c.currentConversation != nil {
 			prompt = fmt.Sprintf("[%s] > ", c.currentConversation.ID)
+			if typing := c.currentConversation.typingLabel(); typing != "" {
+				prompt = fmt.Sprintf("[%s] (%s) > ", c.currentConversation.ID, typing)
+			}
 		} else {
 			prompt = "[Lobby] > "
 		}
 	}
 	c.mu.RUnlock()
-	fmt.Print(prompt)
+	c.Console.SetPrompt(prompt)
 }
 
 func (c *Client) notifyOrUpdate(name, convType, message string) {
@@ -413,45 +962,72 @@ func (c *Client) notifyOrUpdate(name, convType, message string) {
 		isCurrent = (currentKey == key)
 	}
 	inLobby := c.currentConversation == nil
+	conv := c.conversations[key]
 	c.mu.RUnlock()
 
+	var unread int64
+	if !isCurrent && conv != nil {
+		conv.mu.Lock()
+		conv.Unread++
+		unread = conv.Unread
+		conv.mu.Unlock()
+	}
+
 	if isCurrent {
 		c.printToScreen(message)
 	} else if inLobby {
-		c.printToScreen(fmt.Sprintf("New message in %s (%s)", name, convType))
+		c.printToScreen(fmt.Sprintf("New message in %s (%s) [%d unread]", name, convType, unread))
 	}
 }
 
 func (c *Client) printHelp() {
-	fmt.Println("--- ShellTalk Help ---")
-	fmt.Println("  /help                  - Show this help message")
-	fmt.Println("  /list                  - List all available rooms")
-	fmt.Println("  /myrooms               - List rooms you have joined")
-	fmt.Println("  /create <name> <pass>  - Create a new room")
-	fmt.Println("  /join <name> <pass>    - Join a room by its name")
-	fmt.Println("  /leave <name>          - Leave a room by its name")
-	fmt.Println("  /members <name>        - List members of a room")
-	fmt.Println("  /switch dm <nickname>  - Switch to a DM conversation")
-	fmt.Println("  /switch room <name>    - Switch to a room conversation")
-	fmt.Println("  /exit                  - Exit the current conversation to the lobby")
+	fmt.Fprintln(c.Console, "--- ShellTalk Help ---")
+	fmt.Fprintln(c.Console, "  /help                  - Show this help message")
+	fmt.Fprintln(c.Console, "  /list                  - List all available rooms")
+	fmt.Fprintln(c.Console, "  /myrooms               - List rooms you have joined")
+	fmt.Fprintln(c.Console, "  /create <name> [pass]  - Create a room; public without a password")
+	fmt.Fprintln(c.Console, "  /create <name> --invite-only - Create a hidden room joined by invitation")
+	fmt.Fprintln(c.Console, "  /join <name> [pass]    - Join a room by its name")
+	fmt.Fprintln(c.Console, "  /leave <name>          - Leave a room by its name")
+	fmt.Fprintln(c.Console, "  /members <name>        - List members of a room")
+	fmt.Fprintln(c.Console, "  /invite <room> <nick> [dur]           - Invite a user directly (owner/admin)")
+	fmt.Fprintln(c.Console, "  /invitecode <room> [uses] [dur]       - Create a shareable invite code (owner/admin)")
+	fmt.Fprintln(c.Console, "  /accept <code>                        - Join a room with an invite code")
+	fmt.Fprintln(c.Console, "  /invites <room>                       - List a room's active invites (owner/admin)")
+	fmt.Fprintln(c.Console, "  /revoke <room> <code>                 - Revoke an invite (owner/admin)")
+	fmt.Fprintln(c.Console, "  /visibility <room> <public|invite_only|password <pass>> - Change who can join (owner only)")
+	fmt.Fprintln(c.Console, "  /webhook add <room> <url> [events]    - Send room events to a URL (owner only)")
+	fmt.Fprintln(c.Console, "  /webhook list|failures <room>         - List a room's webhooks or failed deliveries (owner only)")
+	fmt.Fprintln(c.Console, "  /webhook remove <room> <id>           - Remove a webhook (owner only)")
+	fmt.Fprintln(c.Console, "  /incoming add <room> <bot_name>       - Create a URL that posts to a room as a bot (owner only)")
+	fmt.Fprintln(c.Console, "  /incoming list|remove <room> [id]     - List or revoke a room's incoming webhooks (owner only)")
+	fmt.Fprintln(c.Console, "  /topic <room> [topic]                 - Set or clear a room's topic (owner only)")
+	fmt.Fprintln(c.Console, "  /rename <room> <new_name>             - Rename a room (owner only)")
+	fmt.Fprintln(c.Console, "  /roompass <room> <new_password>       - Change a room's password (owner only)")
+	fmt.Fprintln(c.Console, "  /deleteroom <room>                    - Delete a room and its history (owner only)")
+	fmt.Fprintln(c.Console, "  /kick <room> <nick> [reason]          - Remove a member (owner/admin)")
+	fmt.Fprintln(c.Console, "  /ban <room> <nick> [dur] [reason]     - Ban a member, e.g. dur=1h (owner/admin)")
+	fmt.Fprintln(c.Console, "  /unban <room> <nick>                  - Lift a ban (owner/admin)")
+	fmt.Fprintln(c.Console, "  /mute <room> <nick> [dur]             - Stop a member from posting (owner/admin)")
+	fmt.Fprintln(c.Console, "  /unmute <room> <nick>                 - Lift a mute (owner/admin)")
+	fmt.Fprintln(c.Console, "  /role <room> <nick> <role>            - Set admin, member or read_only")
+	fmt.Fprintln(c.Console, "  /transfer <room> <nick>               - Hand room ownership to a member (owner only)")
+	fmt.Fprintln(c.Console, "  /nick <new_nickname>   - Change your nickname")
+	fmt.Fprintln(c.Console, "  /passwd                - Change your password")
+	fmt.Fprintln(c.Console, "  /deleteaccount         - Delete your account; owned rooms pass to another member")
+	fmt.Fprintln(c.Console, "  /away                  - Show others that you are away")
+	fmt.Fprintln(c.Console, "  /back                  - Show others that you are online again")
+	fmt.Fprintln(c.Console, "  /switch dm <nickname>  - Switch to a DM conversation")
+	fmt.Fprintln(c.Console, "  /switch room <name>    - Switch to a room conversation")
+	fmt.Fprintln(c.Console, "  /more                  - Load older messages in the current conversation")
+	fmt.Fprintln(c.Console, "  /edit <n> <text>       - Edit your n-th most recent message (1 = latest)")
+	fmt.Fprintln(c.Console, "  /delete <n>            - Delete the n-th most recent message (1 = latest)")
+	fmt.Fprintln(c.Console, "  /retry                 - Resend failed messages in the current conversation")
+	fmt.Fprintln(c.Console, "  /exit                  - Exit the current conversation to the lobby")
 }
 
-func (conv *Conversation) addHistory(msg string) {
+func (conv *Conversation) addHistory(entry *HistoryEntry) {
 	conv.mu.Lock()
 	defer conv.mu.Unlock()
-	conv.History = append(conv.History, msg)
-}
-
-func clearScreen() {
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command("cmd", "/c", "cls")
-	} else {
-		cmd = exec.Command("clear")
-	}
-	cmd.Stdout = os.Stdout
-	err := cmd.Run()
-	if err != nil {
-		fmt.Print(strings.Repeat("\n", 50))
-	}
+	conv.History = append(conv.History, entry)
 }
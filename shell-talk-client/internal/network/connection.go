@@ -0,0 +1,209 @@
+package network
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Reconnect backoff bounds.
+const (
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = 30 * time.Second
+)
+
+// Heartbeat holds the connection keepalive settings. The client pings the
+// server every PingInterval; if nothing arrives for PongWait the server is
+// presumed dead and the client reconnects.
+type Heartbeat struct {
+	PingInterval   time.Duration
+	PongWait       time.Duration
+	WriteWait      time.Duration // Time allowed to write one message
+	MaxMessageSize int64         // Largest message accepted from the server, in bytes
+}
+
+// Connect establishes a WebSocket connection to the server. If the
+// connection later drops, the client reconnects and resumes its session.
+func (c *Client) Connect(serverURL string) error {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return err
+	}
+	c.serverURL = u.String()
+
+	log.Printf("Connecting to %s...", c.serverURL)
+	conn, _, err := websocket.DefaultDialer.Dial(c.serverURL, nil)
+	if err != nil {
+		return err
+	}
+	c.startPumps(conn)
+	return nil
+}
+
+// startPumps runs the read and write loops for a new connection.
+func (c *Client) startPumps(conn *websocket.Conn) {
+	c.mu.Lock()
+	c.Conn = conn
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go c.writePump(conn, done)
+	go c.readPump(conn, done)
+}
+
+func (c *Client) readPump(conn *websocket.Conn, done chan struct{}) {
+	conn.SetReadLimit(c.heartbeat.MaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(c.heartbeat.PongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(c.heartbeat.PongWait))
+	})
+
+	for {
+		var msg WebSocketMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if closeErr, ok := err.(*websocket.CloseError); ok && closeErr.Code == websocket.ClosePolicyViolation {
+				// Reconnecting would only repeat whatever got us disconnected.
+				c.printToScreen(fmt.Sprintf("[SYSTEM] Disconnected by server: %s. Restart the client to reconnect.", closeErr.Text))
+				close(done)
+				conn.Close()
+				return
+			}
+			c.printToScreen(fmt.Sprintf("[SYSTEM] Connection to server lost: %v", err))
+			break
+		}
+		conn.SetReadDeadline(time.Now().Add(c.heartbeat.PongWait))
+		c.handleServerMessage(msg)
+	}
+	close(done)
+	conn.Close()
+	c.reconnect()
+}
+
+func (c *Client) writePump(conn *websocket.Conn, done chan struct{}) {
+	ticker := time.NewTicker(c.heartbeat.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case msg := <-c.Send:
+			conn.SetWriteDeadline(time.Now().Add(c.heartbeat.WriteWait))
+			if err := conn.WriteJSON(msg); err != nil {
+				log.Printf("Write error: %v", err)
+				// Closing the connection makes readPump reconnect.
+				conn.Close()
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(c.heartbeat.WriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// reconnect dials the server until it succeeds, backing off exponentially
+// from the delay the server asked for when it shut down, if any, then
+// resumes the session if there is one. Messages queued on Send while
+// disconnected are delivered once the new connection is up.
+func (c *Client) reconnect() {
+	c.mu.Lock()
+	delay := max(c.reconnectHint, reconnectMinDelay)
+	c.reconnectHint = 0
+	c.mu.Unlock()
+	for attempt := 1; ; attempt++ {
+		c.printToScreen(fmt.Sprintf("[SYSTEM] Reconnecting in %s (attempt %d)...", delay, attempt))
+		time.Sleep(delay)
+
+		conn, _, err := websocket.DefaultDialer.Dial(c.serverURL, nil)
+		if err != nil {
+			delay = min(delay*2, reconnectMaxDelay)
+			continue
+		}
+
+		c.mu.RLock()
+		token := ""
+		if c.AuthInfo != nil {
+			token = c.AuthInfo.SessionToken
+		}
+		c.mu.RUnlock()
+
+		// Resume before anything queued is written, so the server handles it first.
+		if token != "" {
+			conn.SetWriteDeadline(time.Now().Add(c.heartbeat.WriteWait))
+			if err := conn.WriteJSON(WebSocketMessage{Type: "resume", Payload: ResumePayload{SessionToken: token}}); err != nil {
+				conn.Close()
+				delay = min(delay*2, reconnectMaxDelay)
+				continue
+			}
+		}
+		c.startPumps(conn)
+		return
+	}
+}
+
+// catchUp runs after a session is resumed. Each conversation fetches the
+// messages it missed, then unacknowledged sends are repeated under their
+// original request IDs so the server stores each one at most once.
+func (c *Client) catchUp() {
+	c.mu.RLock()
+	convs := make([]*Conversation, 0, len(c.conversations))
+	for _, conv := range c.conversations {
+		convs = append(convs, conv)
+	}
+	var resend []WebSocketMessage
+	for _, p := range c.pending {
+		p.conv.mu.RLock()
+		if p.entry.Status == statusPending {
+			resend = append(resend, p.msg)
+		}
+		p.conv.mu.RUnlock()
+	}
+	c.mu.RUnlock()
+
+	for _, conv := range convs {
+		conv.mu.RLock()
+		lastID := conv.lastMessageID()
+		skip := conv.Type == "ROOM" && !conv.Joined
+		conv.mu.RUnlock()
+		if lastID != "" && !skip {
+			c.requestNewer(conv, lastID)
+		}
+	}
+	for _, msg := range resend {
+		c.Send <- msg
+	}
+}
+
+// requestNewer asks the server for the messages that follow afterID.
+func (c *Client) requestNewer(conv *Conversation, afterID string) {
+	c.Send <- WebSocketMessage{Type: "fetch_history", Payload: FetchHistoryPayload{ConversationType: strings.ToLower(conv.Type), Name: conv.ID, AfterID: afterID}}
+}
+
+// appendNewer adds messages missed while disconnected to the end of a
+// conversation, continuing to fetch until it has caught up.
+func (c *Client) appendNewer(conv *Conversation, payload HistoryPayload, me string, isCurrent bool) {
+	conv.mu.Lock()
+	added := 0
+	for _, m := range payload.Messages {
+		if conv.findByID(m.ID) != nil {
+			continue
+		}
+		conv.History = append(conv.History, &HistoryEntry{ID: m.ID, Sender: displaySender(m.SenderNickname, m.Bot, me), Content: m.Content, Timestamp: m.Timestamp, Edited: m.Edited, Deleted: m.Deleted})
+		added++
+	}
+	conv.mu.Unlock()
+
+	if payload.HasMore && len(payload.Messages) > 0 {
+		c.requestNewer(conv, payload.Messages[len(payload.Messages)-1].ID)
+	}
+	if added > 0 && isCurrent {
+		c.redrawView()
+	}
+}
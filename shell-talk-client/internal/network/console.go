@@ -0,0 +1,158 @@
+package network
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// Console is the client's terminal I/O. On an interactive terminal it runs a
+// line editor in raw mode, so incoming messages are printed above the line
+// being typed and individual keystrokes can be observed. When stdin is not a
+// terminal it falls back to plain buffered line reading.
+type Console struct {
+	term     *term.Terminal
+	oldState *term.State
+	reader   *bufio.Reader
+
+	mu         sync.Mutex
+	onKeypress func(line string)
+	closed     bool
+}
+
+// NewConsole puts stdin into raw mode if it is a terminal.
+func NewConsole() *Console {
+	c := &Console{}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		c.reader = bufio.NewReader(os.Stdin)
+		return c
+	}
+
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		c.reader = bufio.NewReader(os.Stdin)
+		return c
+	}
+	c.oldState = oldState
+
+	rw := struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}
+	c.term = term.NewTerminal(rw, "")
+	if width, height, err := term.GetSize(fd); err == nil {
+		c.term.SetSize(width, height)
+	}
+	c.term.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		c.mu.Lock()
+		onKeypress := c.onKeypress
+		c.mu.Unlock()
+		if onKeypress != nil && key >= 32 {
+			onKeypress(line[:pos] + string(key) + line[pos:])
+		}
+		return "", 0, false
+	}
+	return c
+}
+
+// Interactive reports whether the console is running the raw-mode editor.
+func (c *Console) Interactive() bool {
+	return c.term != nil
+}
+
+// OnKeypress registers fn to be called with the edited line after every
+// printable keystroke. It is never called in non-interactive mode.
+func (c *Console) OnKeypress(fn func(line string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onKeypress = fn
+}
+
+// ReadLine reads one line of input. It returns io.EOF on Ctrl-C or Ctrl-D.
+func (c *Console) ReadLine() (string, error) {
+	if c.term != nil {
+		return c.term.ReadLine()
+	}
+	line, err := c.reader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Write prints output above the prompt and any partially typed line.
+func (c *Console) Write(p []byte) (int, error) {
+	if c.term != nil {
+		return c.term.Write(p)
+	}
+	return os.Stdout.Write(p)
+}
+
+// SetPrompt changes the prompt. In non-interactive mode the prompt is
+// printed immediately, matching a plain line-based terminal.
+func (c *Console) SetPrompt(prompt string) {
+	if c.term == nil {
+		fmt.Print(prompt)
+		return
+	}
+	c.term.SetPrompt(prompt)
+	// An empty write repaints the prompt and the line being edited.
+	c.term.Write(nil)
+}
+
+// ClearScreen clears the terminal.
+func (c *Console) ClearScreen() {
+	if c.term != nil {
+		c.term.Write([]byte("\x1b[2J\x1b[H"))
+		return
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/c", "cls")
+	} else {
+		cmd = exec.Command("clear")
+	}
+	cmd.Stdout = os.Stdout
+	err := cmd.Run()
+	if err != nil {
+		fmt.Print(strings.Repeat("\n", 50))
+	}
+}
+
+// Close restores the terminal to its original mode. It is safe to call
+// more than once.
+func (c *Console) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	if c.oldState != nil {
+		term.Restore(int(os.Stdin.Fd()), c.oldState)
+	}
+}
+
+// ReadPassword reads a line without echoing it. Keystrokes are not passed to
+// the keypress callback meanwhile. When stdin is not a terminal echo cannot
+// be controlled, so it reads a plain line.
+func (c *Console) ReadPassword(prompt string) (string, error) {
+	if c.term != nil {
+		c.mu.Lock()
+		onKeypress := c.onKeypress
+		c.onKeypress = nil
+		c.mu.Unlock()
+		defer c.OnKeypress(onKeypress)
+		return c.term.ReadPassword(prompt)
+	}
+	fmt.Print(prompt)
+	return c.ReadLine()
+}
@@ -0,0 +1,124 @@
+package network
+
+import (
+	"fmt"
+	"time"
+)
+
+// Delivery states of a message sent by this client.
+const (
+	statusPending = "pending"
+	statusFailed  = "failed"
+)
+
+// HistoryEntry is a single chat message shown in a conversation.
+type HistoryEntry struct {
+	ID        string // Server message ID, empty until the server acknowledges it
+	RequestID string // Client-generated ID, only set for messages sent by this client
+	Sender    string // "Me" for messages sent by this user
+	Content   string
+	Timestamp time.Time
+	Status    string // "", statusPending or statusFailed
+	Edited    bool
+	Deleted   bool
+}
+
+// displaySender returns how a message's sender is shown: "Me" for the
+// user's own messages and marked as such for bots posting through incoming
+// webhooks, which may share a user's nickname.
+func displaySender(nickname string, bot bool, me string) string {
+	if bot {
+		return nickname + " [bot]"
+	}
+	if nickname == me {
+		return "Me"
+	}
+	return nickname
+}
+
+// String formats the entry as a line of chat output.
+func (e *HistoryEntry) String() string {
+	if e.Deleted {
+		return fmt.Sprintf("[%s] [%s]: (message deleted)", e.Timestamp.Local().Format("15:04:05"), e.Sender)
+	}
+	line := fmt.Sprintf("[%s] [%s]: %s", e.Timestamp.Local().Format("15:04:05"), e.Sender, e.Content)
+	if e.Edited {
+		line += " (edited)"
+	}
+	switch e.Status {
+	case statusPending:
+		line += " (sending...)"
+	case statusFailed:
+		line += " (failed, /retry to resend)"
+	}
+	return line
+}
+
+// pendingSend tracks a sent message until the server acks or rejects it.
+type pendingSend struct {
+	conv  *Conversation
+	entry *HistoryEntry
+	msg   WebSocketMessage
+}
+
+// findByID returns the entry with the given server message ID, or nil.
+// The caller must hold conv.mu.
+func (conv *Conversation) findByID(id string) *HistoryEntry {
+	for _, entry := range conv.History {
+		if entry.ID == id {
+			return entry
+		}
+	}
+	return nil
+}
+
+// nthFromEnd returns the n-th most recent acknowledged message (1-based),
+// optionally counting only messages sent by this user, or nil if there are
+// fewer than n.
+func (conv *Conversation) nthFromEnd(n int, mineOnly bool) *HistoryEntry {
+	conv.mu.RLock()
+	defer conv.mu.RUnlock()
+	for i := len(conv.History) - 1; i >= 0; i-- {
+		entry := conv.History[i]
+		if entry.ID == "" || entry.Deleted || (mineOnly && entry.Sender != "Me") {
+			continue
+		}
+		n--
+		if n == 0 {
+			return entry
+		}
+	}
+	return nil
+}
+
+// lastMessageID returns the server ID of the newest acknowledged message, or
+// "" if there is none. The caller must hold conv.mu.
+func (conv *Conversation) lastMessageID() string {
+	for i := len(conv.History) - 1; i >= 0; i-- {
+		if conv.History[i].ID != "" {
+			return conv.History[i].ID
+		}
+	}
+	return ""
+}
+
+// removeDuplicates keeps only the first entry with the given server ID and
+// reports whether any were removed.
+func (conv *Conversation) removeDuplicates(id string) bool {
+	conv.mu.Lock()
+	defer conv.mu.Unlock()
+	kept := conv.History[:0]
+	found, removed := false, false
+	for _, entry := range conv.History {
+		if entry.ID == id {
+			if found {
+				removed = true
+				continue
+			}
+			found = true
+		}
+		kept = append(kept, entry)
+	}
+	conv.History = kept
+	return removed
+}
@@ -0,0 +1,90 @@
+package network
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultInviteDuration is how long invites stay valid unless a duration is given.
+const defaultInviteDuration = 24 * time.Hour
+
+// handleInviteCommand parses /invite, /invitecode, /accept, /invites and /revoke.
+func (c *Client) handleInviteCommand(command string, parts []string) {
+	switch command {
+	case "/invite":
+		if len(parts) < 3 || len(parts) > 4 {
+			c.printToScreen("[ERROR] Usage: /invite <room_name> <nickname> [duration, e.g. 2h]")
+			return
+		}
+		duration, ok := c.parseInviteDuration(parts[3:])
+		if !ok {
+			return
+		}
+		c.Send <- WebSocketMessage{Type: "create_invite", Payload: CreateInvitePayload{RoomName: parts[1], Nickname: parts[2], DurationSeconds: duration}}
+	case "/invitecode":
+		if len(parts) < 2 || len(parts) > 4 {
+			c.printToScreen("[ERROR] Usage: /invitecode <room_name> [max_uses, 0 for unlimited] [duration, e.g. 2h]")
+			return
+		}
+		maxUses := 1
+		if len(parts) > 2 {
+			n, err := strconv.Atoi(parts[2])
+			if err != nil || n < 0 {
+				c.printToScreen("[ERROR] max_uses must be a number, 0 for unlimited.")
+				return
+			}
+			maxUses = n
+		}
+		duration, ok := c.parseInviteDuration(parts[min(len(parts), 3):])
+		if !ok {
+			return
+		}
+		c.Send <- WebSocketMessage{Type: "create_invite", Payload: CreateInvitePayload{RoomName: parts[1], MaxUses: maxUses, DurationSeconds: duration}}
+	case "/accept":
+		if len(parts) != 2 {
+			c.printToScreen("[ERROR] Usage: /accept <invite_code>")
+			return
+		}
+		c.Send <- WebSocketMessage{Type: "accept_invite", Payload: AcceptInvitePayload{Code: strings.ToUpper(parts[1])}}
+	case "/invites":
+		if len(parts) != 2 {
+			c.printToScreen("[ERROR] Usage: /invites <room_name>")
+			return
+		}
+		c.Send <- WebSocketMessage{Type: "list_invites", Payload: ListInvitesPayload{RoomName: parts[1]}}
+	case "/revoke":
+		if len(parts) != 3 {
+			c.printToScreen("[ERROR] Usage: /revoke <room_name> <invite_code>")
+			return
+		}
+		c.Send <- WebSocketMessage{Type: "revoke_invite", Payload: RevokeInvitePayload{RoomName: parts[1], Code: strings.ToUpper(parts[2])}}
+	}
+}
+
+// parseInviteDuration reads an optional invite duration in seconds, falling
+// back to defaultInviteDuration.
+func (c *Client) parseInviteDuration(args []string) (int64, bool) {
+	if len(args) == 0 {
+		return int64(defaultInviteDuration / time.Second), true
+	}
+	duration, err := time.ParseDuration(args[0])
+	if err != nil || duration < time.Second {
+		c.printToScreen("[ERROR] Duration must look like 30m or 2h.")
+		return 0, false
+	}
+	return int64(duration / time.Second), true
+}
+
+// describeInvite renders an invite's remaining uses and expiry.
+func describeInvite(maxUses, uses int, expiresAt *time.Time) string {
+	usesText := "unlimited uses"
+	if maxUses > 0 {
+		usesText = fmt.Sprintf("%d of %d uses left", maxUses-uses, maxUses)
+	}
+	if expiresAt == nil {
+		return usesText + ", never expires"
+	}
+	return fmt.Sprintf("%s, expires %s", usesText, expiresAt.Local().Format("2006-01-02 15:04"))
+}
@@ -0,0 +1,87 @@
+package network
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// handleModerationCommand parses /kick, /ban, /unban, /mute and /unmute.
+func (c *Client) handleModerationCommand(command string, parts []string) {
+	if len(parts) < 3 {
+		switch command {
+		case "/kick":
+			c.printToScreen("[ERROR] Usage: /kick <room_name> <nickname> [reason]")
+		case "/ban":
+			c.printToScreen("[ERROR] Usage: /ban <room_name> <nickname> [duration, e.g. 30m] [reason]")
+		case "/mute":
+			c.printToScreen("[ERROR] Usage: /mute <room_name> <nickname> [duration, e.g. 10m]")
+		default:
+			c.printToScreen(fmt.Sprintf("[ERROR] Usage: %s <room_name> <nickname>", command))
+		}
+		return
+	}
+
+	payload := ModerationPayload{RoomName: parts[1], Nickname: parts[2]}
+	rest := parts[3:]
+
+	// Bans and mutes take an optional leading duration; anything else is the reason.
+	if command == "/ban" || command == "/mute" {
+		if len(rest) > 0 {
+			if duration, err := time.ParseDuration(rest[0]); err == nil {
+				if duration < time.Second {
+					c.printToScreen("[ERROR] Duration must be at least 1s.")
+					return
+				}
+				payload.DurationSeconds = int64(duration / time.Second)
+				rest = rest[1:]
+			}
+		}
+	}
+	if command == "/kick" || command == "/ban" {
+		payload.Reason = strings.Join(rest, " ")
+	}
+
+	msgType := map[string]string{
+		"/kick":   "kick_member",
+		"/ban":    "ban_member",
+		"/unban":  "unban_member",
+		"/mute":   "mute_member",
+		"/unmute": "unmute_member",
+	}[command]
+	c.Send <- WebSocketMessage{Type: msgType, Payload: payload}
+}
+
+// dropRoom marks a room as no longer joined, returning to the lobby if it is
+// the current conversation.
+func (c *Client) dropRoom(name string) {
+	conv := c.getOrCreateConversation(name, "ROOM")
+	conv.mu.Lock()
+	conv.Joined = false
+	conv.mu.Unlock()
+
+	c.mu.Lock()
+	wasCurrent := c.currentConversation == conv
+	if wasCurrent {
+		c.currentConversation = nil
+	}
+	c.mu.Unlock()
+
+	if wasCurrent {
+		c.redrawView()
+	}
+}
+
+// roleBadge renders a member's room role as a prefix for the member list.
+func roleBadge(role string) string {
+	switch role {
+	case "owner":
+		return "[owner] "
+	case "admin":
+		return "[admin] "
+	case "read_only":
+		return "[read-only] "
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,40 @@
+package network
+
+// removeConversation forgets a conversation entirely, returning to the lobby
+// if it is the current one.
+func (c *Client) removeConversation(name, convType string) {
+	key := convType + "_" + name
+	c.mu.Lock()
+	conv, exists := c.conversations[key]
+	delete(c.conversations, key)
+	wasCurrent := exists && c.currentConversation == conv
+	if wasCurrent {
+		c.currentConversation = nil
+	}
+	c.mu.Unlock()
+
+	if wasCurrent {
+		c.redrawView()
+	}
+}
+
+// renameConversation moves a conversation, with its history, to a new name.
+func (c *Client) renameConversation(convType, oldName, newName string) {
+	oldKey := convType + "_" + oldName
+	c.mu.Lock()
+	conv, exists := c.conversations[oldKey]
+	if exists {
+		delete(c.conversations, oldKey)
+		conv.mu.Lock()
+		conv.ID = newName
+		conv.typing = nil
+		conv.mu.Unlock()
+		c.conversations[convType+"_"+newName] = conv
+	}
+	isCurrent := exists && c.currentConversation == conv
+	c.mu.Unlock()
+
+	if isCurrent {
+		c.redrawView()
+	}
+}
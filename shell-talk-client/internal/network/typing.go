@@ -0,0 +1,86 @@
+package network
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// typingRefreshInterval is how often typing_start is re-sent while the
+	// user keeps typing. The server forgets a typist shortly after this.
+	typingRefreshInterval = 3 * time.Second
+	// typingDisplayTimeout hides an indicator whose typing_stop was lost.
+	typingDisplayTimeout = 6 * time.Second
+)
+
+// handleKeypress notifies the current conversation that the user is typing,
+// at most once per typingRefreshInterval. Commands are not announced.
+func (c *Client) handleKeypress(line string) {
+	if strings.HasPrefix(line, "/") {
+		return
+	}
+
+	c.mu.Lock()
+	conv := c.currentConversation
+	if conv == nil || (c.typingConv == conv && time.Since(c.typingSentAt) < typingRefreshInterval) {
+		c.mu.Unlock()
+		return
+	}
+	c.typingConv = conv
+	c.typingSentAt = time.Now()
+	c.mu.Unlock()
+
+	c.Send <- WebSocketMessage{Type: "typing_start", Payload: TypingPayload{ConversationType: strings.ToLower(conv.Type), Name: conv.ID}}
+}
+
+// stopTyping clears a typing indicator we previously started.
+func (c *Client) stopTyping() {
+	c.mu.Lock()
+	conv := c.typingConv
+	c.typingConv = nil
+	c.mu.Unlock()
+
+	if conv != nil {
+		c.Send <- WebSocketMessage{Type: "typing_stop", Payload: TypingPayload{ConversationType: strings.ToLower(conv.Type), Name: conv.ID}}
+	}
+}
+
+func (conv *Conversation) setTyping(nickname string, typing bool) {
+	conv.mu.Lock()
+	defer conv.mu.Unlock()
+	if conv.typing == nil {
+		conv.typing = make(map[string]time.Time)
+	}
+	if typing {
+		conv.typing[nickname] = time.Now().Add(typingDisplayTimeout)
+	} else {
+		delete(conv.typing, nickname)
+	}
+}
+
+// typingLabel describes who is typing, e.g. "alice is typing…", or returns
+// an empty string if nobody is.
+func (conv *Conversation) typingLabel() string {
+	conv.mu.RLock()
+	defer conv.mu.RUnlock()
+
+	now := time.Now()
+	var names []string
+	for nickname, until := range conv.typing {
+		if now.Before(until) {
+			names = append(names, nickname)
+		}
+	}
+	sort.Strings(names)
+
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("%s is typing…", names[0])
+	default:
+		return fmt.Sprintf("%s are typing…", strings.Join(names, ", "))
+	}
+}
@@ -0,0 +1,142 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// handleWebhookCommand parses /webhook add, list, remove and failures.
+func (c *Client) handleWebhookCommand(parts []string) {
+	if len(parts) < 3 {
+		c.printToScreen("[ERROR] Usage: /webhook <add|list|remove|failures> <room_name> ...")
+		return
+	}
+	subcommand, room := parts[1], parts[2]
+	switch subcommand {
+	case "add":
+		if len(parts) < 4 || len(parts) > 5 {
+			c.printToScreen("[ERROR] Usage: /webhook add <room_name> <url> [event,event,... (default all)]")
+			return
+		}
+		payload := CreateWebhookPayload{RoomName: room, URL: parts[3]}
+		if len(parts) == 5 {
+			payload.Events = strings.Split(parts[4], ",")
+		}
+		c.Send <- WebSocketMessage{Type: "create_webhook", Payload: payload}
+	case "list":
+		c.Send <- WebSocketMessage{Type: "list_webhooks", Payload: ListWebhooksPayload{RoomName: room}}
+	case "remove":
+		if len(parts) != 4 {
+			c.printToScreen("[ERROR] Usage: /webhook remove <room_name> <webhook_id>")
+			return
+		}
+		c.Send <- WebSocketMessage{Type: "delete_webhook", Payload: DeleteWebhookPayload{RoomName: room, ID: parts[3]}}
+	case "failures":
+		c.Send <- WebSocketMessage{Type: "list_webhook_failures", Payload: ListWebhookFailuresPayload{RoomName: room}}
+	default:
+		c.printToScreen("[ERROR] Usage: /webhook <add|list|remove|failures> <room_name> ...")
+	}
+}
+
+// handleIncomingWebhookCommand parses /incoming add, list and remove.
+func (c *Client) handleIncomingWebhookCommand(parts []string) {
+	if len(parts) < 3 {
+		c.printToScreen("[ERROR] Usage: /incoming <add|list|remove> <room_name> ...")
+		return
+	}
+	subcommand, room := parts[1], parts[2]
+	switch subcommand {
+	case "add":
+		if len(parts) < 4 {
+			c.printToScreen("[ERROR] Usage: /incoming add <room_name> <bot_name>")
+			return
+		}
+		c.Send <- WebSocketMessage{Type: "create_incoming_webhook", Payload: CreateIncomingWebhookPayload{RoomName: room, Name: strings.Join(parts[3:], " ")}}
+	case "list":
+		c.Send <- WebSocketMessage{Type: "list_incoming_webhooks", Payload: ListIncomingWebhooksPayload{RoomName: room}}
+	case "remove":
+		if len(parts) != 4 {
+			c.printToScreen("[ERROR] Usage: /incoming remove <room_name> <webhook_id>")
+			return
+		}
+		c.Send <- WebSocketMessage{Type: "delete_incoming_webhook", Payload: DeleteIncomingWebhookPayload{RoomName: room, ID: parts[3]}}
+	default:
+		c.printToScreen("[ERROR] Usage: /incoming <add|list|remove> <room_name> ...")
+	}
+}
+
+// incomingWebhookURL turns the path of an incoming webhook into a URL on the
+// server this client is connected to.
+func (c *Client) incomingWebhookURL(path string) string {
+	u, err := url.Parse(c.serverURL)
+	if err != nil {
+		return path
+	}
+	switch u.Scheme {
+	case "wss":
+		u.Scheme = "https"
+	default:
+		u.Scheme = "http"
+	}
+	u.Path, u.RawQuery = path, ""
+	return u.String()
+}
+
+// handleWebhookMessage shows the server's answers to /webhook and /incoming
+// commands.
+func (c *Client) handleWebhookMessage(msgType string, payloadBytes []byte, timestamp string) {
+	switch msgType {
+	case "webhook_created":
+		var payload WebhookCreatedPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		c.printToScreen(fmt.Sprintf("[SYSTEM] Webhook %s added to '%s' for %s.\n  Signing secret (shown only once): %s",
+			payload.Webhook.ID, payload.RoomName, strings.Join(payload.Webhook.Events, ", "), payload.Secret))
+
+	case "webhook_list":
+		var payload WebhookListPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		var builder strings.Builder
+		builder.WriteString(fmt.Sprintf("\r[%s] [Webhooks for %s]:\n", timestamp, payload.RoomName))
+		if len(payload.Webhooks) == 0 {
+			builder.WriteString("  No webhooks.")
+		}
+		for _, webhook := range payload.Webhooks {
+			builder.WriteString(fmt.Sprintf("  - %s %s (%s) by %s\n", webhook.ID, webhook.URL, strings.Join(webhook.Events, ", "), webhook.CreatedBy))
+		}
+		c.printToScreen(builder.String())
+
+	case "webhook_failures":
+		var payload WebhookFailuresPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		var builder strings.Builder
+		builder.WriteString(fmt.Sprintf("\r[%s] [Failed webhook deliveries for %s]:\n", timestamp, payload.RoomName))
+		if len(payload.Failures) == 0 {
+			builder.WriteString("  No failed deliveries.")
+		}
+		for _, failure := range payload.Failures {
+			builder.WriteString(fmt.Sprintf("  - %s %s to %s after %d attempts: %s\n", failure.FailedAt.Local().Format("2006-01-02 15:04"), failure.Event, failure.URL, failure.Attempts, failure.LastError))
+		}
+		c.printToScreen(builder.String())
+
+	case "incoming_webhook_created":
+		var payload IncomingWebhookCreatedPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		c.printToScreen(fmt.Sprintf("[SYSTEM] Incoming webhook %s added to '%s', posting as '%s'.\n  POST {\"content\": \"...\"} to this URL (shown only once): %s",
+			payload.Webhook.ID, payload.RoomName, payload.Webhook.Name, c.incomingWebhookURL(payload.Path)))
+
+	case "incoming_webhook_list":
+		var payload IncomingWebhookListPayload
+		_ = json.Unmarshal(payloadBytes, &payload)
+		var builder strings.Builder
+		builder.WriteString(fmt.Sprintf("\r[%s] [Incoming webhooks for %s]:\n", timestamp, payload.RoomName))
+		if len(payload.Webhooks) == 0 {
+			builder.WriteString("  No incoming webhooks.")
+		}
+		for _, webhook := range payload.Webhooks {
+			builder.WriteString(fmt.Sprintf("  - %s '%s' by %s\n", webhook.ID, webhook.Name, webhook.CreatedBy))
+		}
+		c.printToScreen(builder.String())
+	}
+}
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"shell-talk-server/internal/config"
 	"shell-talk-server/internal/repository/postgres"
+	"syscall"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -13,7 +18,7 @@ import (
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool { return true },
+	CheckOrigin:     func(r *http.Request) bool { return true },
 }
 
 func main() {
@@ -29,12 +34,27 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to initialize app: %v", err)
 	}
-	defer cleanup()
 
-	go app.Hub.Run()
+	hubCtx, stopHub := context.WithCancel(context.Background())
+	hubDone := make(chan struct{})
+	go func() {
+		app.Hub.Run(hubCtx)
+		close(hubDone)
+	}()
+
+	webhooksCtx, stopWebhooks := context.WithCancel(context.Background())
+	webhooksDone := make(chan struct{})
+	go func() {
+		app.Webhooks.Run(webhooksCtx)
+		close(webhooksDone)
+	}()
 
 	r := mux.NewRouter()
 	r.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		if !app.Hub.Accepting() {
+			http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			log.Printf("failed to upgrade connection: %v", err)
@@ -42,9 +62,43 @@ func main() {
 		}
 		app.Hub.ServeWs(conn)
 	})
+	app.API.Register(r)
+
+	srv := &http.Server{Addr: ":8080", Handler: r}
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Println("Server started on :8080")
+		serveErr <- srv.ListenAndServe()
+	}()
 
-	log.Println("Server started on :8080")
-	if err := http.ListenAndServe(":8080", r); err != nil {
-		log.Fatalf("failed to start server: %v", err)
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	exitCode := 0
+	select {
+	case err := <-serveErr:
+		log.Printf("failed to start server: %v", err)
+		exitCode = 1
+	case <-signalCtx.Done():
+		log.Println("Shutdown signal received.")
+	}
+	// A second signal kills the process without waiting for the shutdown.
+	stopSignals()
+
+	// Stop accepting connections, let the hub flush pending writes and close
+	// the WebSockets, then stop the hub and the webhook sender before closing
+	// the database pools. Undelivered webhooks stay queued for the next start.
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+	if err := srv.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("error shutting down http server: %v", err)
+	}
+	if err := app.Hub.Shutdown(ctx); err != nil {
+		log.Printf("error shutting down hub: %v", err)
 	}
+	cancel()
+	stopHub()
+	<-hubDone
+	stopWebhooks()
+	<-webhooksDone
+	cleanup()
+	log.Println("Server stopped.")
+	os.Exit(exitCode)
 }
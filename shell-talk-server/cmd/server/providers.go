@@ -3,9 +3,11 @@ package main
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"shell-talk-server/internal/config"
 	"shell-talk-server/internal/repository/mongo"
 	"shell-talk-server/internal/repository/postgres"
+	"shell-talk-server/internal/service"
 
 	mongodriver "go.mongodb.org/mongo-driver/mongo"
 )
@@ -28,6 +30,22 @@ func provideMongoDB(ctx context.Context, cfg *config.Config) (*mongodriver.Datab
 	return db, cleanup, nil
 }
 
+// provideBroker picks how hub events reach other server instances.
+func provideBroker(cfg *config.Config, db *sql.DB) (service.IBroker, func(), error) {
+	switch cfg.Broker {
+	case "local":
+		return service.NewLocalBroker(), func() {}, nil
+	case "postgres":
+		broker, err := postgres.NewBroker(db, cfg.PostgresURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		return broker, func() { broker.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown broker %q", cfg.Broker)
+	}
+}
+
 func provideContext() (context.Context, func()) {
 	ctx, cancel := context.WithCancel(context.Background())
 	return ctx, func() { cancel() }
@@ -5,6 +5,7 @@ package main
 
 import (
 	"shell-talk-server/internal/config"
+	"shell-talk-server/internal/handler"
 	"shell-talk-server/internal/hub"
 	"shell-talk-server/internal/repository/mongo"
 	"shell-talk-server/internal/repository/postgres"
@@ -15,7 +16,9 @@ import (
 
 // App is the main application container.
 type App struct {
-	Hub *hub.Hub
+	Hub      *hub.Hub
+	API      *handler.API
+	Webhooks *service.WebhookSender
 }
 
 // InitializeApp creates a new application.
@@ -27,6 +30,7 @@ func InitializeApp() (*App, func(), error) {
 			provideContext,
 			providePostgresDB,
 			provideMongoDB,
+			provideBroker,
 		),
 		// Repository Providers
 		wire.NewSet(
@@ -38,17 +42,42 @@ func InitializeApp() (*App, func(), error) {
 
 			mongo.NewMessageRepository,
 			wire.Bind(new(service.IMessageRepository), new(*mongo.MessageRepository)),
+
+			postgres.NewPresenceRepository,
+			wire.Bind(new(service.IPresenceRepository), new(*postgres.PresenceRepository)),
+
+			mongo.NewReadCursorRepository,
+			wire.Bind(new(service.IReadCursorRepository), new(*mongo.ReadCursorRepository)),
+
+			postgres.NewWebhookRepository,
+			wire.Bind(new(service.IWebhookRepository), new(*postgres.WebhookRepository)),
 		),
 		// Service Providers
 		wire.NewSet(
+			service.NewValidator,
+			service.NewAttemptGuard,
+
 			service.NewUserService,
 			wire.Bind(new(service.IUserService), new(*service.UserService)),
 
 			service.NewRoomService,
 			wire.Bind(new(service.IRoomService), new(*service.RoomService)),
+
+			service.NewPresenceService,
+			wire.Bind(new(service.IPresenceService), new(*service.PresenceService)),
+
+			service.NewSessionService,
+			wire.Bind(new(service.ISessionService), new(*service.SessionService)),
+
+			service.NewWebhookService,
+			wire.Bind(new(service.IWebhookService), new(*service.WebhookService)),
+			service.NewWebhookSender,
 		),
 		// Hub Provider
+		hub.NewRateLimiter,
 		hub.NewHub,
+		// HTTP API Provider
+		handler.NewAPI,
 		// App Provider
 		wire.NewSet(
 			wire.Struct(new(App), "*"),
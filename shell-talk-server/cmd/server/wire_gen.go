@@ -8,6 +8,7 @@ package main
 
 import (
 	"shell-talk-server/internal/config"
+	"shell-talk-server/internal/handler"
 	"shell-talk-server/internal/hub"
 	"shell-talk-server/internal/repository/mongo"
 	"shell-talk-server/internal/repository/postgres"
@@ -24,9 +25,15 @@ func InitializeApp() (*App, func(), error) {
 		return nil, nil, err
 	}
 	userRepository := postgres.NewUserRepository(db)
-	userService := service.NewUserService(userRepository)
 	roomRepository := postgres.NewRoomRepository(db)
-	roomService := service.NewRoomService(roomRepository)
+	validator, err := service.NewValidator(configConfig)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	attemptGuard := service.NewAttemptGuard(configConfig)
+	userService := service.NewUserService(userRepository, roomRepository, validator, attemptGuard)
+	roomService := service.NewRoomService(roomRepository, userRepository, validator, attemptGuard)
 	context, cleanup2 := provideContext()
 	database, cleanup3, err := provideMongoDB(context, configConfig)
 	if err != nil {
@@ -35,11 +42,30 @@ func InitializeApp() (*App, func(), error) {
 		return nil, nil, err
 	}
 	messageRepository := mongo.NewMessageRepository(database)
-	hubHub := hub.NewHub(userService, roomService, messageRepository)
+	readCursorRepository := mongo.NewReadCursorRepository(database)
+	presenceRepository := postgres.NewPresenceRepository(db)
+	presenceService := service.NewPresenceService(configConfig, presenceRepository, roomRepository, messageRepository)
+	sessionService := service.NewSessionService(configConfig, userRepository)
+	webhookRepository := postgres.NewWebhookRepository(db)
+	webhookService := service.NewWebhookService(configConfig, roomService, webhookRepository)
+	rateLimiter := hub.NewRateLimiter(configConfig)
+	iBroker, cleanup4, err := provideBroker(configConfig, db)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	hubHub := hub.NewHub(userService, roomService, messageRepository, readCursorRepository, presenceService, sessionService, webhookService, rateLimiter, iBroker, configConfig)
+	api := handler.NewAPI(userService, roomService, messageRepository, sessionService, webhookService, rateLimiter, hubHub)
+	webhookSender := service.NewWebhookSender(configConfig, webhookRepository)
 	app := &App{
-		Hub: hubHub,
+		Hub:      hubHub,
+		API:      api,
+		Webhooks: webhookSender,
 	}
 	return app, func() {
+		cleanup4()
 		cleanup3()
 		cleanup2()
 		cleanup()
@@ -50,5 +76,7 @@ func InitializeApp() (*App, func(), error) {
 
 // App is the main application container.
 type App struct {
-	Hub *hub.Hub
+	Hub      *hub.Hub
+	API      *handler.API
+	Webhooks *service.WebhookSender
 }
@@ -0,0 +1,58 @@
+// Command webhook-echo is a stand-in webhook receiver for local testing. It
+// checks each request's signature and prints the event it carries.
+//
+//	go run ./cmd/webhook-echo -addr :9000 -secret <secret from /webhook add>
+//
+// With -fail set it answers every request with that status, to exercise
+// retries and dead letters. Webhooks only reach public addresses by
+// default; run the server with WEBHOOK_ALLOW_PRIVATE_HOSTS=true to send
+// them to this receiver on localhost.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"shell-talk-server/internal/domain"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "address to listen on")
+	secret := flag.String("secret", "", "webhook secret; signatures are not checked if empty")
+	fail := flag.Int("fail", 0, "status to answer every request with, e.g. 500")
+	flag.Parse()
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if *secret != "" {
+			err := domain.VerifyWebhook(*secret, r.Header.Get(domain.WebhookSignatureHeader), r.Header.Get(domain.WebhookTimestampHeader), body, 5*time.Minute)
+			if err != nil {
+				log.Printf("rejected %s: %v", r.Header.Get(domain.WebhookDeliveryHeader), err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, body, "", "  "); err != nil {
+			pretty.Write(body)
+		}
+		log.Printf("%s %s\n%s", r.Header.Get(domain.WebhookEventHeader), r.Header.Get(domain.WebhookDeliveryHeader), pretty.String())
+		if *fail != 0 {
+			w.WriteHeader(*fail)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Printf("Listening for webhooks on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// Broker event kinds.
+const (
+	// BrokerDeliver asks every instance to pass Message to those of UserIDs
+	// connected to it.
+	BrokerDeliver = "deliver"
+	// BrokerSessionStarted announces that UserIDs[0] logged in on Node, so
+	// any other instance holding a session for them closes it.
+	BrokerSessionStarted = "session_started"
+)
+
+// BrokerEvent is a hub event shared between server instances.
+type BrokerEvent struct {
+	Kind       string          `json:"kind"`
+	Node       string          `json:"node"` // Instance that published the event
+	UserIDs    []uuid.UUID     `json:"user_ids"`
+	Message    json.RawMessage `json:"message,omitempty"`     // WebSocket message to deliver
+	BestEffort bool            `json:"best_effort,omitempty"` // May be dropped for a slow client, e.g. typing updates
+}
@@ -1,8 +1,13 @@
 package domain
 
 import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -12,6 +17,93 @@ type ChatMessage struct {
 	ConversationID string             `bson:"conversation_id"`
 	SenderID       string             `bson:"sender_id"`
 	SenderNickname string             `bson:"sender_nickname"`
+	Bot            bool               `bson:"bot,omitempty"` // Posted through an incoming webhook
 	Content        string             `bson:"content"`
 	Timestamp      time.Time          `bson:"timestamp"`
+	RequestID      string             `bson:"request_id,omitempty"` // Client-generated, used to dedupe retries
+	Version        int                `bson:"version"`              // Incremented on every edit
+	Edits          []MessageEdit      `bson:"edits,omitempty"`      // Previous contents, oldest first
+	EditedAt       *time.Time         `bson:"edited_at,omitempty"`
+	Deleted        bool               `bson:"deleted,omitempty"`
+	DeletedAt      *time.Time         `bson:"deleted_at,omitempty"`
+}
+
+// MessageEdit records the content a message had before an edit.
+type MessageEdit struct {
+	Version  int       `bson:"version"`
+	Content  string    `bson:"content"`
+	EditedAt time.Time `bson:"edited_at"`
+}
+
+// MessageCursor marks a position in a conversation's history. Messages are
+// ordered by timestamp, with the ObjectID breaking ties between messages
+// stored within the same instant.
+type MessageCursor struct {
+	Timestamp time.Time
+	ID        primitive.ObjectID
+}
+
+// NewMessageCursor returns a cursor pointing at the given message.
+func NewMessageCursor(message *ChatMessage) *MessageCursor {
+	return &MessageCursor{Timestamp: message.Timestamp, ID: message.ID}
+}
+
+// Encode serializes the cursor into an opaque string for clients.
+func (c *MessageCursor) Encode() string {
+	return strconv.FormatInt(c.Timestamp.UnixMilli(), 10) + "_" + c.ID.Hex()
+}
+
+// ParseMessageCursor decodes a cursor previously produced by Encode.
+func ParseMessageCursor(s string) (*MessageCursor, error) {
+	millis, hex, ok := strings.Cut(s, "_")
+	if !ok {
+		return nil, errors.New("malformed cursor")
+	}
+	ms, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	return &MessageCursor{Timestamp: time.UnixMilli(ms), ID: id}, nil
+}
+
+// DMConversationID returns the conversation ID for a DM between two users.
+// It is the same regardless of argument order.
+func DMConversationID(userID1, userID2 uuid.UUID) string {
+	ids := []string{userID1.String(), userID2.String()}
+	sort.Strings(ids)
+	return strings.Join(ids, "_")
+}
+
+// ParseDMConversationID splits a DM conversation ID into its two user IDs.
+// It reports false for room conversation IDs.
+func ParseDMConversationID(convoID string) (uuid.UUID, uuid.UUID, bool) {
+	first, second, ok := strings.Cut(convoID, "_")
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	firstID, err := uuid.Parse(first)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, false
+	}
+	secondID, err := uuid.Parse(second)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, false
+	}
+	return firstID, secondID, true
+}
+
+// DMPeerID returns the participant of a DM conversation other than self.
+func DMPeerID(convoID string, self uuid.UUID) (uuid.UUID, bool) {
+	first, second, ok := ParseDMConversationID(convoID)
+	if !ok {
+		return uuid.Nil, false
+	}
+	if first == self {
+		return second, true
+	}
+	return first, true
 }
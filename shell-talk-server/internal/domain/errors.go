@@ -0,0 +1,32 @@
+package domain
+
+import "errors"
+
+// ErrDuplicateMessage is returned when a message with the same sender and
+// client request ID has already been stored.
+var ErrDuplicateMessage = errors.New("duplicate message")
+
+// ErrMessageConflict is returned when a message changed between being read
+// and being updated.
+var ErrMessageConflict = errors.New("message was modified concurrently")
+
+// ErrRoomNotFound is returned when no room has the given name or ID.
+var ErrRoomNotFound = errors.New("room not found")
+
+// ErrInvalidCredentials is returned by login when the nickname or password
+// is wrong. Which of the two is not revealed.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ErrWebhookNotFound is returned when a room has no webhook with the given ID.
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// PermissionError reports that a user may not do something in a room, for
+// example because of their role or a mute. Its message is meant to be shown
+// to the user as is.
+type PermissionError struct {
+	Message string
+}
+
+func (e *PermissionError) Error() string {
+	return e.Message
+}
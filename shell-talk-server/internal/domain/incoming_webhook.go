@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxBotNameLength is the longest name an incoming webhook can post under.
+	MaxBotNameLength = 32
+	// MaxBotMessageLength is the longest message an incoming webhook can post.
+	MaxBotMessageLength = 8000
+	// IncomingWebhookPath is where messages are POSTed, followed by the token.
+	IncomingWebhookPath = "/api/v1/hooks/"
+)
+
+// IncomingWebhook lets anyone holding its token post to a room as a bot,
+// without an account. Only a hash of the token is stored.
+type IncomingWebhook struct {
+	ID        uuid.UUID `json:"id"`
+	RoomID    uuid.UUID `json:"room_id"`
+	Name      string    `json:"name"`
+	TokenHash string    `json:"-"`
+	CreatedBy uuid.UUID `json:"created_by"` // uuid.Nil once the creator's account is deleted
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewIncomingWebhook creates an incoming webhook posting under the given
+// name, and returns it with its token.
+func NewIncomingWebhook(roomID, createdBy uuid.UUID, name string) (*IncomingWebhook, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", errors.New("webhook name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > MaxBotNameLength {
+		return nil, "", fmt.Errorf("webhook name cannot be longer than %d characters", MaxBotNameLength)
+	}
+	if strings.ContainsFunc(name, unicode.IsControl) {
+		return nil, "", errors.New("webhook name cannot contain control characters")
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return &IncomingWebhook{
+		ID:        uuid.New(),
+		RoomID:    roomID,
+		Name:      name,
+		TokenHash: HashWebhookToken(token),
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}, token, nil
+}
+
+// HashWebhookToken returns the stored form of an incoming webhook token.
+func HashWebhookToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SenderID identifies the webhook as the sender of the messages it posts.
+// It cannot collide with a user ID.
+func (w *IncomingWebhook) SenderID() string {
+	return "webhook:" + w.ID.String()
+}
+
+// Info describes the webhook for clients, naming its creator.
+func (w *IncomingWebhook) Info(createdBy string) IncomingWebhookInfo {
+	return IncomingWebhookInfo{ID: w.ID.String(), Name: w.Name, CreatedBy: createdBy, CreatedAt: w.CreatedAt}
+}
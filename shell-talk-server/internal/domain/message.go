@@ -28,20 +28,29 @@ type LoginPayload struct {
 
 // LoginSuccessPayload is the payload for the 'login_success' message.
 type LoginSuccessPayload struct {
-	UserID   uuid.UUID `json:"user_id"`
-	Nickname string    `json:"nickname"`
+	UserID           uuid.UUID `json:"user_id"`
+	Nickname         string    `json:"nickname"`
+	SessionToken     string    `json:"session_token"` // Pass to 'resume' on a new connection
+	SessionExpiresAt time.Time `json:"session_expires_at"`
+}
+
+// ResumePayload is the payload for the 'resume' message.
+type ResumePayload struct {
+	SessionToken string `json:"session_token"`
 }
 
 // --- DM & Room Message Payloads ---
 
 // SendDirectMessagePayload SendDirectMessagePayload는 'send_direct_message' 요청의 페이로드입니다.
 type SendDirectMessagePayload struct {
+	RequestID         string `json:"request_id,omitempty"`
 	RecipientNickname string `json:"recipient_nickname"`
 	Content           string `json:"content"`
 }
 
 // DirectMessagePayload DirectMessagePayload는 'new_direct_message' 타입의 페이로드입니다.
 type DirectMessagePayload struct {
+	ID        string    `json:"id"`
 	Sender    string    `json:"sender"`
 	Content   string    `json:"content"`
 	Timestamp time.Time `json:"timestamp"`
@@ -49,24 +58,141 @@ type DirectMessagePayload struct {
 
 // SendRoomMessagePayload is the payload for the 'send_room_message' message.
 type SendRoomMessagePayload struct {
-	RoomName string `json:"room_name"`
-	Content  string `json:"content"`
+	RequestID string `json:"request_id,omitempty"`
+	RoomName  string `json:"room_name"`
+	Content   string `json:"content"`
 }
 
 // RoomMessagePayload is the payload for the 'room_message' message.
 type RoomMessagePayload struct {
+	ID             string    `json:"id"`
 	RoomName       string    `json:"room_name"`
 	SenderNickname string    `json:"sender_nickname"`
+	Bot            bool      `json:"bot,omitempty"` // The sender is an incoming webhook, not a user
 	Content        string    `json:"content"`
 	Timestamp      time.Time `json:"timestamp"`
 }
 
+// MessageAckPayload is the payload for the 'message_ack' message, confirming
+// that a sent message has been stored.
+type MessageAckPayload struct {
+	RequestID string    `json:"request_id"`
+	MessageID string    `json:"message_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// MessageRejectedPayload is the payload for the 'message_rejected' message.
+type MessageRejectedPayload struct {
+	RequestID string `json:"request_id"`
+	Reason    string `json:"reason"`
+}
+
+// --- Typing Payloads ---
+
+// TypingPayload is the payload for the 'typing_start' and 'typing_stop' messages.
+type TypingPayload struct {
+	ConversationType string `json:"conversation_type"` // "room" or "dm"
+	Name             string `json:"name"`
+}
+
+// TypingUpdatePayload is the payload for the 'typing_update' message.
+type TypingUpdatePayload struct {
+	ConversationType string `json:"conversation_type"`
+	Name             string `json:"name"` // Room name or DM peer nickname, as seen by the recipient
+	Nickname         string `json:"nickname"`
+	Typing           bool   `json:"typing"`
+}
+
+// --- Edit & Delete Payloads ---
+
+// EditMessagePayload is the payload for the 'edit_message' message.
+type EditMessagePayload struct {
+	MessageID string `json:"message_id"`
+	Content   string `json:"content"`
+}
+
+// DeleteMessagePayload is the payload for the 'delete_message' message.
+type DeleteMessagePayload struct {
+	MessageID string `json:"message_id"`
+}
+
+// MessageEditedPayload is the payload for the 'message_edited' message.
+type MessageEditedPayload struct {
+	MessageID        string    `json:"message_id"`
+	ConversationType string    `json:"conversation_type"`
+	Name             string    `json:"name"` // Room name or DM peer nickname, as seen by the recipient
+	Content          string    `json:"content"`
+	Version          int       `json:"version"`
+	EditedAt         time.Time `json:"edited_at"`
+}
+
+// MessageDeletedPayload is the payload for the 'message_deleted' message.
+type MessageDeletedPayload struct {
+	MessageID        string `json:"message_id"`
+	ConversationType string `json:"conversation_type"`
+	Name             string `json:"name"`
+}
+
+// --- History Payloads ---
+
+// FetchHistoryPayload is the payload for the 'fetch_history' message.
+type FetchHistoryPayload struct {
+	ConversationType string `json:"conversation_type"`  // "room" or "dm"
+	Name             string `json:"name"`               // Room name or DM peer nickname
+	Before           string `json:"before,omitempty"`   // Cursor from a previous 'history' response
+	AfterID          string `json:"after_id,omitempty"` // Fetch messages newer than this message ID instead
+	Limit            int64  `json:"limit,omitempty"`
+}
+
+// HistoryMessage is a single stored message returned in a 'history' payload.
+type HistoryMessage struct {
+	ID             string    `json:"id"`
+	SenderNickname string    `json:"sender_nickname"`
+	Bot            bool      `json:"bot,omitempty"`
+	Content        string    `json:"content"`
+	Timestamp      time.Time `json:"timestamp"`
+	Edited         bool      `json:"edited,omitempty"`
+	Deleted        bool      `json:"deleted,omitempty"`
+}
+
+// HistoryPayload is the payload for the 'history' message.
+type HistoryPayload struct {
+	ConversationType string           `json:"conversation_type"`
+	Name             string           `json:"name"`
+	Before           string           `json:"before,omitempty"`      // Echo of the request cursor
+	AfterID          string           `json:"after_id,omitempty"`    // Echo of the request message ID
+	Messages         []HistoryMessage `json:"messages"`              // Oldest to newest
+	NextCursor       string           `json:"next_cursor,omitempty"` // Pass as 'before' to page further back
+	HasMore          bool             `json:"has_more"`              // More messages exist past this page, in the direction requested
+}
+
+// --- Read State Payloads ---
+
+// MarkReadPayload is the payload for the 'mark_read' message.
+type MarkReadPayload struct {
+	ConversationType string `json:"conversation_type"` // "room" or "dm"
+	Name             string `json:"name"`
+}
+
+// UnreadConversation is the unread state of a single conversation.
+type UnreadConversation struct {
+	ConversationType string `json:"conversation_type"`
+	Name             string `json:"name"`
+	UnreadCount      int64  `json:"unread_count"`
+}
+
+// UnreadSummaryPayload is the payload for the 'unread_summary' message.
+type UnreadSummaryPayload struct {
+	Conversations []UnreadConversation `json:"conversations"`
+}
+
 // --- Room Management Payloads ---
 
 // CreateRoomPayload is the payload for the 'create_room' message.
 type CreateRoomPayload struct {
-	Name     string `json:"name"`
-	Password string `json:"password"`
+	Name       string `json:"name"`
+	Password   string `json:"password"`
+	Visibility string `json:"visibility,omitempty"` // Defaults to "password" if a password is given, else "public"
 }
 
 // JoinRoomPayload is the payload for the 'join_room' message.
@@ -87,14 +213,17 @@ type ListMembersPayload struct {
 
 // RoomMembersPayload is the payload for the 'room_members' message.
 type RoomMembersPayload struct {
-	RoomName string   `json:"room_name"`
-	Members  []string `json:"members"` // List of nicknames
+	RoomName string        `json:"room_name"`
+	Members  []*RoomMember `json:"members"`
 }
 
 // RoomInfo represents basic information about a room.
 type RoomInfo struct {
-	ID   string `json:"id"` // This is the UUID
-	Name string `json:"name"`
+	ID          string `json:"id"` // This is the UUID
+	Name        string `json:"name"`
+	Visibility  string `json:"visibility"`
+	Topic       string `json:"topic,omitempty"`
+	MemberCount int    `json:"member_count"`
 }
 
 // RoomListPayload is the payload for the 'room_list' message.
@@ -113,10 +242,317 @@ type LeaveSuccessPayload struct {
 	RoomID string `json:"room_id"`
 }
 
+// --- Moderation Payloads ---
+
+// ModerationPayload is the payload for the 'kick_member', 'ban_member',
+// 'unban_member', 'mute_member' and 'unmute_member' messages.
+type ModerationPayload struct {
+	RoomName        string `json:"room_name"`
+	Nickname        string `json:"nickname"`
+	Reason          string `json:"reason,omitempty"`
+	DurationSeconds int64  `json:"duration_seconds,omitempty"` // Bans and mutes only; 0 means permanent
+}
+
+// SetRolePayload is the payload for the 'set_role' message.
+type SetRolePayload struct {
+	RoomName string `json:"room_name"`
+	Nickname string `json:"nickname"`
+	Role     string `json:"role"` // "admin", "member" or "read_only"
+}
+
+// TransferOwnershipPayload is the payload for the 'transfer_ownership' message.
+type TransferOwnershipPayload struct {
+	RoomName string `json:"room_name"`
+	Nickname string `json:"nickname"`
+}
+
+// RoomNoticePayload is the payload for the 'room_notice' message, a system
+// notice shown inside a room's conversation.
+type RoomNoticePayload struct {
+	RoomName  string    `json:"room_name"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RoomRemovedPayload is the payload for the 'room_removed' message, sent to a
+// user who was removed from a room by someone else.
+type RoomRemovedPayload struct {
+	RoomName string `json:"room_name"`
+	Reason   string `json:"reason"`
+}
+
+// --- Room Settings Payloads ---
+
+// DeleteRoomPayload is the payload for the 'delete_room' message.
+type DeleteRoomPayload struct {
+	RoomName string `json:"room_name"`
+}
+
+// RenameRoomPayload is the payload for the 'rename_room' message.
+type RenameRoomPayload struct {
+	RoomName string `json:"room_name"`
+	NewName  string `json:"new_name"`
+}
+
+// ChangeRoomPasswordPayload is the payload for the 'change_room_password' message.
+type ChangeRoomPasswordPayload struct {
+	RoomName    string `json:"room_name"`
+	NewPassword string `json:"new_password"`
+}
+
+// SetVisibilityPayload is the payload for the 'set_visibility' message.
+type SetVisibilityPayload struct {
+	RoomName   string `json:"room_name"`
+	Visibility string `json:"visibility"`
+	Password   string `json:"password,omitempty"` // Required for "password"
+}
+
+// SetTopicPayload is the payload for the 'set_topic' message.
+type SetTopicPayload struct {
+	RoomName string `json:"room_name"`
+	Topic    string `json:"topic"`
+}
+
+// RoomDeletedPayload is the payload for the 'room_deleted' message.
+type RoomDeletedPayload struct {
+	RoomName  string `json:"room_name"`
+	DeletedBy string `json:"deleted_by"`
+}
+
+// RoomRenamedPayload is the payload for the 'room_renamed' message.
+type RoomRenamedPayload struct {
+	OldName   string `json:"old_name"`
+	NewName   string `json:"new_name"`
+	RenamedBy string `json:"renamed_by"`
+}
+
+// --- Invite Payloads ---
+
+// CreateInvitePayload is the payload for the 'create_invite' message.
+type CreateInvitePayload struct {
+	RoomName        string `json:"room_name"`
+	Nickname        string `json:"nickname,omitempty"`         // Set for a direct, single-use invite
+	MaxUses         int    `json:"max_uses,omitempty"`         // 0 means unlimited
+	DurationSeconds int64  `json:"duration_seconds,omitempty"` // 0 means the invite never expires
+}
+
+// InviteCreatedPayload is the payload for the 'invite_created' message.
+type InviteCreatedPayload struct {
+	RoomName  string     `json:"room_name"`
+	Code      string     `json:"code"`
+	Invitee   string     `json:"invitee,omitempty"`
+	MaxUses   int        `json:"max_uses"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// AcceptInvitePayload is the payload for the 'accept_invite' message.
+type AcceptInvitePayload struct {
+	Code string `json:"code"`
+}
+
+// ListInvitesPayload is the payload for the 'list_invites' message.
+type ListInvitesPayload struct {
+	RoomName string `json:"room_name"`
+}
+
+// InviteInfo describes an active invite in the 'invite_list' message.
+type InviteInfo struct {
+	Code      string     `json:"code"`
+	CreatedBy string     `json:"created_by"`
+	Invitee   string     `json:"invitee,omitempty"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// InviteListPayload is the payload for the 'invite_list' message.
+type InviteListPayload struct {
+	RoomName string       `json:"room_name"`
+	Invites  []InviteInfo `json:"invites"`
+}
+
+// RevokeInvitePayload is the payload for the 'revoke_invite' message.
+type RevokeInvitePayload struct {
+	RoomName string `json:"room_name"`
+	Code     string `json:"code"`
+}
+
+// --- Webhook Payloads ---
+
+// CreateWebhookPayload is the payload for the 'create_webhook' message.
+type CreateWebhookPayload struct {
+	RoomName string   `json:"room_name"`
+	URL      string   `json:"url"`
+	Events   []string `json:"events,omitempty"` // Empty subscribes to every event
+}
+
+// WebhookInfo describes a webhook in the 'webhook_created' and
+// 'webhook_list' messages.
+type WebhookInfo struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookCreatedPayload is the payload for the 'webhook_created' message. It
+// is the only time the signing secret is shown.
+type WebhookCreatedPayload struct {
+	RoomName string      `json:"room_name"`
+	Webhook  WebhookInfo `json:"webhook"`
+	Secret   string      `json:"secret"`
+}
+
+// ListWebhooksPayload is the payload for the 'list_webhooks' message.
+type ListWebhooksPayload struct {
+	RoomName string `json:"room_name"`
+}
+
+// WebhookListPayload is the payload for the 'webhook_list' message.
+type WebhookListPayload struct {
+	RoomName string        `json:"room_name"`
+	Webhooks []WebhookInfo `json:"webhooks"`
+}
+
+// DeleteWebhookPayload is the payload for the 'delete_webhook' message.
+type DeleteWebhookPayload struct {
+	RoomName string `json:"room_name"`
+	ID       string `json:"id"`
+}
+
+// CreateIncomingWebhookPayload is the payload for the 'create_incoming_webhook' message.
+type CreateIncomingWebhookPayload struct {
+	RoomName string `json:"room_name"`
+	Name     string `json:"name"` // Shown as the sender of posted messages
+}
+
+// IncomingWebhookInfo describes an incoming webhook.
+type IncomingWebhookInfo struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IncomingWebhookCreatedPayload is the payload for the
+// 'incoming_webhook_created' message. It is the only time the token is shown.
+type IncomingWebhookCreatedPayload struct {
+	RoomName string              `json:"room_name"`
+	Webhook  IncomingWebhookInfo `json:"webhook"`
+	Token    string              `json:"token"`
+	Path     string              `json:"path"` // Where to POST messages, relative to the server's address
+}
+
+// ListIncomingWebhooksPayload is the payload for the 'list_incoming_webhooks' message.
+type ListIncomingWebhooksPayload struct {
+	RoomName string `json:"room_name"`
+}
+
+// IncomingWebhookListPayload is the payload for the 'incoming_webhook_list' message.
+type IncomingWebhookListPayload struct {
+	RoomName string                `json:"room_name"`
+	Webhooks []IncomingWebhookInfo `json:"webhooks"`
+}
+
+// DeleteIncomingWebhookPayload is the payload for the 'delete_incoming_webhook' message.
+type DeleteIncomingWebhookPayload struct {
+	RoomName string `json:"room_name"`
+	ID       string `json:"id"`
+}
+
+// ListWebhookFailuresPayload is the payload for the 'list_webhook_failures' message.
+type ListWebhookFailuresPayload struct {
+	RoomName string `json:"room_name"`
+	Limit    int    `json:"limit,omitempty"`
+}
+
+// WebhookFailureInfo describes a delivery that was given up on.
+type WebhookFailureInfo struct {
+	ID        string    `json:"id"`
+	WebhookID string    `json:"webhook_id"`
+	URL       string    `json:"url"`
+	Event     string    `json:"event"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+// WebhookFailuresPayload is the payload for the 'webhook_failures' message.
+type WebhookFailuresPayload struct {
+	RoomName string               `json:"room_name"`
+	Failures []WebhookFailureInfo `json:"failures"`
+}
+
+// --- Presence Payloads ---
+
+// SetPresencePayload is the payload for the 'set_presence' message.
+type SetPresencePayload struct {
+	Status string `json:"status"` // "online" or "away"
+}
+
+// PresenceUpdatePayload is the payload for the 'presence_update' message.
+type PresenceUpdatePayload struct {
+	Nickname   string    `json:"nickname"`
+	Status     string    `json:"status"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// --- Account Payloads ---
+
+// ChangePasswordPayload is the payload for the 'change_password' message.
+type ChangePasswordPayload struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+// PasswordChangedPayload is the payload for the 'password_changed' message.
+// Earlier session tokens are no longer valid, so it carries a fresh one.
+type PasswordChangedPayload struct {
+	SessionToken     string    `json:"session_token"`
+	SessionExpiresAt time.Time `json:"session_expires_at"`
+}
+
+// ChangeNicknamePayload is the payload for the 'change_nickname' message.
+type ChangeNicknamePayload struct {
+	NewNickname string `json:"new_nickname"`
+}
+
+// NicknameChangedPayload is the payload for the 'nickname_changed' message.
+type NicknameChangedPayload struct {
+	OldNickname string `json:"old_nickname"`
+	NewNickname string `json:"new_nickname"`
+}
+
+// DeleteAccountPayload is the payload for the 'delete_account' message.
+type DeleteAccountPayload struct {
+	Password string `json:"password"`
+}
+
+// --- Flood Control Payloads ---
+
+// RateLimitedPayload is the payload for the 'rate_limited' message, sent in
+// place of handling a message that exceeded a rate limit.
+type RateLimitedPayload struct {
+	MessageType  string `json:"message_type"`
+	RequestID    string `json:"request_id,omitempty"` // Set if the dropped message carried one
+	RetryAfterMs int64  `json:"retry_after_ms"`
+}
+
+// ServerShutdownPayload is the payload for the 'server_shutdown' message,
+// sent to every connection before the server stops.
+type ServerShutdownPayload struct {
+	Content          string `json:"content"`
+	ReconnectAfterMs int64  `json:"reconnect_after_ms"` // Suggested wait before reconnecting
+}
+
 // --- System & Error Payloads ---
 
 // SystemPayload SystemPayload는 'system_message' 또는 'error_message' 타입의 페이로드입니다.
 type SystemPayload struct {
-	Content   string    `json:"content"`
-	Timestamp time.Time `json:"timestamp"`
+	Content    string    `json:"content"`
+	Timestamp  time.Time `json:"timestamp"`
+	Code       string    `json:"code,omitempty"`        // Machine-readable reason for errors, e.g. "too_short"
+	Field      string    `json:"field,omitempty"`       // Input the error refers to, e.g. "nickname"
+	RetryAfter int       `json:"retry_after,omitempty"` // Seconds to wait before retrying
 }
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RoomBan prevents a user from joining a room.
+type RoomBan struct {
+	RoomID    uuid.UUID  `json:"room_id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	BannedBy  uuid.UUID  `json:"banned_by"` // uuid.Nil once the moderator's account is deleted
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Nil for a permanent ban
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RoomMute prevents a member from posting in a room.
+type RoomMute struct {
+	RoomID    uuid.UUID  `json:"room_id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	MutedBy   uuid.UUID  `json:"muted_by"`             // uuid.Nil once the moderator's account is deleted
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Nil for a permanent mute
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// expiryFromDuration converts a duration into an expiry time, where zero
+// means the restriction never expires.
+func expiryFromDuration(now time.Time, duration time.Duration) *time.Time {
+	if duration <= 0 {
+		return nil
+	}
+	expiresAt := now.Add(duration)
+	return &expiresAt
+}
+
+// NewRoomBan creates a ban lasting for duration, or permanently if duration is zero.
+func NewRoomBan(roomID, userID, bannedBy uuid.UUID, reason string, duration time.Duration) *RoomBan {
+	now := time.Now()
+	return &RoomBan{
+		RoomID:    roomID,
+		UserID:    userID,
+		BannedBy:  bannedBy,
+		Reason:    reason,
+		ExpiresAt: expiryFromDuration(now, duration),
+		CreatedAt: now,
+	}
+}
+
+// NewRoomMute creates a mute lasting for duration, or permanently if duration is zero.
+func NewRoomMute(roomID, userID, mutedBy uuid.UUID, duration time.Duration) *RoomMute {
+	now := time.Now()
+	return &RoomMute{
+		RoomID:    roomID,
+		UserID:    userID,
+		MutedBy:   mutedBy,
+		ExpiresAt: expiryFromDuration(now, duration),
+		CreatedAt: now,
+	}
+}
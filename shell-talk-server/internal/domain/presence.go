@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Presence statuses.
+const (
+	PresenceOnline  = "online"
+	PresenceAway    = "away"
+	PresenceOffline = "offline"
+)
+
+// UserPresence is a user's last known presence, stored in PostgreSQL.
+type UserPresence struct {
+	UserID     uuid.UUID `json:"user_id"`
+	Status     string    `json:"status"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// RoomMember is a member of a room along with their role and presence.
+type RoomMember struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	Nickname   string     `json:"nickname"`
+	Role       RoomRole   `json:"role"`
+	Status     string     `json:"status"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"` // Nil if the user has never connected
+}
@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+// ReadCursor records how far a user has read in a conversation, stored in MongoDB.
+type ReadCursor struct {
+	UserID         string    `bson:"user_id"`
+	ConversationID string    `bson:"conversation_id"`
+	LastReadAt     time.Time `bson:"last_read_at"`
+}
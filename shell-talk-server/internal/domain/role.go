@@ -0,0 +1,62 @@
+package domain
+
+// RoomRole is a member's role within a room.
+type RoomRole string
+
+// Room roles, from most to least privileged.
+const (
+	RoleOwner    RoomRole = "owner"
+	RoleAdmin    RoomRole = "admin"
+	RoleMember   RoomRole = "member"
+	RoleReadOnly RoomRole = "read_only"
+)
+
+// RoomPermission is an action a room member may be allowed to take.
+type RoomPermission int
+
+// Room permissions.
+const (
+	PermViewRoom       RoomPermission = iota // Read history and the member list
+	PermSendMessages                         // Post, edit own messages and send typing indicators
+	PermDeleteMessages                       // Delete other members' messages
+	PermManageMembers                        // Kick, ban, mute and change roles of lower-ranked members
+	PermManageRoom                           // Change room settings and transfer ownership
+)
+
+var rolePermissions = map[RoomRole][]RoomPermission{
+	RoleOwner:    {PermViewRoom, PermSendMessages, PermDeleteMessages, PermManageMembers, PermManageRoom},
+	RoleAdmin:    {PermViewRoom, PermSendMessages, PermDeleteMessages, PermManageMembers},
+	RoleMember:   {PermViewRoom, PermSendMessages},
+	RoleReadOnly: {PermViewRoom},
+}
+
+var roleRanks = map[RoomRole]int{
+	RoleOwner:    4,
+	RoleAdmin:    3,
+	RoleMember:   2,
+	RoleReadOnly: 1,
+}
+
+// ParseRoomRole validates a role name received from a client.
+func ParseRoomRole(name string) (RoomRole, bool) {
+	role := RoomRole(name)
+	_, ok := roleRanks[role]
+	return role, ok
+}
+
+// Can reports whether the role grants a permission. The zero role, used for
+// non-members, grants nothing.
+func (r RoomRole) Can(perm RoomPermission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Outranks reports whether the role is strictly more privileged than other.
+// Every role outranks the zero role.
+func (r RoomRole) Outranks(other RoomRole) bool {
+	return roleRanks[r] > roleRanks[other]
+}
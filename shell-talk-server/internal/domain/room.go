@@ -1,39 +1,94 @@
 package domain
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Room visibilities.
+const (
+	RoomPublic     = "public"      // Listed, joinable without a password
+	RoomPassword   = "password"    // Listed, joinable with the room password
+	RoomInviteOnly = "invite_only" // Not listed, joinable only by invitation
+)
+
 // Room represents a chat room in the system.
 type Room struct {
 	ID           uuid.UUID `json:"id"`
 	Name         string    `json:"name"`
 	OwnerID      uuid.UUID `json:"owner_id"`
-	PasswordHash string    `json:"-"` // Do not expose password hash
+	Visibility   string    `json:"visibility"`
+	PasswordHash string    `json:"-"` // Do not expose password hash; empty unless password-protected
+	Topic        string    `json:"topic"`
 	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
-// NewRoom creates a new room.
-func NewRoom(name, password string, ownerID uuid.UUID) (*Room, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+// RoomSummary is a room as shown in the room list.
+type RoomSummary struct {
+	Room
+	MemberCount int `json:"member_count"`
+}
+
+// MaxRoomTopicLength is the longest topic the rooms table can store.
+const MaxRoomTopicLength = 255
+
+// NewRoom creates a new room. The password is only used, and required, for
+// password-protected rooms.
+func NewRoom(name, password, visibility string, ownerID uuid.UUID) (*Room, error) {
+	now := time.Now()
+	room := &Room{
+		ID:        uuid.New(),
+		Name:      name,
+		OwnerID:   ownerID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := room.SetVisibility(visibility, password); err != nil {
 		return nil, err
 	}
-
-	return &Room{
-		ID:           uuid.New(),
-		Name:         name,
-		OwnerID:      ownerID,
-		PasswordHash: string(hashedPassword),
-		CreatedAt:    time.Now(),
-	}, nil
+	return room, nil
 }
 
-// CheckPassword compares a plaintext password with the room's hashed password.
+// CheckPassword compares a plaintext password with the room's hashed
+// password. Rooms that are not password-protected accept any password.
 func (r *Room) CheckPassword(password string) bool {
+	if r.Visibility != RoomPassword {
+		return true
+	}
 	err := bcrypt.CompareHashAndPassword([]byte(r.PasswordHash), []byte(password))
 	return err == nil
 }
+
+// SetPassword replaces the room's password.
+func (r *Room) SetPassword(password string) error {
+	if password == "" {
+		return errors.New("password cannot be empty")
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	r.PasswordHash = string(hashedPassword)
+	return nil
+}
+
+// SetVisibility changes who can see and join the room. Making a room
+// password-protected requires a password; any other visibility clears it.
+func (r *Room) SetVisibility(visibility, password string) error {
+	switch visibility {
+	case RoomPassword:
+		if err := r.SetPassword(password); err != nil {
+			return err
+		}
+	case RoomPublic, RoomInviteOnly:
+		r.PasswordHash = ""
+	default:
+		return errors.New("visibility must be 'public', 'password' or 'invite_only'")
+	}
+	r.Visibility = visibility
+	return nil
+}
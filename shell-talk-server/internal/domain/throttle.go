@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Throttling error codes sent to clients alongside the message.
+const (
+	ErrCodeThrottled = "throttled"  // Too many recent failures; wait before retrying
+	ErrCodeLockedOut = "locked_out" // Too many failures; temporarily locked
+)
+
+// ThrottleError reports that an attempt was refused without being checked
+// because of earlier failures.
+type ThrottleError struct {
+	Code       string
+	RetryAfter time.Duration
+}
+
+func (e *ThrottleError) Error() string {
+	wait := e.RetryAfter.Round(time.Second)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	if e.Code == ErrCodeLockedOut {
+		return fmt.Sprintf("too many failed attempts; locked for %s", wait)
+	}
+	return fmt.Sprintf("too many failed attempts; try again in %s", wait)
+}
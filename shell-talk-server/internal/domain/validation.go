@@ -0,0 +1,23 @@
+package domain
+
+// Validation error codes sent to clients alongside the message.
+const (
+	ErrCodeRequired     = "required"
+	ErrCodeTooShort     = "too_short"
+	ErrCodeTooLong      = "too_long"
+	ErrCodeInvalidChars = "invalid_characters"
+	ErrCodeReserved     = "reserved"
+	ErrCodeWeakPassword = "weak_password"
+)
+
+// ValidationError reports input that breaks a validation rule. Its message is
+// meant to be shown to the user as is.
+type ValidationError struct {
+	Field   string // "nickname", "password" or "room_name"
+	Code    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
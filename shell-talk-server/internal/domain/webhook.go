@@ -0,0 +1,202 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Room events an outgoing webhook can subscribe to.
+const (
+	WebhookRoomMessage  = "room_message"  // Data is a RoomMessagePayload
+	WebhookMemberJoined = "member_joined" // Data is a WebhookMemberData
+	WebhookMemberLeft   = "member_left"   // Data is a WebhookMemberData
+	WebhookRoomUpdated  = "room_updated"  // Data is a WebhookRoomData
+	WebhookRoomDeleted  = "room_deleted"  // Data is a WebhookRoomData
+)
+
+// WebhookEvents lists every event a webhook can subscribe to.
+var WebhookEvents = []string{WebhookRoomMessage, WebhookMemberJoined, WebhookMemberLeft, WebhookRoomUpdated, WebhookRoomDeleted}
+
+// Headers sent with every webhook request. The signature is
+// "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>", keyed
+// with the webhook's secret, where timestamp is the Unix time in the
+// timestamp header.
+const (
+	WebhookEventHeader     = "X-ShellTalk-Event"
+	WebhookDeliveryHeader  = "X-ShellTalk-Delivery"
+	WebhookTimestampHeader = "X-ShellTalk-Timestamp"
+	WebhookSignatureHeader = "X-ShellTalk-Signature"
+)
+
+// RoomWebhook subscribes a URL to a room's events.
+type RoomWebhook struct {
+	ID        uuid.UUID `json:"id"`
+	RoomID    uuid.UUID `json:"room_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`          // Signs requests; only shown when the webhook is created
+	Events    []string  `json:"events"`     // Empty means every event
+	CreatedBy uuid.UUID `json:"created_by"` // uuid.Nil once the creator's account is deleted
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewRoomWebhook creates a webhook with a random secret, checking that the
+// URL is absolute HTTP(S) and that the events are known.
+func NewRoomWebhook(roomID, createdBy uuid.UUID, rawURL string, events []string) (*RoomWebhook, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, errors.New("webhook URL must be an absolute http or https URL")
+	}
+	subscribed := []string{}
+	for _, event := range events {
+		if !slices.Contains(WebhookEvents, event) {
+			return nil, fmt.Errorf("unknown webhook event '%s'", event)
+		}
+		if !slices.Contains(subscribed, event) {
+			subscribed = append(subscribed, event)
+		}
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return &RoomWebhook{
+		ID:        uuid.New(),
+		RoomID:    roomID,
+		URL:       rawURL,
+		Secret:    hex.EncodeToString(secret),
+		Events:    subscribed,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// nonPublicPrefixes are the special-purpose ranges not covered by netip's
+// own predicates: "this network", carrier-grade NAT, IETF protocol
+// assignments, benchmarking and reserved addresses.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// IsPublicAddress reports whether a webhook may be sent to addr. Loopback,
+// private, link-local and other special-purpose addresses reach the
+// server's own network, such as its databases or a cloud metadata service.
+func IsPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Subscribes reports whether the webhook wants an event.
+func (w *RoomWebhook) Subscribes(event string) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, event)
+}
+
+// Info describes the webhook for clients, naming its creator.
+func (w *RoomWebhook) Info(createdBy string) WebhookInfo {
+	events := w.Events
+	if len(events) == 0 {
+		events = WebhookEvents
+	}
+	return WebhookInfo{ID: w.ID.String(), URL: w.URL, Events: events, CreatedBy: createdBy, CreatedAt: w.CreatedAt}
+}
+
+// WebhookEvent is the JSON body POSTed to a webhook.
+type WebhookEvent struct {
+	ID        uuid.UUID   `json:"id"` // Same across retries, so receivers can drop duplicates
+	Event     string      `json:"event"`
+	RoomID    uuid.UUID   `json:"room_id"`
+	RoomName  string      `json:"room_name"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookMemberData is the data of 'member_joined' and 'member_left' events.
+type WebhookMemberData struct {
+	Nickname string `json:"nickname"`
+	Reason   string `json:"reason,omitempty"` // "invite" if joined by invitation; "left", "kicked" or "banned" for member_left
+}
+
+// WebhookRoomData is the data of 'room_updated' and 'room_deleted' events.
+type WebhookRoomData struct {
+	Change     string `json:"change,omitempty"` // For room_updated: "renamed", "topic" or "visibility"
+	By         string `json:"by"`
+	OldName    string `json:"old_name,omitempty"`
+	Topic      string `json:"topic,omitempty"`
+	Visibility string `json:"visibility,omitempty"`
+}
+
+// WebhookDelivery is a webhook request waiting in the queue, or a dead
+// letter once FailedAt is set. The URL and secret are copied from the
+// webhook when the event happens.
+type WebhookDelivery struct {
+	ID            uuid.UUID  `json:"id"`
+	WebhookID     uuid.UUID  `json:"webhook_id"`
+	RoomID        uuid.UUID  `json:"room_id"`
+	URL           string     `json:"url"`
+	Secret        string     `json:"-"`
+	Event         string     `json:"event"`
+	Payload       []byte     `json:"-"` // The marshalled WebhookEvent
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	FailedAt      *time.Time `json:"failed_at,omitempty"`
+}
+
+// FailureInfo describes a dead letter for clients.
+func (d *WebhookDelivery) FailureInfo() WebhookFailureInfo {
+	info := WebhookFailureInfo{ID: d.ID.String(), WebhookID: d.WebhookID.String(), URL: d.URL, Event: d.Event, Attempts: d.Attempts, LastError: d.LastError}
+	if d.FailedAt != nil {
+		info.FailedAt = *d.FailedAt
+	}
+	return info
+}
+
+// SignWebhook returns the signature header value for a request body sent at
+// the given time.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks a request's signature against its body and the
+// timestamp header, rejecting requests more than tolerance old.
+func VerifyWebhook(secret, signature, timestampHeader string, body []byte, tolerance time.Duration) error {
+	unix, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return errors.New("invalid timestamp")
+	}
+	timestamp := time.Unix(unix, 0)
+	if age := time.Since(timestamp); age > tolerance || age < -tolerance {
+		return errors.New("timestamp outside tolerance")
+	}
+	if !hmac.Equal([]byte(signature), []byte(SignWebhook(secret, timestamp, body))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := IsPublicAddress(netip.MustParseAddr(tt.addr)); got != tt.public {
+			t.Errorf("IsPublicAddress(%s) = %v, want %v", tt.addr, got, tt.public)
+		}
+	}
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	const secret = "whsec_test"
+	const tolerance = 5 * time.Minute
+	body := []byte(`{"event":"message_posted"}`)
+	now := time.Now()
+	stamp := func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }
+
+	tests := []struct {
+		name      string
+		signature string
+		timestamp string
+		body      []byte
+		ok        bool
+	}{
+		{"valid", SignWebhook(secret, now, body), stamp(now), body, true},
+		{"valid near the edge of the tolerance", SignWebhook(secret, now.Add(-tolerance+time.Minute), body), stamp(now.Add(-tolerance + time.Minute)), body, true},
+		{"signed with another secret", SignWebhook("whsec_other", now, body), stamp(now), body, false},
+		{"body changed", SignWebhook(secret, now, body), stamp(now), []byte(`{"event":"room_deleted"}`), false},
+		{"signature for another timestamp", SignWebhook(secret, now.Add(-time.Minute), body), stamp(now), body, false},
+		{"malformed signature", "sha256=zz", stamp(now), body, false},
+		{"too old", SignWebhook(secret, now.Add(-tolerance-time.Minute), body), stamp(now.Add(-tolerance - time.Minute)), body, false},
+		{"too far in the future", SignWebhook(secret, now.Add(tolerance+time.Minute), body), stamp(now.Add(tolerance + time.Minute)), body, false},
+		{"malformed timestamp", SignWebhook(secret, now, body), "yesterday", body, false},
+	}
+	for _, tt := range tests {
+		err := VerifyWebhook(secret, tt.signature, tt.timestamp, tt.body, tolerance)
+		if tt.ok && err != nil {
+			t.Errorf("%s: got %v, want no error", tt.name, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: got no error, want one", tt.name)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"shell-talk-server/internal/domain"
+	"shell-talk-server/internal/hub"
+	"shell-talk-server/internal/service"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// maxRequestBody bounds the size of a JSON request body.
+const maxRequestBody = 1 << 20
+
+// API serves the JSON REST API under /api/v1. It uses the same services as
+// the WebSocket hub, and hands anything connected clients should see to the
+// hub, so scripts can take part in chat without holding a WebSocket.
+type API struct {
+	userService    service.IUserService
+	roomService    service.IRoomService
+	messageRepo    service.IMessageRepository
+	sessionService service.ISessionService
+	webhookService service.IWebhookService
+	rateLimiter    *hub.RateLimiter
+	hub            *hub.Hub
+}
+
+// NewAPI creates a new API.
+func NewAPI(userService service.IUserService, roomService service.IRoomService, messageRepo service.IMessageRepository, sessionService service.ISessionService, webhookService service.IWebhookService, rateLimiter *hub.RateLimiter, h *hub.Hub) *API {
+	return &API{
+		userService:    userService,
+		roomService:    roomService,
+		messageRepo:    messageRepo,
+		sessionService: sessionService,
+		webhookService: webhookService,
+		rateLimiter:    rateLimiter,
+		hub:            h,
+	}
+}
+
+// Register adds the API's routes to r. Requests are rate limited under the
+// same message types as their WebSocket counterparts, sharing their buckets.
+func (a *API) Register(r *mux.Router) {
+	api := r.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/auth/register", a.limited("register", a.handleRegister)).Methods(http.MethodPost)
+	api.HandleFunc("/auth/login", a.limited("login", a.handleLogin)).Methods(http.MethodPost)
+	api.HandleFunc("/hooks/{token}", a.limited("post_webhook_message", a.handlePostWebhookMessage)).Methods(http.MethodPost)
+
+	authed := api.NewRoute().Subrouter()
+	authed.Use(a.requireSession)
+	authed.HandleFunc("/rooms", a.limited("list_rooms", a.handleListRooms)).Methods(http.MethodGet)
+	authed.HandleFunc("/rooms", a.limited("create_room", a.handleCreateRoom)).Methods(http.MethodPost)
+	authed.HandleFunc("/rooms/{name}", a.limited("get_room", a.handleGetRoom)).Methods(http.MethodGet)
+	authed.HandleFunc("/rooms/{name}", a.limited("update_room", a.handleUpdateRoom)).Methods(http.MethodPatch)
+	authed.HandleFunc("/rooms/{name}", a.limited("delete_room", a.handleDeleteRoom)).Methods(http.MethodDelete)
+	authed.HandleFunc("/rooms/{name}/members", a.limited("list_members", a.handleListMembers)).Methods(http.MethodGet)
+	authed.HandleFunc("/rooms/{name}/members", a.limited("join_room", a.handleJoinRoom)).Methods(http.MethodPost)
+	authed.HandleFunc("/rooms/{name}/members/me", a.limited("leave_room", a.handleLeaveRoom)).Methods(http.MethodDelete)
+	authed.HandleFunc("/rooms/{name}/messages", a.limited("fetch_history", a.handleListMessages)).Methods(http.MethodGet)
+	authed.HandleFunc("/rooms/{name}/messages", a.limited("send_room_message", a.handleSendMessage)).Methods(http.MethodPost)
+	authed.HandleFunc("/rooms/{name}/webhooks", a.limited("list_webhooks", a.handleListWebhooks)).Methods(http.MethodGet)
+	authed.HandleFunc("/rooms/{name}/webhooks", a.limited("create_webhook", a.handleCreateWebhook)).Methods(http.MethodPost)
+	authed.HandleFunc("/rooms/{name}/webhooks/failures", a.limited("list_webhook_failures", a.handleListWebhookFailures)).Methods(http.MethodGet)
+	authed.HandleFunc("/rooms/{name}/webhooks/{id}", a.limited("delete_webhook", a.handleDeleteWebhook)).Methods(http.MethodDelete)
+	authed.HandleFunc("/rooms/{name}/incoming-webhooks", a.limited("list_incoming_webhooks", a.handleListIncomingWebhooks)).Methods(http.MethodGet)
+	authed.HandleFunc("/rooms/{name}/incoming-webhooks", a.limited("create_incoming_webhook", a.handleCreateIncomingWebhook)).Methods(http.MethodPost)
+	authed.HandleFunc("/rooms/{name}/incoming-webhooks/{id}", a.limited("delete_incoming_webhook", a.handleDeleteIncomingWebhook)).Methods(http.MethodDelete)
+}
+
+type contextKey int
+
+const userKey contextKey = iota
+
+// requireSession authenticates a request by the session token in its
+// Authorization header, as issued by login over either the API or a
+// WebSocket.
+func (a *API) requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "Missing session token.")
+			return
+		}
+		user, err := a.sessionService.ValidateToken(token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, fmt.Sprintf("Invalid session token: %v.", err))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey, user)))
+	})
+}
+
+// currentUser returns the user authenticated by requireSession.
+func currentUser(r *http.Request) *domain.User {
+	user, _ := r.Context().Value(userKey).(*domain.User)
+	return user
+}
+
+// limited applies the rate limits to a request, per user once
+// authenticated and per address before.
+func (a *API) limited(msgType string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		subject := "addr:" + remoteHost(r)
+		if user := currentUser(r); user != nil {
+			subject = "user:" + user.ID.String()
+		}
+		if a.throttled(w, subject, msgType) {
+			return
+		}
+		next(w, r)
+	}
+}
+
+// throttled applies a rate limit to subject, answering the request if it is
+// exceeded.
+func (a *API) throttled(w http.ResponseWriter, subject, msgType string) bool {
+	retryAfter, _ := a.rateLimiter.Allow(subject, msgType)
+	if retryAfter <= 0 {
+		return false
+	}
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	writeJSON(w, http.StatusTooManyRequests, errorResponse{Error: "Too many requests.", Code: "rate_limited", RetryAfter: seconds})
+	return true
+}
+
+// errorResponse is the body of every failed request.
+type errorResponse struct {
+	Error      string `json:"error"`
+	Code       string `json:"code,omitempty"`        // Machine-readable reason, e.g. "too_short"
+	Field      string `json:"field,omitempty"`       // Input the error refers to, e.g. "nickname"
+	RetryAfter int    `json:"retry_after,omitempty"` // Seconds to wait before retrying
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if body != nil {
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+// writeServiceError reports a failed request as "<action>: <err>", choosing
+// the status from the kind of error, like sendError does for WebSockets.
+func writeServiceError(w http.ResponseWriter, action string, err error) {
+	body := errorResponse{Error: fmt.Sprintf("%s: %v", action, err)}
+	status := http.StatusBadRequest
+
+	var validationErr *domain.ValidationError
+	var throttleErr *domain.ThrottleError
+	var permissionErr *domain.PermissionError
+	switch {
+	case errors.As(err, &validationErr):
+		status = http.StatusUnprocessableEntity
+		body.Code = validationErr.Code
+		body.Field = validationErr.Field
+	case errors.As(err, &throttleErr):
+		status = http.StatusTooManyRequests
+		body.Code = throttleErr.Code
+		body.RetryAfter = int(math.Ceil(throttleErr.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(body.RetryAfter))
+	case errors.As(err, &permissionErr):
+		status = http.StatusForbidden
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		status = http.StatusUnauthorized
+	case errors.Is(err, domain.ErrRoomNotFound), errors.Is(err, domain.ErrWebhookNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, hub.ErrShuttingDown):
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, body)
+}
+
+// decodeJSON reads a JSON request body into v, reporting a malformed body to
+// the client. An empty body leaves v unchanged.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(v)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v.", err))
+		return false
+	}
+	return true
+}
+
+// remoteHost returns the IP address a request came from.
+func remoteHost(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// logInternal logs an unexpected error and reports a generic failure.
+func logInternal(w http.ResponseWriter, message string, err error) {
+	log.Printf("%s: %v", message, err)
+	writeError(w, http.StatusInternalServerError, message+".")
+}
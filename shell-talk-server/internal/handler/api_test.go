@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"shell-talk-server/internal/config"
+	"shell-talk-server/internal/domain"
+	"shell-talk-server/internal/hub"
+	"shell-talk-server/internal/service"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// The fakes below embed the service interfaces and implement only what the
+// tested routes call; anything else panics.
+
+var (
+	alice = &domain.User{ID: uuid.New(), Nickname: "alice"}
+	bob   = &domain.User{ID: uuid.New(), Nickname: "bob"}
+)
+
+type fakeUserService struct{ service.IUserService }
+
+func (fakeUserService) Login(nickname, password, remoteAddr string) (*domain.User, error) {
+	if nickname != alice.Nickname || password != "secret" {
+		return nil, domain.ErrInvalidCredentials
+	}
+	return alice, nil
+}
+
+// fakeSessionService issues each user their nickname as a token.
+type fakeSessionService struct{ service.ISessionService }
+
+func (fakeSessionService) IssueToken(user *domain.User) (string, time.Time, error) {
+	return user.Nickname, time.Now().Add(time.Hour), nil
+}
+
+func (fakeSessionService) ValidateToken(token string) (*domain.User, error) {
+	for _, user := range []*domain.User{alice, bob} {
+		if token == user.Nickname {
+			return user, nil
+		}
+	}
+	return nil, domain.ErrInvalidCredentials
+}
+
+// fakeRoomService keeps rooms in memory. Only owners may delete a room, and
+// only members may view one.
+type fakeRoomService struct {
+	service.IRoomService
+	rooms   map[string]*domain.Room
+	members map[string][]uuid.UUID
+}
+
+func (s *fakeRoomService) CreateRoom(name, password, visibility string, owner *domain.User) (*domain.Room, error) {
+	if _, ok := s.rooms[name]; ok {
+		return nil, &domain.ValidationError{Field: "room_name", Code: "taken", Message: "room name is taken"}
+	}
+	room := &domain.Room{ID: uuid.New(), Name: name, Visibility: visibility, OwnerID: owner.ID}
+	s.rooms[name] = room
+	s.members[name] = []uuid.UUID{owner.ID}
+	return room, nil
+}
+
+func (s *fakeRoomService) GetRoomByName(name string) (*domain.Room, error) {
+	return s.rooms[name], nil
+}
+
+func (s *fakeRoomService) GetRoomMemberIDs(name string) ([]uuid.UUID, error) {
+	return s.members[name], nil
+}
+
+func (s *fakeRoomService) Authorize(name string, user *domain.User, perm domain.RoomPermission) (*domain.Room, error) {
+	room, ok := s.rooms[name]
+	if !ok {
+		return nil, domain.ErrRoomNotFound
+	}
+	if !slices.Contains(s.members[name], user.ID) {
+		return nil, &domain.PermissionError{Message: "you are not a member of this room"}
+	}
+	return room, nil
+}
+
+func (s *fakeRoomService) DeleteRoom(name string, actor *domain.User) (*domain.Room, []uuid.UUID, error) {
+	room, ok := s.rooms[name]
+	if !ok {
+		return nil, nil, domain.ErrRoomNotFound
+	}
+	if room.OwnerID != actor.ID {
+		return nil, nil, &domain.PermissionError{Message: "only the owner can delete the room"}
+	}
+	memberIDs := s.members[name]
+	delete(s.rooms, name)
+	delete(s.members, name)
+	return room, memberIDs, nil
+}
+
+func (s *fakeRoomService) JoinRoom(name, password string, user *domain.User, remoteAddr string) (*domain.Room, error) {
+	room, ok := s.rooms[name]
+	if !ok {
+		return nil, domain.ErrRoomNotFound
+	}
+	s.members[name] = append(s.members[name], user.ID)
+	return room, nil
+}
+
+func (s *fakeRoomService) LeaveRoom(name string, user *domain.User) (*domain.Room, error) {
+	room, ok := s.rooms[name]
+	if !ok {
+		return nil, domain.ErrRoomNotFound
+	}
+	s.members[name] = slices.DeleteFunc(s.members[name], func(id uuid.UUID) bool { return id == user.ID })
+	return room, nil
+}
+
+type fakeMessageRepo struct{ service.IMessageRepository }
+
+func (fakeMessageRepo) DeleteConversation(ctx context.Context, conversationID string) error {
+	return nil
+}
+
+type fakeReadCursorRepo struct{ service.IReadCursorRepository }
+
+func (fakeReadCursorRepo) MarkRead(ctx context.Context, userID, conversationID string, readAt time.Time) error {
+	return nil
+}
+
+type fakePresenceService struct{ service.IPresenceService }
+
+func (fakePresenceService) OnlineElsewhere(userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+type fakeWebhookService struct{ service.IWebhookService }
+
+func (fakeWebhookService) Emit(room *domain.Room, event string, data interface{}) error {
+	return nil
+}
+
+func (fakeWebhookService) RoomDeleted(room *domain.Room, deletedBy string) error {
+	return nil
+}
+
+// newTestServer returns the API's routes, backed by the fakes above and a
+// hub that is not running, as no WebSocket connects in these tests.
+func newTestServer(rateLimit config.RateLimitConfig) (http.Handler, *hub.Hub) {
+	cfg := &config.Config{NodeID: "test", RateLimit: rateLimit}
+	rooms := &fakeRoomService{rooms: make(map[string]*domain.Room), members: make(map[string][]uuid.UUID)}
+	rateLimiter := hub.NewRateLimiter(cfg)
+	h := hub.NewHub(fakeUserService{}, rooms, fakeMessageRepo{}, fakeReadCursorRepo{}, fakePresenceService{}, fakeSessionService{}, fakeWebhookService{}, rateLimiter, service.NewLocalBroker(), cfg)
+	api := NewAPI(fakeUserService{}, rooms, fakeMessageRepo{}, fakeSessionService{}, fakeWebhookService{}, rateLimiter, h)
+
+	router := mux.NewRouter()
+	api.Register(router)
+	return router, h
+}
+
+var unlimited = config.RateLimitConfig{Default: config.RateLimit{Rate: 1000, Burst: 1000}}
+
+// serve sends a request as the user whose token is given, if any, and
+// returns the response.
+func serve(handler http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestStatusCodes runs requests in order against one server, checking the
+// status each is answered with.
+func TestStatusCodes(t *testing.T) {
+	server, _ := newTestServer(unlimited)
+	steps := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		want   int
+	}{
+		{"login", http.MethodPost, "/api/v1/auth/login", "", `{"nickname":"alice","password":"secret"}`, http.StatusOK},
+		{"login with a wrong password", http.MethodPost, "/api/v1/auth/login", "", `{"nickname":"alice","password":"guess"}`, http.StatusUnauthorized},
+		{"login with a malformed body", http.MethodPost, "/api/v1/auth/login", "", `{"nickname":`, http.StatusBadRequest},
+		{"no session token", http.MethodGet, "/api/v1/rooms/general", "", "", http.StatusUnauthorized},
+		{"invalid session token", http.MethodGet, "/api/v1/rooms/general", "mallory", "", http.StatusUnauthorized},
+		{"create room", http.MethodPost, "/api/v1/rooms", "alice", `{"name":"general"}`, http.StatusCreated},
+		{"create a room that exists", http.MethodPost, "/api/v1/rooms", "alice", `{"name":"general"}`, http.StatusUnprocessableEntity},
+		{"get room", http.MethodGet, "/api/v1/rooms/general", "alice", "", http.StatusOK},
+		{"get missing room", http.MethodGet, "/api/v1/rooms/lobby", "alice", "", http.StatusNotFound},
+		{"get room as a non-member", http.MethodGet, "/api/v1/rooms/general", "bob", "", http.StatusForbidden},
+		{"join room", http.MethodPost, "/api/v1/rooms/general/members", "bob", `{}`, http.StatusOK},
+		{"join missing room", http.MethodPost, "/api/v1/rooms/lobby/members", "bob", `{}`, http.StatusNotFound},
+		{"get room as a member", http.MethodGet, "/api/v1/rooms/general", "bob", "", http.StatusOK},
+		{"leave room", http.MethodDelete, "/api/v1/rooms/general/members/me", "bob", "", http.StatusNoContent},
+		{"leave missing room", http.MethodDelete, "/api/v1/rooms/lobby/members/me", "bob", "", http.StatusNotFound},
+		{"delete room as a non-owner", http.MethodDelete, "/api/v1/rooms/general", "bob", "", http.StatusForbidden},
+		{"delete room", http.MethodDelete, "/api/v1/rooms/general", "alice", "", http.StatusNoContent},
+		{"delete missing room", http.MethodDelete, "/api/v1/rooms/general", "alice", "", http.StatusNotFound},
+	}
+	for _, step := range steps {
+		rec := serve(server, step.method, step.path, step.token, step.body)
+		if rec.Code != step.want {
+			t.Fatalf("%s: got %d, want %d: %s", step.name, rec.Code, step.want, rec.Body)
+		}
+	}
+}
+
+// TestRateLimited checks that requests over the limit are refused with a
+// Retry-After header.
+func TestRateLimited(t *testing.T) {
+	server, _ := newTestServer(config.RateLimitConfig{Default: config.RateLimit{Rate: 0.01, Burst: 1}, AbuseThreshold: 100, AbuseWindow: time.Minute})
+	body := `{"nickname":"alice","password":"secret"}`
+	if rec := serve(server, http.MethodPost, "/api/v1/auth/login", "", body); rec.Code != http.StatusOK {
+		t.Fatalf("first login: got %d, want %d", rec.Code, http.StatusOK)
+	}
+	rec := serve(server, http.MethodPost, "/api/v1/auth/login", "", body)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("second login: got %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("no Retry-After header")
+	}
+}
+
+// TestShuttingDown checks that room changes are refused once the hub starts
+// shutting down.
+func TestShuttingDown(t *testing.T) {
+	server, h := newTestServer(unlimited)
+	if rec := serve(server, http.MethodPost, "/api/v1/rooms", "alice", `{"name":"general"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create room: got %d, want %d", rec.Code, http.StatusCreated)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	steps := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"create room", http.MethodPost, "/api/v1/rooms", `{"name":"lobby"}`},
+		{"rename room", http.MethodPatch, "/api/v1/rooms/general", `{"name":"lobby"}`},
+		{"join room", http.MethodPost, "/api/v1/rooms/general/members", `{}`},
+		{"leave room", http.MethodDelete, "/api/v1/rooms/general/members/me", ""},
+		{"delete room", http.MethodDelete, "/api/v1/rooms/general", ""},
+	}
+	for _, step := range steps {
+		rec := serve(server, step.method, step.path, "alice", step.body)
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: got %d, want %d: %s", step.name, rec.Code, http.StatusServiceUnavailable, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+	"shell-talk-server/internal/domain"
+)
+
+// handleRegister creates an account and returns a session token for it.
+func (a *API) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var body domain.RegisterPayload
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	user, err := a.userService.Register(body.Nickname, body.Password)
+	if err != nil {
+		writeServiceError(w, "Registration failed", err)
+		return
+	}
+	a.writeSession(w, http.StatusCreated, user)
+}
+
+// handleLogin checks a user's credentials and returns a session token.
+func (a *API) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var body domain.LoginPayload
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	user, err := a.userService.Login(body.Nickname, body.Password, remoteHost(r))
+	if err != nil {
+		writeServiceError(w, "Login failed", err)
+		return
+	}
+	a.writeSession(w, http.StatusOK, user)
+}
+
+// writeSession issues a session token, usable both as a bearer token and to
+// resume a WebSocket session.
+func (a *API) writeSession(w http.ResponseWriter, status int, user *domain.User) {
+	token, expiresAt, err := a.sessionService.IssueToken(user)
+	if err != nil {
+		logInternal(w, "Could not start a session", err)
+		return
+	}
+	writeJSON(w, status, domain.LoginSuccessPayload{UserID: user.ID, Nickname: user.Nickname, SessionToken: token, SessionExpiresAt: expiresAt})
+}
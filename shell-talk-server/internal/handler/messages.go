@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"shell-talk-server/internal/domain"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+// sendMessageRequest is the body of POST /rooms/{name}/messages.
+type sendMessageRequest struct {
+	Content   string `json:"content"`
+	RequestID string `json:"request_id,omitempty"` // Makes retrying safe; a repeated ID is stored once
+}
+
+// handleListMessages pages back through a room's history, newest first. The
+// next_cursor of a response is passed as ?before= to get the page before it.
+func (a *API) handleListMessages(w http.ResponseWriter, r *http.Request) {
+	room, err := a.roomService.Authorize(mux.Vars(r)["name"], currentUser(r), domain.PermViewRoom)
+	if err != nil {
+		writeServiceError(w, "Cannot open room", err)
+		return
+	}
+
+	query := r.URL.Query()
+	var before *domain.MessageCursor
+	if raw := query.Get("before"); raw != "" {
+		before, err = domain.ParseMessageCursor(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid history cursor.")
+			return
+		}
+	}
+	limit := int64(defaultHistoryLimit)
+	if raw := query.Get("limit"); raw != "" {
+		limit, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest, "Limit must be a positive number.")
+			return
+		}
+	}
+	limit = min(limit, maxHistoryLimit)
+
+	// Fetch one extra message to learn whether an older page exists.
+	messages, err := a.messageRepo.GetMessagesByConversationID(context.Background(), room.ID.String(), before, limit+1)
+	if err != nil {
+		logInternal(w, "Failed to retrieve history", err)
+		return
+	}
+	hasMore := int64(len(messages)) > limit
+	if hasMore {
+		messages = messages[1:]
+	}
+
+	history := domain.HistoryPayload{
+		ConversationType: "room",
+		Name:             room.Name,
+		Before:           query.Get("before"),
+		Messages:         make([]domain.HistoryMessage, len(messages)),
+		HasMore:          hasMore,
+	}
+	for i, m := range messages {
+		history.Messages[i] = domain.HistoryMessage{ID: m.ID.Hex(), SenderNickname: m.SenderNickname, Bot: m.Bot, Content: m.Content, Timestamp: m.Timestamp, Edited: m.EditedAt != nil, Deleted: m.Deleted}
+	}
+	if hasMore {
+		history.NextCursor = domain.NewMessageCursor(messages[0]).Encode()
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+// handleSendMessage posts a message to a room. It is delivered live to the
+// room's connected members, the sender's own WebSocket included.
+func (a *API) handleSendMessage(w http.ResponseWriter, r *http.Request) {
+	var body sendMessageRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if strings.TrimSpace(body.Content) == "" {
+		writeError(w, http.StatusBadRequest, "Message content cannot be empty.")
+		return
+	}
+	chatMsg, err := a.hub.PostRoomMessage(currentUser(r), mux.Vars(r)["name"], body.Content, body.RequestID)
+	if err != nil {
+		writeServiceError(w, "Failed to send message", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, domain.MessageAckPayload{RequestID: chatMsg.RequestID, MessageID: chatMsg.ID.Hex(), Timestamp: chatMsg.Timestamp})
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"net/http"
+	"shell-talk-server/internal/domain"
+
+	"github.com/gorilla/mux"
+)
+
+// updateRoomRequest is the body of PATCH /rooms/{name}. Fields left out are
+// not changed.
+type updateRoomRequest struct {
+	Name  *string `json:"name,omitempty"`
+	Topic *string `json:"topic,omitempty"`
+}
+
+// joinRoomRequest is the body of POST /rooms/{name}/members.
+type joinRoomRequest struct {
+	Password string `json:"password"`
+}
+
+func roomInfo(room *domain.Room, memberCount int) domain.RoomInfo {
+	return domain.RoomInfo{ID: room.ID.String(), Name: room.Name, Visibility: room.Visibility, Topic: room.Topic, MemberCount: memberCount}
+}
+
+func (a *API) handleListRooms(w http.ResponseWriter, r *http.Request) {
+	rooms, err := a.roomService.ListRooms()
+	if err != nil {
+		logInternal(w, "Failed to retrieve room list", err)
+		return
+	}
+	roomInfos := make([]domain.RoomInfo, len(rooms))
+	for i, room := range rooms {
+		roomInfos[i] = roomInfo(&room.Room, room.MemberCount)
+	}
+	writeJSON(w, http.StatusOK, domain.RoomListPayload{Rooms: roomInfos})
+}
+
+func (a *API) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
+	var body domain.CreateRoomPayload
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	visibility := body.Visibility
+	if visibility == "" {
+		visibility = domain.RoomPublic
+		if body.Password != "" {
+			visibility = domain.RoomPassword
+		}
+	}
+	user := currentUser(r)
+	room, err := a.hub.CreateRoom(user, body.Name, body.Password, visibility)
+	if err != nil {
+		writeServiceError(w, "Failed to create room", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, roomInfo(room, 1))
+}
+
+// handleGetRoom describes a room the user is a member of.
+func (a *API) handleGetRoom(w http.ResponseWriter, r *http.Request) {
+	room, err := a.roomService.Authorize(mux.Vars(r)["name"], currentUser(r), domain.PermViewRoom)
+	if err != nil {
+		writeServiceError(w, "Cannot open room", err)
+		return
+	}
+	a.writeRoom(w, room)
+}
+
+// handleUpdateRoom renames a room and/or sets its topic.
+func (a *API) handleUpdateRoom(w http.ResponseWriter, r *http.Request) {
+	var body updateRoomRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	user := currentUser(r)
+	name := mux.Vars(r)["name"]
+	room, err := a.roomService.Authorize(name, user, domain.PermViewRoom)
+	if err != nil {
+		writeServiceError(w, "Cannot open room", err)
+		return
+	}
+
+	if body.Name != nil && *body.Name != room.Name {
+		room, err = a.hub.RenameRoom(user, name, *body.Name)
+		if err != nil {
+			writeServiceError(w, "Failed to rename room", err)
+			return
+		}
+	}
+	if body.Topic != nil && *body.Topic != room.Topic {
+		room, err = a.hub.SetRoomTopic(user, room.Name, *body.Topic)
+		if err != nil {
+			writeServiceError(w, "Failed to set topic", err)
+			return
+		}
+	}
+	a.writeRoom(w, room)
+}
+
+func (a *API) handleDeleteRoom(w http.ResponseWriter, r *http.Request) {
+	if err := a.hub.DeleteRoom(currentUser(r), mux.Vars(r)["name"]); err != nil {
+		writeServiceError(w, "Failed to delete room", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) handleListMembers(w http.ResponseWriter, r *http.Request) {
+	room, err := a.roomService.Authorize(mux.Vars(r)["name"], currentUser(r), domain.PermViewRoom)
+	if err != nil {
+		writeServiceError(w, "Failed to get members", err)
+		return
+	}
+	members, err := a.roomService.GetRoomMembers(room.Name)
+	if err != nil {
+		logInternal(w, "Failed to get members", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, domain.RoomMembersPayload{RoomName: room.Name, Members: members})
+}
+
+// handleJoinRoom adds the user to a room.
+func (a *API) handleJoinRoom(w http.ResponseWriter, r *http.Request) {
+	var body joinRoomRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	user := currentUser(r)
+	room, err := a.hub.JoinRoom(user, mux.Vars(r)["name"], body.Password, remoteHost(r))
+	if err != nil {
+		writeServiceError(w, "Failed to join room", err)
+		return
+	}
+	a.writeRoom(w, room)
+}
+
+// handleLeaveRoom removes the user from a room.
+func (a *API) handleLeaveRoom(w http.ResponseWriter, r *http.Request) {
+	if err := a.hub.LeaveRoom(currentUser(r), mux.Vars(r)["name"]); err != nil {
+		writeServiceError(w, "Failed to leave room", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeRoom responds with a room and its current member count.
+func (a *API) writeRoom(w http.ResponseWriter, room *domain.Room) {
+	memberIDs, err := a.roomService.GetRoomMemberIDs(room.Name)
+	if err != nil {
+		logInternal(w, "Failed to load room", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, roomInfo(room, len(memberIDs)))
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"shell-talk-server/internal/domain"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// createWebhookRequest is the body of POST /rooms/{name}/webhooks.
+type createWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty"` // Empty subscribes to every event
+}
+
+func (a *API) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	room, webhooks, err := a.webhookService.ListWebhooks(mux.Vars(r)["name"], currentUser(r))
+	if err != nil {
+		writeServiceError(w, "Failed to list webhooks", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, domain.WebhookListPayload{RoomName: room.Name, Webhooks: a.hub.WebhookInfos(webhooks)})
+}
+
+// handleCreateWebhook subscribes a URL to a room's events. The response
+// holds the secret requests are signed with, which is not shown again.
+func (a *API) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var body createWebhookRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	user := currentUser(r)
+	webhook, room, err := a.webhookService.CreateWebhook(mux.Vars(r)["name"], user, strings.TrimSpace(body.URL), body.Events)
+	if err != nil {
+		writeServiceError(w, "Failed to create webhook", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, domain.WebhookCreatedPayload{RoomName: room.Name, Webhook: webhook.Info(user.Nickname), Secret: webhook.Secret})
+}
+
+func (a *API) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if _, err := a.webhookService.DeleteWebhook(vars["name"], currentUser(r), vars["id"]); err != nil {
+		writeServiceError(w, "Failed to delete webhook", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListWebhookFailures lists the room's most recent deliveries that
+// were given up on, newest first.
+func (a *API) handleListWebhookFailures(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest, "Limit must be a positive number.")
+			return
+		}
+	}
+	room, failures, err := a.webhookService.ListFailures(mux.Vars(r)["name"], currentUser(r), limit)
+	if err != nil {
+		writeServiceError(w, "Failed to list webhook failures", err)
+		return
+	}
+	payload := domain.WebhookFailuresPayload{RoomName: room.Name, Failures: make([]domain.WebhookFailureInfo, len(failures))}
+	for i, failure := range failures {
+		payload.Failures[i] = failure.FailureInfo()
+	}
+	writeJSON(w, http.StatusOK, payload)
+}
+
+// createIncomingWebhookRequest is the body of POST /rooms/{name}/incoming-webhooks.
+type createIncomingWebhookRequest struct {
+	Name string `json:"name"` // Shown as the sender of the webhook's messages
+}
+
+func (a *API) handleListIncomingWebhooks(w http.ResponseWriter, r *http.Request) {
+	room, webhooks, err := a.webhookService.ListIncomingWebhooks(mux.Vars(r)["name"], currentUser(r))
+	if err != nil {
+		writeServiceError(w, "Failed to list incoming webhooks", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, domain.IncomingWebhookListPayload{RoomName: room.Name, Webhooks: a.hub.IncomingWebhookInfos(webhooks)})
+}
+
+// handleCreateIncomingWebhook creates a webhook that posts to the room. The
+// response holds its token, which is not shown again.
+func (a *API) handleCreateIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	var body createIncomingWebhookRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	user := currentUser(r)
+	webhook, token, room, err := a.webhookService.CreateIncomingWebhook(mux.Vars(r)["name"], user, body.Name)
+	if err != nil {
+		writeServiceError(w, "Failed to create incoming webhook", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, domain.IncomingWebhookCreatedPayload{RoomName: room.Name, Webhook: webhook.Info(user.Nickname), Token: token, Path: domain.IncomingWebhookPath + token})
+}
+
+func (a *API) handleDeleteIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if _, err := a.webhookService.DeleteIncomingWebhook(vars["name"], currentUser(r), vars["id"]); err != nil {
+		writeServiceError(w, "Failed to delete incoming webhook", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePostWebhookMessage posts a message to a room as an incoming
+// webhook's bot. The token in the path is the only credential, so requests
+// are limited per webhook as well as per address.
+func (a *API) handlePostWebhookMessage(w http.ResponseWriter, r *http.Request) {
+	webhook, err := a.webhookService.AuthenticateIncomingWebhook(mux.Vars(r)["token"])
+	if errors.Is(err, domain.ErrWebhookNotFound) {
+		writeError(w, http.StatusNotFound, "Unknown webhook.")
+		return
+	}
+	if err != nil {
+		logInternal(w, "Failed to look up webhook", err)
+		return
+	}
+	if a.throttled(w, "webhook:"+webhook.ID.String(), "post_webhook_message") {
+		return
+	}
+
+	var body sendMessageRequest
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if strings.TrimSpace(body.Content) == "" {
+		writeError(w, http.StatusBadRequest, "Message content cannot be empty.")
+		return
+	}
+	if utf8.RuneCountInString(body.Content) > domain.MaxBotMessageLength {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Message content cannot be longer than %d characters.", domain.MaxBotMessageLength))
+		return
+	}
+	chatMsg, err := a.hub.PostWebhookMessage(webhook, body.Content, body.RequestID)
+	if err != nil {
+		writeServiceError(w, "Failed to send message", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, domain.MessageAckPayload{RequestID: chatMsg.RequestID, MessageID: chatMsg.ID.Hex(), Timestamp: chatMsg.Timestamp})
+}
@@ -0,0 +1,114 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"shell-talk-server/internal/domain"
+	"time"
+)
+
+func (h *Hub) handleChangePassword(req *ClientRequest) {
+	var payload domain.ChangePasswordPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid change_password payload.")
+		return
+	}
+	user, err := h.userService.ChangePassword(req.Client.Auth().UserID, payload.OldPassword, payload.NewPassword)
+	if err != nil {
+		req.Client.sendError("error_message", "Failed to change password", err)
+		return
+	}
+
+	// The change revoked the client's session token; give it a new one.
+	token, expiresAt, err := h.sessionService.IssueToken(user)
+	if err != nil {
+		log.Printf("error issuing session token for %s: %v", user.Nickname, err)
+		token = ""
+	}
+	changedPayload := domain.PasswordChangedPayload{SessionToken: token, SessionExpiresAt: expiresAt}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "password_changed", Payload: changedPayload})
+	req.Client.send(msg)
+}
+
+func (h *Hub) handleChangeNickname(req *ClientRequest) {
+	var payload domain.ChangeNicknamePayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid change_nickname payload.")
+		return
+	}
+	oldNickname := req.Client.Auth().Nickname
+	user, err := h.userService.ChangeNickname(req.Client.Auth().UserID, payload.NewNickname)
+	if err != nil {
+		req.Client.sendError("error_message", "Failed to change nickname", err)
+		return
+	}
+
+	// Typing indicators carry the old name; peers see them again on the next keystroke.
+	h.clearUserTyping(user.ID)
+	req.Client.setAuth(&Auth{UserID: user.ID, Nickname: user.Nickname})
+
+	changedPayload := domain.NicknameChangedPayload{OldNickname: oldNickname, NewNickname: user.Nickname}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "nickname_changed", Payload: changedPayload})
+	req.Client.send(msg)
+
+	contactIDs, err := h.presenceService.GetContactIDs(user.ID)
+	if err != nil {
+		log.Printf("error loading contacts for %s: %v", user.Nickname, err)
+		return
+	}
+	h.deliver(contactIDs, msg)
+}
+
+func (h *Hub) handleDeleteAccount(req *ClientRequest) {
+	var payload domain.DeleteAccountPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid delete_account payload.")
+		return
+	}
+	client := req.Client
+	// Contacts are resolved up front; memberships are gone once the user is.
+	contactIDs, err := h.presenceService.GetContactIDs(client.Auth().UserID)
+	if err != nil {
+		log.Printf("error loading contacts for %s: %v", client.Auth().Nickname, err)
+	}
+
+	deletion, err := h.userService.DeleteAccount(client.Auth().UserID, payload.Password)
+	if err != nil {
+		client.sendError("error_message", "Failed to delete account", err)
+		return
+	}
+
+	for _, room := range deletion.DeletedRooms {
+		if err := h.messageRepo.DeleteConversation(context.Background(), room.ID.String()); err != nil {
+			log.Printf("error deleting history of room %s: %v", room.Name, err)
+		}
+		h.emitRoomDeleted(room, client.Auth().Nickname)
+	}
+	for _, handover := range deletion.Handovers {
+		h.sendRoomNotice(handover.Room, fmt.Sprintf("%s deleted their account. %s is now the owner.", client.Auth().Nickname, handover.NewOwner.Nickname))
+		h.emitMemberLeft(handover.Room, client.Auth().Nickname, "left")
+	}
+	for _, room := range deletion.LeftRooms {
+		h.sendRoomNotice(room, fmt.Sprintf("%s deleted their account and left the room.", client.Auth().Nickname))
+		h.emitMemberLeft(room, client.Auth().Nickname, "left")
+	}
+
+	// Detach the session so the connection closing does not record presence
+	// for a user that no longer exists.
+	h.clearUserTyping(client.Auth().UserID)
+	h.mu.Lock()
+	if h.authenticatedClients[client.Auth().UserID] == client {
+		delete(h.authenticatedClients, client.Auth().UserID)
+	}
+	h.mu.Unlock()
+
+	updatePayload := domain.PresenceUpdatePayload{Nickname: client.Auth().Nickname, Status: domain.PresenceOffline, LastSeenAt: time.Now()}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "presence_update", Payload: updatePayload})
+	h.deliver(contactIDs, msg)
+
+	client.sendSystemMessage("account_deleted", "Your account has been deleted.")
+	// writePump delivers the notice, then closes the connection.
+	client.closeSend()
+}
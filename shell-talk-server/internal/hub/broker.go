@@ -0,0 +1,103 @@
+package hub
+
+import (
+	"log"
+	"shell-talk-server/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// deliver sends msg to each of the users who is online, on whichever server
+// instance they are connected to.
+func (h *Hub) deliver(userIDs []uuid.UUID, msg []byte) {
+	h.route(userIDs, msg, false)
+}
+
+// deliverBestEffort is deliver for messages a slow client may miss, such as
+// typing updates.
+func (h *Hub) deliverBestEffort(userIDs []uuid.UUID, msg []byte) {
+	h.route(userIDs, msg, true)
+}
+
+func (h *Hub) route(userIDs []uuid.UUID, msg []byte, bestEffort bool) {
+	// A user is connected to at most one instance, so only those not
+	// connected here need to be passed on.
+	var elsewhere []uuid.UUID
+	for _, userID := range userIDs {
+		client, ok := h.onlineClient(userID)
+		if !ok {
+			elsewhere = append(elsewhere, userID)
+			continue
+		}
+		client.deliver(msg, bestEffort)
+	}
+	if len(elsewhere) == 0 {
+		return
+	}
+	// Offline users are dropped, so a room message does not name every
+	// absent member. Someone whose login elsewhere is not yet recorded may
+	// miss a message live; it is still in the conversation's history.
+	online, err := h.presenceService.OnlineElsewhere(elsewhere)
+	if err != nil {
+		log.Printf("error looking up users online elsewhere: %v", err)
+		online = elsewhere
+	}
+	if len(online) == 0 {
+		return
+	}
+	event := &domain.BrokerEvent{Kind: domain.BrokerDeliver, Node: h.nodeID, UserIDs: online, Message: msg, BestEffort: bestEffort}
+	if err := h.broker.Publish(event); err != nil {
+		log.Printf("error publishing delivery: %v", err)
+	}
+}
+
+// announceSession tells the other instances that a user logged in here.
+func (h *Hub) announceSession(userID uuid.UUID) {
+	event := &domain.BrokerEvent{Kind: domain.BrokerSessionStarted, Node: h.nodeID, UserIDs: []uuid.UUID{userID}}
+	if err := h.broker.Publish(event); err != nil {
+		log.Printf("error announcing session of %s: %v", userID, err)
+	}
+}
+
+// handleBrokerEvent acts on an event published by another instance. It runs
+// on the broker's goroutine, so it must not block.
+func (h *Hub) handleBrokerEvent(event *domain.BrokerEvent) {
+	if event.Node == h.nodeID {
+		return
+	}
+	switch event.Kind {
+	case domain.BrokerDeliver:
+		for _, userID := range event.UserIDs {
+			if client, ok := h.onlineClient(userID); ok {
+				client.deliver(event.Message, event.BestEffort)
+			}
+		}
+	case domain.BrokerSessionStarted:
+		for _, userID := range event.UserIDs {
+			h.endSession(userID)
+		}
+	}
+}
+
+// endSession closes a user's session on this instance after they logged in
+// on another. The session is detached first, so the connection closing
+// does not mark the user offline.
+func (h *Hub) endSession(userID uuid.UUID) {
+	h.mu.Lock()
+	client, ok := h.authenticatedClients[userID]
+	delete(h.authenticatedClients, userID)
+	h.mu.Unlock()
+	if !ok {
+		return
+	}
+	h.submitClearTyping(userID)
+	takeOver(client)
+}
+
+// takeOver disconnects a client whose user has logged in elsewhere.
+func takeOver(client *Client) {
+	client.sendSystemMessage("error_message", "You have been logged in from another location.")
+	// Policy violation tells the old client not to reconnect and take the session back.
+	client.disconnect(websocket.ClosePolicyViolation, "logged in from another location")
+}
@@ -2,20 +2,35 @@ package hub
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"math"
+	"net"
 	"shell-talk-server/internal/domain"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
+// closeGracePeriod bounds how long writing a close frame may take.
+const closeGracePeriod = time.Second
+
 // Client is a middleman between the websocket connection and the hub.
 type Client struct {
-	Hub      *Hub
-	Conn     *websocket.Conn
-	Send     chan []byte
-	AuthInfo *Auth // Holds authenticated user info, nil if not authenticated
+	Hub        *Hub
+	Conn       *websocket.Conn
+	Send       chan []byte
+	RemoteAddr string // Peer IP address, used to throttle password guessing
+
+	authMu sync.RWMutex
+	auth   *Auth // Nil until authenticated; replaced, never modified, afterwards
+
+	sendMu     sync.Mutex // Guards sending on Send against it being closed
+	sendClosed bool
+	closeMsg   []byte // Close frame writePump sends once Send is closed
 }
 
 // Auth holds the authenticated user's data.
@@ -24,18 +39,49 @@ type Auth struct {
 	Nickname string
 }
 
+// Auth returns the authenticated user's info, or nil if the client has not
+// logged in.
+func (c *Client) Auth() *Auth {
+	c.authMu.RLock()
+	defer c.authMu.RUnlock()
+	return c.auth
+}
+
+func (c *Client) setAuth(auth *Auth) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	c.auth = auth
+}
+
 // readPump pumps messages from the websocket connection to the hub.
 //
 // The application runs one readPump per connection. The readPump ensures
 // that there is at most one reader on a connection by executing all
 // reads from this goroutine. It does this by sending all messages
 // to the hub for processing.
+//
+// Until the client is authenticated, readPump waits for each of its login,
+// register and resume requests to be handled, so nothing it sends afterwards
+// is read before the outcome is known. They still run in the dispatcher, so
+// a shutdown waits for them.
 func (c *Client) readPump() {
 	defer func() {
-		c.Hub.unregister <- c
+		select {
+		case c.Hub.unregister <- c:
+		case <-c.Hub.done:
+		}
 		c.Conn.Close()
 	}()
 
+	// A peer that stops answering pings hits the read deadline, so half-open
+	// connections are dropped instead of lingering as online.
+	heartbeat := c.Hub.heartbeat
+	c.Conn.SetReadLimit(heartbeat.MaxMessageSize)
+	c.Conn.SetReadDeadline(time.Now().Add(heartbeat.PongWait))
+	c.Conn.SetPongHandler(func(string) error {
+		return c.Conn.SetReadDeadline(time.Now().Add(heartbeat.PongWait))
+	})
+
 	for {
 		var req domain.WebSocketMessage
 		if err := c.Conn.ReadJSON(&req); err != nil {
@@ -44,59 +90,239 @@ func (c *Client) readPump() {
 			}
 			break
 		}
+		c.Conn.SetReadDeadline(time.Now().Add(heartbeat.PongWait))
+		if c.Hub.shuttingDown.Load() {
+			// The client has been told to reconnect and will resend what is unacknowledged.
+			continue
+		}
 
-		// If client is not authenticated, only allow login or register messages
-		if c.AuthInfo == nil {
-			if req.Type != "login" && req.Type != "register" {
-				c.sendSystemMessage("error_message", "Authentication required. Please /login or /register.")
-				continue
-			}
+		if limited, disconnect := c.rateLimited(req); disconnect {
+			break
+		} else if limited {
+			continue
 		}
 
-		// Add client context to the request and send to hub
+		// Add client context to the request
 		request := &ClientRequest{
 			Client:  c,
 			Message: req,
 		}
-		c.Hub.messages <- request
+
+		// If client is not authenticated, only allow login, register or resume messages
+		if c.Auth() == nil {
+			if req.Type != "login" && req.Type != "register" && req.Type != "resume" {
+				c.sendSystemMessage("error_message", "Authentication required. Please /login or /register.")
+				continue
+			}
+			// Refused once the server starts shutting down, like any other request.
+			c.Hub.runInLane(connLane(c), func() { c.Hub.handleMessage(request) })
+			continue
+		}
+
+		select {
+		case c.Hub.messages <- request:
+		case <-c.Hub.done:
+			return
+		}
+	}
+}
+
+// rateLimited applies the rate limits to an incoming message. A limited
+// message is answered with rate_limited and should be dropped; a client that
+// keeps flooding is sent a close frame and should be disconnected.
+func (c *Client) rateLimited(req domain.WebSocketMessage) (limited, disconnect bool) {
+	subject := fmt.Sprintf("conn:%p", c)
+	if c.Auth() != nil {
+		subject = "user:" + c.Auth().UserID.String()
+	}
+	retryAfter, abusive := c.Hub.rateLimiter.Allow(subject, req.Type)
+	if retryAfter == 0 {
+		return false, false
 	}
+	if abusive {
+		log.Printf("disconnecting %s (%s) for flooding", subject, c.RemoteAddr)
+		closeMsg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "rate limit exceeded")
+		c.Conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		return true, true
+	}
+
+	// Echo the request ID so the client can mark that message as failed.
+	var requestID string
+	if payload, ok := req.Payload.(map[string]interface{}); ok {
+		requestID, _ = payload["request_id"].(string)
+	}
+	limitedPayload := domain.RateLimitedPayload{MessageType: req.Type, RequestID: requestID, RetryAfterMs: retryAfter.Milliseconds() + 1}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "rate_limited", Payload: limitedPayload})
+	c.trySend(msg)
+	return true, false
 }
 
-// writePump pumps messages from the hub to the websocket connection.
+// writePump pumps messages from the hub to the websocket connection, and
+// pings the peer to keep the connection alive and detect when it is gone.
 func (c *Client) writePump() {
-	defer c.Conn.Close()
+	heartbeat := c.Hub.heartbeat
+	ticker := time.NewTicker(heartbeat.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.Conn.Close()
+	}()
 	for {
 		select {
 		case message, ok := <-c.Send:
+			c.Conn.SetWriteDeadline(time.Now().Add(heartbeat.WriteWait))
 			if !ok {
 				// The hub closed the channel.
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				c.Conn.WriteMessage(websocket.CloseMessage, c.closeMsg)
 				return
 			}
 
 			if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
 				return
 			}
+		case <-ticker.C:
+			c.Conn.SetWriteDeadline(time.Now().Add(heartbeat.WriteWait))
+			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
 		}
 	}
 }
 
+// send queues a message without blocking. A client whose buffer is full is
+// not keeping up; rather than stall the hub or silently lose its messages,
+// it is disconnected and may reconnect to catch up. It reports whether the
+// message was queued.
+func (c *Client) send(msg []byte) bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.sendClosed {
+		return false
+	}
+	select {
+	case c.Send <- msg:
+		return true
+	default:
+	}
+	log.Printf("disconnecting slow client %s: send buffer full", c.RemoteAddr)
+	c.disconnectLocked(websocket.CloseTryAgainLater, "too slow to keep up")
+	return false
+}
+
+// trySend queues a best-effort message, such as a typing indicator, dropping
+// it if the client's buffer is full.
+func (c *Client) trySend(msg []byte) bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.sendClosed {
+		return false
+	}
+	select {
+	case c.Send <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// deliver queues a message for this client with send or, if bestEffort, trySend.
+func (c *Client) deliver(msg []byte, bestEffort bool) {
+	if bestEffort {
+		c.trySend(msg)
+	} else {
+		c.send(msg)
+	}
+}
+
+// closeSend closes Send so writePump finishes. It is safe to call more than
+// once and concurrently with send.
+func (c *Client) closeSend() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.closeSendLocked()
+}
+
+func (c *Client) closeSendLocked() {
+	if !c.sendClosed {
+		c.sendClosed = true
+		close(c.Send)
+	}
+}
+
+// finish closes Send with a close frame carrying code and reason, so the
+// client gets everything already queued before it is told to go.
+func (c *Client) finish(code int, reason string) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if !c.sendClosed {
+		c.closeMsg = websocket.FormatCloseMessage(code, reason)
+		c.closeSendLocked()
+	}
+}
+
+// disconnect tells the client why it is being dropped with a close frame,
+// then closes the connection, whatever is still queued. readPump then fails
+// and unregisters the client as usual.
+func (c *Client) disconnect(code int, reason string) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.disconnectLocked(code, reason)
+}
+
+func (c *Client) disconnectLocked(code int, reason string) {
+	c.closeSendLocked()
+	// The connection may be the slow part, so never write to it from the hub.
+	go func() {
+		closeMsg := websocket.FormatCloseMessage(code, reason)
+		c.Conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(closeGracePeriod))
+		c.Conn.Close()
+	}()
+}
+
 // sendSystemMessage sends a system message to this client.
 func (c *Client) sendSystemMessage(msgType string, content string) {
-	payload := domain.SystemPayload{
+	c.sendSystemPayload(msgType, domain.SystemPayload{
 		Content:   content,
 		Timestamp: time.Now(),
+	})
+}
+
+// sendError reports a failed request as "<action>: <err>". Validation and
+// throttling failures also carry a code, plus the field to fix or the
+// seconds to wait.
+func (c *Client) sendError(msgType, action string, err error) {
+	payload := domain.SystemPayload{
+		Content:   fmt.Sprintf("%s: %v", action, err),
+		Timestamp: time.Now(),
+	}
+	var validationErr *domain.ValidationError
+	var throttleErr *domain.ThrottleError
+	switch {
+	case errors.As(err, &validationErr):
+		payload.Code = validationErr.Code
+		payload.Field = validationErr.Field
+	case errors.As(err, &throttleErr):
+		payload.Code = throttleErr.Code
+		payload.RetryAfter = int(math.Ceil(throttleErr.RetryAfter.Seconds()))
 	}
+	c.sendSystemPayload(msgType, payload)
+}
+
+func (c *Client) sendSystemPayload(msgType string, payload domain.SystemPayload) {
 	respMsg := domain.WebSocketMessage{
 		Type:    msgType,
 		Payload: payload,
 	}
 	jsonMsg, err := json.Marshal(respMsg)
 	if err == nil {
-		select {
-		case c.Send <- jsonMsg:
-		default:
-			log.Printf("Could not send system message to client (channel closed or full)")
-		}
+		c.send(jsonMsg)
+	}
+}
+
+// remoteHost returns the IP address of a connection's peer.
+func remoteHost(conn *websocket.Conn) string {
+	addr := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
 	}
+	return addr
 }
@@ -0,0 +1,151 @@
+package hub
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestClient returns a Client with a send buffer of the given size,
+// connected to peer, the other end of its WebSocket.
+func newTestClient(t *testing.T, buffer int) (client *Client, peer *websocket.Conn) {
+	t.Helper()
+	conns := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { peer.Close() })
+	conn := <-conns
+	t.Cleanup(func() { conn.Close() })
+	return &Client{Conn: conn, Send: make(chan []byte, buffer), RemoteAddr: "test"}, peer
+}
+
+// TestSendRacesClose runs sends against closeSend and finish. Run with
+// -race: a send on the closed channel panics, and unguarded state races.
+func TestSendRacesClose(t *testing.T) {
+	for range 20 {
+		client, _ := newTestClient(t, 4)
+
+		drained := make(chan struct{})
+		go func() {
+			defer close(drained)
+			for range client.Send {
+			}
+		}()
+
+		var wg sync.WaitGroup
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 100 {
+					client.deliver([]byte("message"), i%2 == 0)
+				}
+			}()
+		}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			client.closeSend()
+		}()
+		go func() {
+			defer wg.Done()
+			client.finish(websocket.CloseServiceRestart, "server shutting down")
+		}()
+		wg.Wait()
+		<-drained
+
+		if client.send([]byte("late")) || client.trySend([]byte("late")) {
+			t.Fatal("message queued after Send was closed")
+		}
+	}
+}
+
+// TestSlowClientDisconnected fills a client's buffer and checks that the
+// next message drops the client with a close frame saying why, while a
+// best-effort message is merely dropped.
+func TestSlowClientDisconnected(t *testing.T) {
+	client, peer := newTestClient(t, 2)
+
+	for i := range 2 {
+		if !client.send([]byte("message")) {
+			t.Fatalf("send %d: not queued with room in the buffer", i)
+		}
+	}
+	if client.trySend([]byte("typing")) {
+		t.Fatal("trySend queued a message into a full buffer")
+	}
+	if client.sendClosed {
+		t.Fatal("trySend disconnected the client")
+	}
+	if client.send([]byte("overflow")) {
+		t.Fatal("send queued a message into a full buffer")
+	}
+
+	peer.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := peer.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) {
+		t.Fatalf("peer read: got %v, want a close frame", err)
+	}
+	if closeErr.Code != websocket.CloseTryAgainLater || closeErr.Text != "too slow to keep up" {
+		t.Fatalf("close frame: got %d %q, want %d %q", closeErr.Code, closeErr.Text, websocket.CloseTryAgainLater, "too slow to keep up")
+	}
+
+	// What was queued is still there, then Send is closed.
+	for i := range 2 {
+		if _, ok := <-client.Send; !ok {
+			t.Fatalf("queued message %d lost", i)
+		}
+	}
+	if _, ok := <-client.Send; ok {
+		t.Fatal("Send still open after disconnecting")
+	}
+	if client.send([]byte("late")) {
+		t.Fatal("message queued after disconnecting")
+	}
+}
+
+// TestCloseTwice closes Send every way there is, more than once.
+func TestCloseTwice(t *testing.T) {
+	client, _ := newTestClient(t, 1)
+
+	client.closeSend()
+	client.closeSend()
+	client.finish(websocket.CloseServiceRestart, "server shutting down")
+	client.disconnect(websocket.ClosePolicyViolation, "logged in from another location")
+
+	if _, ok := <-client.Send; ok {
+		t.Fatal("Send still open")
+	}
+	if client.closeMsg != nil {
+		t.Fatal("finish after close replaced the close frame")
+	}
+
+	client, _ = newTestClient(t, 1)
+	client.finish(websocket.CloseServiceRestart, "server shutting down")
+	client.finish(websocket.CloseGoingAway, "again")
+	client.closeSend()
+	want := string(websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server shutting down"))
+	if string(client.closeMsg) != want {
+		t.Fatalf("close frame: got %q, want the first finish's %q", client.closeMsg, want)
+	}
+}
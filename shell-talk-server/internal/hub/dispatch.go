@@ -0,0 +1,156 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"shell-talk-server/internal/domain"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// dispatcher runs handlers concurrently while keeping the work submitted to
+// each lane in order. A lane's goroutine exists only while it has work.
+type dispatcher struct {
+	mu      sync.Mutex
+	lanes   map[string][]func() // Queued work; a key is present while its lane runs
+	pending int                 // Work submitted but not finished
+	idle    chan struct{}       // Closed when pending drops to zero; nil if nobody waits
+}
+
+func newDispatcher() *dispatcher {
+	return &dispatcher{lanes: make(map[string][]func())}
+}
+
+// submit queues fn to run after everything already submitted to lane.
+func (d *dispatcher) submit(lane string, fn func()) {
+	d.mu.Lock()
+	d.pending++
+	queue, running := d.lanes[lane]
+	d.lanes[lane] = append(queue, fn)
+	d.mu.Unlock()
+	if !running {
+		go d.run(lane)
+	}
+}
+
+func (d *dispatcher) run(lane string) {
+	for {
+		d.mu.Lock()
+		queue := d.lanes[lane]
+		if len(queue) == 0 {
+			delete(d.lanes, lane)
+			d.mu.Unlock()
+			return
+		}
+		fn := queue[0]
+		d.lanes[lane] = queue[1:]
+		d.mu.Unlock()
+
+		fn()
+		d.done()
+	}
+}
+
+func (d *dispatcher) done() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending--
+	if d.pending == 0 && d.idle != nil {
+		close(d.idle)
+		d.idle = nil
+	}
+}
+
+// wait blocks until all submitted work has finished or ctx is done.
+func (d *dispatcher) wait(ctx context.Context) error {
+	d.mu.Lock()
+	if d.pending == 0 {
+		d.mu.Unlock()
+		return nil
+	}
+	if d.idle == nil {
+		d.idle = make(chan struct{})
+	}
+	idle := d.idle
+	d.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// laneKey picks the lane a request runs in. Requests about the same room or
+// DM conversation share a lane, so messages are stored and delivered in the
+// order they were received; anything else runs in the sender's own lane.
+// Lanes are keyed by room and user ID, as names can change, so the room or
+// peer a request names is looked up first. A request naming a room or user
+// that does not exist runs in the sender's lane and fails there.
+func (h *Hub) laneKey(req *ClientRequest) string {
+	auth := req.Client.Auth()
+	payload, _ := req.Message.Payload.(map[string]interface{})
+	field := func(name string) string {
+		value, _ := payload[name].(string)
+		return value
+	}
+
+	var roomName, peerNickname string
+	switch {
+	case req.Message.Type == "create_room":
+		// The room does not exist yet; anything sent to it afterwards is
+		// only looked up once this has run.
+	case field("room_name") != "":
+		roomName = field("room_name")
+	case field("recipient_nickname") != "":
+		peerNickname = field("recipient_nickname")
+	case field("conversation_type") == "room" && field("name") != "":
+		roomName = field("name")
+	case field("conversation_type") == "dm" && field("name") != "":
+		peerNickname = field("name")
+	}
+
+	if roomName != "" {
+		if room, err := h.roomService.GetRoomByName(roomName); err == nil && room != nil {
+			return roomLane(room.ID)
+		}
+	}
+	if peerNickname != "" {
+		if peer, err := h.userService.GetUserByNickname(peerNickname); err == nil && peer != nil {
+			return dmLane(auth.UserID, peer.ID)
+		}
+	}
+	return userLane(auth.UserID)
+}
+
+// dispatch runs a request in its lane. It runs in the sender's lane, so the
+// sender's requests reach their lanes in the order they were sent, even
+// though looking up a lane may block.
+func (h *Hub) dispatch(req *ClientRequest) {
+	lane := h.laneKey(req)
+	if lane == userLane(req.Client.Auth().UserID) {
+		h.handleMessage(req)
+		return
+	}
+	h.dispatcher.submit(lane, func() { h.handleMessage(req) })
+}
+
+func roomLane(roomID uuid.UUID) string {
+	return "room:" + roomID.String()
+}
+
+// dmLane names the lane of a DM conversation the same way for both peers.
+func dmLane(a, b uuid.UUID) string {
+	return "dm:" + domain.DMConversationID(a, b)
+}
+
+// connLane is the lane of a connection that has not logged in yet.
+func connLane(c *Client) string {
+	return fmt.Sprintf("conn:%p", c)
+}
+
+func userLane(userID uuid.UUID) string {
+	return "user:" + userID.String()
+}
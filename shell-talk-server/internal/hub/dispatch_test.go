@@ -0,0 +1,147 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"shell-talk-server/internal/domain"
+	"shell-talk-server/internal/service"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TestDispatcherKeepsLaneOrder submits work to several lanes at once and
+// checks that each lane ran its work in the order it was submitted.
+func TestDispatcherKeepsLaneOrder(t *testing.T) {
+	d := newDispatcher()
+	const lanes, perLane = 4, 200
+
+	var mu sync.Mutex
+	ran := make(map[string][]int)
+	var wg sync.WaitGroup
+	for l := range lanes {
+		lane := fmt.Sprintf("lane-%d", l)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perLane {
+				d.submit(lane, func() {
+					mu.Lock()
+					ran[lane] = append(ran[lane], i)
+					mu.Unlock()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.wait(ctx); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+
+	for lane, order := range ran {
+		if len(order) != perLane {
+			t.Fatalf("%s: ran %d of %d", lane, len(order), perLane)
+		}
+		for i, got := range order {
+			if got != i {
+				t.Fatalf("%s: position %d ran work %d", lane, i, got)
+			}
+		}
+	}
+	if len(ran) != lanes {
+		t.Fatalf("ran %d lanes, want %d", len(ran), lanes)
+	}
+}
+
+// TestDispatcherRunsLanesConcurrently blocks one lane until work in another
+// lane has run, which deadlocks if lanes share a goroutine.
+func TestDispatcherRunsLanesConcurrently(t *testing.T) {
+	d := newDispatcher()
+	unblock := make(chan struct{})
+	var second bool
+
+	d.submit("a", func() {
+		select {
+		case <-unblock:
+		case <-time.After(5 * time.Second):
+			t.Error("lane b did not run while lane a was busy")
+		}
+	})
+	// Queued behind the blocked work, so it must wait for it.
+	d.submit("a", func() { second = true })
+	d.submit("b", func() {
+		if second {
+			t.Error("lane a ran its second item before the first finished")
+		}
+		close(unblock)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := d.wait(ctx); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if !second {
+		t.Fatal("lane a's second item never ran")
+	}
+}
+
+type laneUserService struct {
+	service.IUserService
+	users map[string]*domain.User
+}
+
+func (s *laneUserService) GetUserByNickname(nickname string) (*domain.User, error) {
+	return s.users[nickname], nil
+}
+
+// TestLaneKeyFollowsRenames checks that requests about a room or DM keep
+// their lane when the room or the peer is renamed, and that requests about
+// something that does not exist run in the sender's lane.
+func TestLaneKeyFollowsRenames(t *testing.T) {
+	alice := &domain.User{ID: uuid.New(), Nickname: "alice"}
+	bob := &domain.User{ID: uuid.New(), Nickname: "bob"}
+	room := &domain.Room{ID: uuid.New(), Name: "general"}
+	rooms := &benchRoomService{rooms: map[string]*domain.Room{"general": room}}
+	users := &laneUserService{users: map[string]*domain.User{"alice": alice, "bob": bob}}
+	h := &Hub{roomService: rooms, userService: users}
+
+	client := &Client{}
+	client.setAuth(&Auth{UserID: alice.ID, Nickname: alice.Nickname})
+	request := func(msgType string, payload map[string]interface{}) *ClientRequest {
+		return &ClientRequest{Client: client, Message: domain.WebSocketMessage{Type: msgType, Payload: payload}}
+	}
+	roomMessage := request("send_room_message", map[string]interface{}{"room_name": "general"})
+	dm := request("send_direct_message", map[string]interface{}{"recipient_nickname": "bob"})
+
+	roomBefore, dmBefore := h.laneKey(roomMessage), h.laneKey(dm)
+	if roomBefore != roomLane(room.ID) {
+		t.Fatalf("room message lane: got %q, want %q", roomBefore, roomLane(room.ID))
+	}
+	// Bob sees the DM in the same lane as Alice.
+	if dmBefore != dmLane(bob.ID, alice.ID) {
+		t.Fatalf("DM lane: got %q, want %q", dmBefore, dmLane(bob.ID, alice.ID))
+	}
+
+	room.Name = "lobby"
+	rooms.rooms = map[string]*domain.Room{"lobby": room}
+	bob.Nickname = "robert"
+	users.users = map[string]*domain.User{"alice": alice, "robert": bob}
+	renamed := request("fetch_history", map[string]interface{}{"conversation_type": "room", "name": "lobby"})
+	if got := h.laneKey(renamed); got != roomBefore {
+		t.Fatalf("lane after room rename: got %q, want %q", got, roomBefore)
+	}
+	renamedDM := request("fetch_history", map[string]interface{}{"conversation_type": "dm", "name": "robert"})
+	if got := h.laneKey(renamedDM); got != dmBefore {
+		t.Fatalf("lane after nickname change: got %q, want %q", got, dmBefore)
+	}
+
+	if got := h.laneKey(roomMessage); got != userLane(alice.ID) {
+		t.Fatalf("lane for a missing room: got %q, want the sender's %q", got, userLane(alice.ID))
+	}
+}
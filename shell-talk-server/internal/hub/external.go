@@ -0,0 +1,230 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"shell-talk-server/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrShuttingDown is returned for work arriving other than over a WebSocket,
+// such as from the REST API, once the hub has begun shutting down.
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// runInLane runs fn in a dispatcher lane and waits for it, so work arriving
+// other than over a WebSocket is ordered with the requests in that lane.
+func (h *Hub) runInLane(lane string, fn func()) error {
+	done := make(chan struct{})
+	// Checking under mu means Shutdown either sees the work as pending or
+	// has already refused it.
+	h.mu.RLock()
+	if h.shuttingDown.Load() {
+		h.mu.RUnlock()
+		return ErrShuttingDown
+	}
+	h.dispatcher.submit(lane, func() {
+		defer close(done)
+		fn()
+	})
+	h.mu.RUnlock()
+	<-done
+	return nil
+}
+
+// runInRoomLane runs fn in the lane of the named room, like a WebSocket
+// request about that room, and returns its error.
+func (h *Hub) runInRoomLane(roomName string, fn func() error) error {
+	room, err := h.roomService.GetRoomByName(roomName)
+	if err != nil {
+		return err
+	}
+	if room == nil {
+		return domain.ErrRoomNotFound
+	}
+	if laneErr := h.runInLane(roomLane(room.ID), func() { err = fn() }); laneErr != nil {
+		return laneErr
+	}
+	return err
+}
+
+// PostRoomMessage stores a message sent to a room other than over a
+// WebSocket, such as through the REST API, and delivers it to the room's
+// online members, the sender included. Retrying with the same request ID
+// returns the stored message without delivering it again.
+func (h *Hub) PostRoomMessage(sender *domain.User, roomName, content, requestID string) (*domain.ChatMessage, error) {
+	var chatMsg *domain.ChatMessage
+	err := h.runInRoomLane(roomName, func() (err error) {
+		chatMsg, err = h.postRoomMessage(sender, roomName, content, requestID)
+		return err
+	})
+	return chatMsg, err
+}
+
+func (h *Hub) postRoomMessage(sender *domain.User, roomName, content, requestID string) (*domain.ChatMessage, error) {
+	room, err := h.roomService.Authorize(roomName, sender, domain.PermSendMessages)
+	if err != nil {
+		return nil, err
+	}
+
+	chatMsg := &domain.ChatMessage{ConversationID: room.ID.String(), SenderID: sender.ID.String(), SenderNickname: sender.Nickname, Content: content, Timestamp: time.Now(), RequestID: requestID}
+	stored, isNew, err := h.saveOnce(chatMsg)
+	if err != nil || !isNew {
+		return stored, err
+	}
+
+	h.clearTyping(typingKey{userID: sender.ID, convType: "room", name: roomName})
+	h.broadcastRoomMessage(room, chatMsg, uuid.Nil)
+	return chatMsg, nil
+}
+
+// PostWebhookMessage stores a message posted through an incoming webhook
+// and delivers it to the room's online members as sent by the webhook's
+// bot. Retrying with the same request ID returns the stored message without
+// delivering it again.
+func (h *Hub) PostWebhookMessage(webhook *domain.IncomingWebhook, content, requestID string) (*domain.ChatMessage, error) {
+	var chatMsg *domain.ChatMessage
+	var err error
+	if laneErr := h.runInLane(roomLane(webhook.RoomID), func() {
+		// Looked up in the lane, so a rename or deletion queued before this
+		// message is seen.
+		var room *domain.Room
+		room, err = h.roomService.GetRoomByID(webhook.RoomID)
+		if err == nil && room == nil {
+			err = domain.ErrRoomNotFound
+		}
+		if err != nil {
+			return
+		}
+		chatMsg = &domain.ChatMessage{ConversationID: room.ID.String(), SenderID: webhook.SenderID(), SenderNickname: webhook.Name, Bot: true, Content: content, Timestamp: time.Now(), RequestID: requestID}
+		var isNew bool
+		chatMsg, isNew, err = h.saveOnce(chatMsg)
+		if err == nil && isNew {
+			h.broadcastRoomMessage(room, chatMsg, uuid.Nil)
+		}
+	}); laneErr != nil {
+		return nil, laneErr
+	}
+	return chatMsg, err
+}
+
+// saveOnce stores a message, reporting whether it is new. A retry of a
+// message that was already stored returns the stored one instead.
+func (h *Hub) saveOnce(chatMsg *domain.ChatMessage) (*domain.ChatMessage, bool, error) {
+	ctx := context.Background()
+	err := h.messageRepo.SaveMessage(ctx, chatMsg)
+	if errors.Is(err, domain.ErrDuplicateMessage) {
+		existing, err := h.messageRepo.GetMessageByRequestID(ctx, chatMsg.SenderID, chatMsg.RequestID)
+		if err == nil && existing == nil {
+			err = errors.New("stored message not found")
+		}
+		return existing, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return chatMsg, true, nil
+}
+
+// CreateRoom creates a room owned by user, for requests arriving other
+// than over a WebSocket. It runs in the owner's lane, like create_room.
+func (h *Hub) CreateRoom(owner *domain.User, name, password, visibility string) (*domain.Room, error) {
+	var room *domain.Room
+	var err error
+	if laneErr := h.runInLane(userLane(owner.ID), func() {
+		room, err = h.roomService.CreateRoom(name, password, visibility, owner)
+		if err == nil {
+			h.notifyJoined(owner, room)
+		}
+	}); laneErr != nil {
+		return nil, laneErr
+	}
+	return room, err
+}
+
+// RenameRoom renames a room for requests arriving other than over a
+// WebSocket, telling its members and webhooks.
+func (h *Hub) RenameRoom(actor *domain.User, name, newName string) (*domain.Room, error) {
+	var room *domain.Room
+	err := h.runInRoomLane(name, func() (err error) {
+		room, err = h.roomService.RenameRoom(name, actor, newName)
+		if err == nil {
+			h.notifyRoomRenamed(room, name, actor.Nickname)
+		}
+		return err
+	})
+	return room, err
+}
+
+// SetRoomTopic sets a room's topic for requests arriving other than over a
+// WebSocket, telling its members and webhooks.
+func (h *Hub) SetRoomTopic(actor *domain.User, name, topic string) (*domain.Room, error) {
+	var room *domain.Room
+	err := h.runInRoomLane(name, func() (err error) {
+		room, err = h.roomService.SetTopic(name, actor, topic)
+		if err == nil {
+			h.notifyTopicChanged(room, actor.Nickname)
+		}
+		return err
+	})
+	return room, err
+}
+
+// DeleteRoom deletes a room and its history for requests arriving other
+// than over a WebSocket, telling its former members and webhooks.
+func (h *Hub) DeleteRoom(actor *domain.User, name string) error {
+	return h.runInRoomLane(name, func() error {
+		_, err := h.deleteRoom(name, actor, actor.Nickname)
+		return err
+	})
+}
+
+// JoinRoom adds a user to a room for requests arriving other than over a
+// WebSocket.
+func (h *Hub) JoinRoom(user *domain.User, name, password, remoteAddr string) (*domain.Room, error) {
+	var room *domain.Room
+	err := h.runInRoomLane(name, func() (err error) {
+		room, err = h.roomService.JoinRoom(name, password, user, remoteAddr)
+		if err == nil {
+			h.notifyJoined(user, room)
+		}
+		return err
+	})
+	return room, err
+}
+
+// LeaveRoom removes a user from a room for requests arriving other than over
+// a WebSocket.
+func (h *Hub) LeaveRoom(user *domain.User, name string) error {
+	return h.runInRoomLane(name, func() error {
+		room, err := h.roomService.LeaveRoom(name, user)
+		if err == nil {
+			h.notifyLeft(user, room)
+		}
+		return err
+	})
+}
+
+// notifyJoined tells a user's connection and the room's webhooks that they
+// joined a room, and marks the history from before they joined as read.
+func (h *Hub) notifyJoined(user *domain.User, room *domain.Room) {
+	if err := h.readCursorRepo.MarkRead(context.Background(), user.ID.String(), room.ID.String(), time.Now()); err != nil {
+		log.Printf("error marking %s read: %v", room.ID, err)
+	}
+	joinSuccessPayload := domain.JoinSuccessPayload{RoomID: room.ID.String(), RoomName: room.Name}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "join_success", Payload: joinSuccessPayload})
+	h.deliver([]uuid.UUID{user.ID}, msg)
+	h.emitWebhook(room, domain.WebhookMemberJoined, domain.WebhookMemberData{Nickname: user.Nickname})
+}
+
+// notifyLeft tells a user's connection and the room's webhooks that they
+// left a room.
+func (h *Hub) notifyLeft(user *domain.User, room *domain.Room) {
+	leaveSuccessPayload := domain.LeaveSuccessPayload{RoomID: room.ID.String()}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "leave_success", Payload: leaveSuccessPayload})
+	h.deliver([]uuid.UUID{user.ID}, msg)
+	h.emitMemberLeft(room, user.Nickname, "left")
+}
@@ -6,14 +6,22 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"shell-talk-server/internal/config"
 	"shell-talk-server/internal/domain"
 	"shell-talk-server/internal/service"
-	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
 )
 
 // ClientRequest bundles a client with their incoming message.
@@ -24,55 +32,205 @@ type ClientRequest struct {
 
 // Hub maintains the set of active clients and broadcasts messages.
 type Hub struct {
+	mu                   sync.RWMutex // Guards connections and authenticatedClients
 	connections          map[*Client]bool
 	authenticatedClients map[uuid.UUID]*Client
+	dispatcher           *dispatcher
 	messages             chan *ClientRequest
 	register             chan *Client
 	unregister           chan *Client
 	userService          service.IUserService
 	roomService          service.IRoomService
 	messageRepo          service.IMessageRepository
+	readCursorRepo       service.IReadCursorRepository
+	presenceService      service.IPresenceService
+	sessionService       service.ISessionService
+	webhookService       service.IWebhookService
+	rateLimiter          *RateLimiter
+	broker               service.IBroker
+	nodeID               string
+	heartbeat            config.HeartbeatConfig
+	shutdown             config.ShutdownConfig
+	shuttingDown         atomic.Bool   // Set under mu once Shutdown starts
+	done                 chan struct{} // Closed when Run returns
+	typingMu             sync.Mutex
+	typing               map[typingKey]*typingState
 }
 
-func NewHub(userService service.IUserService, roomService service.IRoomService, messageRepo service.IMessageRepository) *Hub {
+func NewHub(userService service.IUserService, roomService service.IRoomService, messageRepo service.IMessageRepository, readCursorRepo service.IReadCursorRepository, presenceService service.IPresenceService, sessionService service.ISessionService, webhookService service.IWebhookService, rateLimiter *RateLimiter, broker service.IBroker, cfg *config.Config) *Hub {
 	return &Hub{
 		connections:          make(map[*Client]bool),
 		authenticatedClients: make(map[uuid.UUID]*Client),
+		dispatcher:           newDispatcher(),
 		messages:             make(chan *ClientRequest),
 		register:             make(chan *Client),
 		unregister:           make(chan *Client),
 		userService:          userService,
 		roomService:          roomService,
 		messageRepo:          messageRepo,
+		readCursorRepo:       readCursorRepo,
+		presenceService:      presenceService,
+		sessionService:       sessionService,
+		webhookService:       webhookService,
+		rateLimiter:          rateLimiter,
+		broker:               broker,
+		nodeID:               cfg.NodeID,
+		heartbeat:            cfg.Heartbeat,
+		shutdown:             cfg.Shutdown,
+		done:                 make(chan struct{}),
+		typing:               make(map[typingKey]*typingState),
 	}
 }
 
-func (h *Hub) Run() {
+// Run accepts connections and hands requests to the dispatcher until ctx is
+// done. Handlers run outside this loop, so a slow query or a bcrypt hash
+// never holds up other users.
+func (h *Hub) Run(ctx context.Context) {
+	defer close(h.done)
+
+	// Nobody is connected yet, whatever was recorded before a restart.
+	if err := h.presenceService.ResetPresence(); err != nil {
+		log.Printf("error resetting presence: %v", err)
+	}
+	h.broker.Subscribe(h.handleBrokerEvent)
+
+	typingTicker := time.NewTicker(typingSweepInterval)
+	defer typingTicker.Stop()
+
 	for {
 		select {
 		case client := <-h.register:
-			h.connections[client] = true
+			h.addClient(client)
 		case client := <-h.unregister:
-			if _, ok := h.connections[client]; ok {
-				if client.AuthInfo != nil {
-					delete(h.authenticatedClients, client.AuthInfo.UserID)
-				}
-				delete(h.connections, client)
-				close(client.Send)
-			}
+			h.removeClient(client)
 		case request := <-h.messages:
-			h.handleMessage(request)
+			if h.shuttingDown.Load() {
+				// Clients resend anything unacknowledged once they reconnect.
+				continue
+			}
+			h.dispatcher.submit(userLane(request.Client.Auth().UserID), func() { h.dispatch(request) })
+		case now := <-typingTicker.C:
+			// Clearing indicators may publish to the broker, which can block.
+			h.dispatcher.submit(typingLane, func() { h.sweepTyping(now) })
+		case <-ctx.Done():
+			return
 		}
 	}
 }
 
+// addClient tracks a new connection, or turns it away if the server is
+// shutting down.
+func (h *Hub) addClient(client *Client) {
+	h.mu.Lock()
+	if h.shuttingDown.Load() {
+		h.mu.Unlock()
+		h.sendShutdownNotice(client)
+		client.finish(websocket.CloseServiceRestart, "server shutting down")
+		return
+	}
+	h.connections[client] = true
+	h.mu.Unlock()
+}
+
+// removeClient forgets a closed connection.
+func (h *Hub) removeClient(client *Client) {
+	h.mu.Lock()
+	if _, ok := h.connections[client]; !ok {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.connections, client)
+	// A client replaced by a newer login no longer owns the user's session.
+	auth := client.Auth()
+	ownsSession := auth != nil && h.authenticatedClients[auth.UserID] == client
+	if ownsSession {
+		delete(h.authenticatedClients, auth.UserID)
+	}
+	h.mu.Unlock()
+
+	client.closeSend()
+	// Presence is reset when the server starts again, so a shutdown does not
+	// queue an update for every user.
+	if ownsSession && !h.shuttingDown.Load() {
+		h.submitClearTyping(auth.UserID)
+		h.submitPresence(client, domain.PresenceOffline)
+	}
+}
+
+// onlineClient returns the connection a user is logged in on, if any.
+func (h *Hub) onlineClient(userID uuid.UUID) (*Client, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	client, ok := h.authenticatedClients[userID]
+	return client, ok
+}
+
 func (h *Hub) ServeWs(conn *websocket.Conn) {
-	client := &Client{Hub: h, Conn: conn, Send: make(chan []byte, 256)}
-	h.register <- client
+	client := &Client{Hub: h, Conn: conn, Send: make(chan []byte, 256), RemoteAddr: remoteHost(conn)}
+	select {
+	case h.register <- client:
+	case <-h.done:
+		conn.Close()
+		return
+	}
 	go client.writePump()
 	go client.readPump()
 }
 
+// Accepting reports whether the hub still takes new connections.
+func (h *Hub) Accepting() bool {
+	return !h.shuttingDown.Load()
+}
+
+// Shutdown tells every connection that the server is going away, waits for
+// requests already being handled, such as message writes, to finish, and
+// then closes the connections once their queued messages are written. Run
+// must keep running until Shutdown returns, and should be stopped after.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.shuttingDown.Store(true)
+	clients := make([]*Client, 0, len(h.connections))
+	for client := range h.connections {
+		clients = append(clients, client)
+	}
+	h.mu.Unlock()
+
+	log.Printf("shutting down: notifying %d connections", len(clients))
+	for _, client := range clients {
+		h.sendShutdownNotice(client)
+	}
+	drainErr := h.dispatcher.wait(ctx)
+	for _, client := range clients {
+		client.finish(websocket.CloseServiceRestart, "server shutting down")
+	}
+
+	// Connections are forgotten by Run as their clients hang up.
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		h.mu.RLock()
+		remaining := len(h.connections)
+		h.mu.RUnlock()
+		if remaining == 0 {
+			return drainErr
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Hub) sendShutdownNotice(client *Client) {
+	payload := domain.ServerShutdownPayload{
+		Content:          "Server is shutting down; you will be reconnected shortly.",
+		ReconnectAfterMs: h.shutdown.ReconnectDelay.Milliseconds(),
+	}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "server_shutdown", Payload: payload})
+	client.send(msg)
+}
+
 func (h *Hub) handleMessage(req *ClientRequest) {
 	switch req.Message.Type {
 	case "register":
@@ -81,9 +239,12 @@ func (h *Hub) handleMessage(req *ClientRequest) {
 	case "login":
 		h.handleLogin(req)
 		return
+	case "resume":
+		h.handleResume(req)
+		return
 	}
 
-	if req.Client.AuthInfo == nil {
+	if req.Client.Auth() == nil {
 		req.Client.sendSystemMessage("error_message", "Authentication required.")
 		return
 	}
@@ -103,6 +264,72 @@ func (h *Hub) handleMessage(req *ClientRequest) {
 		h.handleListMembers(req)
 	case "send_room_message":
 		h.handleSendRoomMessage(req)
+	case "fetch_history":
+		h.handleFetchHistory(req)
+	case "mark_read":
+		h.handleMarkRead(req)
+	case "kick_member":
+		h.handleKickMember(req)
+	case "ban_member":
+		h.handleBanMember(req)
+	case "unban_member":
+		h.handleUnbanMember(req)
+	case "mute_member":
+		h.handleMuteMember(req)
+	case "unmute_member":
+		h.handleUnmuteMember(req)
+	case "set_role":
+		h.handleSetRole(req)
+	case "transfer_ownership":
+		h.handleTransferOwnership(req)
+	case "delete_room":
+		h.handleDeleteRoom(req)
+	case "rename_room":
+		h.handleRenameRoom(req)
+	case "change_room_password":
+		h.handleChangeRoomPassword(req)
+	case "create_invite":
+		h.handleCreateInvite(req)
+	case "accept_invite":
+		h.handleAcceptInvite(req)
+	case "list_invites":
+		h.handleListInvites(req)
+	case "revoke_invite":
+		h.handleRevokeInvite(req)
+	case "create_webhook":
+		h.handleCreateWebhook(req)
+	case "list_webhooks":
+		h.handleListWebhooks(req)
+	case "delete_webhook":
+		h.handleDeleteWebhook(req)
+	case "list_webhook_failures":
+		h.handleListWebhookFailures(req)
+	case "create_incoming_webhook":
+		h.handleCreateIncomingWebhook(req)
+	case "list_incoming_webhooks":
+		h.handleListIncomingWebhooks(req)
+	case "delete_incoming_webhook":
+		h.handleDeleteIncomingWebhook(req)
+	case "set_visibility":
+		h.handleSetVisibility(req)
+	case "set_topic":
+		h.handleSetTopic(req)
+	case "change_password":
+		h.handleChangePassword(req)
+	case "change_nickname":
+		h.handleChangeNickname(req)
+	case "delete_account":
+		h.handleDeleteAccount(req)
+	case "set_presence":
+		h.handleSetPresence(req)
+	case "typing_start":
+		h.handleTypingStart(req)
+	case "typing_stop":
+		h.handleTypingStop(req)
+	case "edit_message":
+		h.handleEditMessage(req)
+	case "delete_message":
+		h.handleDeleteMessage(req)
 	default:
 		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Unknown message type: %s", req.Message.Type))
 	}
@@ -112,12 +339,12 @@ func (h *Hub) handleMessage(req *ClientRequest) {
 func (h *Hub) handleRegister(req *ClientRequest) {
 	var payload domain.RegisterPayload
 	if err := parsePayload(req.Message.Payload, &payload); err != nil {
-		req.Client.sendSystemMessage("error_message", "Invalid register payload.")
+		req.Client.sendSystemMessage("register_failed", "Invalid register payload.")
 		return
 	}
 	user, err := h.userService.Register(payload.Nickname, payload.Password)
 	if err != nil {
-		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Registration failed: %v", err))
+		req.Client.sendError("register_failed", "Registration failed", err)
 		return
 	}
 	h.authenticateClient(req.Client, user)
@@ -126,39 +353,68 @@ func (h *Hub) handleRegister(req *ClientRequest) {
 func (h *Hub) handleLogin(req *ClientRequest) {
 	var payload domain.LoginPayload
 	if err := parsePayload(req.Message.Payload, &payload); err != nil {
-		req.Client.sendSystemMessage("error_message", "Invalid login payload.")
+		req.Client.sendSystemMessage("login_failed", "Invalid login payload.")
 		return
 	}
-	user, err := h.userService.Login(payload.Nickname, payload.Password)
+	user, err := h.userService.Login(payload.Nickname, payload.Password, req.Client.RemoteAddr)
 	if err != nil {
-		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Login failed: %v", err))
+		req.Client.sendError("login_failed", "Login failed", err)
+		return
+	}
+	h.authenticateClient(req.Client, user)
+}
+
+func (h *Hub) handleResume(req *ClientRequest) {
+	var payload domain.ResumePayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("resume_failed", "Invalid resume payload.")
+		return
+	}
+	user, err := h.sessionService.ValidateToken(payload.SessionToken)
+	if err != nil {
+		req.Client.sendSystemMessage("resume_failed", fmt.Sprintf("Could not resume session: %v. Please log in again.", err))
 		return
 	}
 	h.authenticateClient(req.Client, user)
 }
 
 func (h *Hub) authenticateClient(client *Client, user *domain.User) {
-	if existingClient, ok := h.authenticatedClients[user.ID]; ok {
-		existingClient.sendSystemMessage("error_message", "You have been logged in from another location.")
-		close(existingClient.Send)
+	token, expiresAt, err := h.sessionService.IssueToken(user)
+	if err != nil {
+		log.Printf("error issuing session token for %s: %v", user.Nickname, err)
+		client.sendSystemMessage("login_failed", "Login failed: could not start a session.")
+		return
 	}
-	client.AuthInfo = &Auth{UserID: user.ID, Nickname: user.Nickname}
+
+	client.setAuth(&Auth{UserID: user.ID, Nickname: user.Nickname})
+	h.mu.Lock()
+	existingClient, ok := h.authenticatedClients[user.ID]
 	h.authenticatedClients[user.ID] = client
+	h.mu.Unlock()
+	if ok && existingClient != client {
+		takeOver(existingClient)
+	}
+	h.announceSession(user.ID)
 
 	// Send login success message
-	loginSuccessPayload := domain.LoginSuccessPayload{UserID: user.ID, Nickname: user.Nickname}
+	loginSuccessPayload := domain.LoginSuccessPayload{UserID: user.ID, Nickname: user.Nickname, SessionToken: token, SessionExpiresAt: expiresAt}
 	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "login_success", Payload: loginSuccessPayload})
-	client.Send <- msg
+	client.send(msg)
 
 	// Send user their existing room memberships synchronously
 	h.syncUserRooms(client)
+
+	// Let the user know what they missed while offline
+	h.sendUnreadSummary(client)
+
+	h.submitPresence(client, domain.PresenceOnline)
 }
 
 func (h *Hub) syncUserRooms(client *Client) {
-	if client.AuthInfo == nil {
+	if client.Auth() == nil {
 		return
 	}
-	rooms, err := h.roomService.GetUserRooms(client.AuthInfo.UserID)
+	rooms, err := h.roomService.GetUserRooms(client.Auth().UserID)
 	if err != nil {
 		log.Printf("error syncing user rooms: %v", err)
 		return
@@ -167,10 +423,72 @@ func (h *Hub) syncUserRooms(client *Client) {
 	for _, room := range rooms {
 		joinSuccessPayload := domain.JoinSuccessPayload{RoomID: room.ID.String(), RoomName: room.Name}
 		msg, _ := json.Marshal(domain.WebSocketMessage{Type: "join_success", Payload: joinSuccessPayload})
-		client.Send <- msg
+		client.send(msg)
 	}
 }
 
+func (h *Hub) sendUnreadSummary(client *Client) {
+	if client.Auth() == nil {
+		return
+	}
+	ctx := context.Background()
+	userID := client.Auth().UserID.String()
+
+	readCursors, err := h.readCursorRepo.GetReadCursors(ctx, userID)
+	if err != nil {
+		log.Printf("error loading read cursors: %v", err)
+		return
+	}
+
+	// Every conversation is counted in one query.
+	type conversation struct{ convoID, convType, name string }
+	var conversations []conversation
+	rooms, err := h.roomService.GetUserRooms(client.Auth().UserID)
+	if err != nil {
+		log.Printf("error loading rooms for unread summary: %v", err)
+	}
+	for _, room := range rooms {
+		conversations = append(conversations, conversation{room.ID.String(), "room", room.Name})
+	}
+
+	dmIDs, err := h.messageRepo.GetDMConversationIDs(ctx, userID)
+	if err != nil {
+		log.Printf("error loading DM conversations for unread summary: %v", err)
+	}
+	for _, convoID := range dmIDs {
+		peerID, ok := domain.DMPeerID(convoID, client.Auth().UserID)
+		if !ok {
+			continue
+		}
+		peer, err := h.userService.GetUserByID(peerID)
+		if err != nil || peer == nil {
+			continue
+		}
+		conversations = append(conversations, conversation{convoID, "dm", peer.Nickname})
+	}
+
+	summary := domain.UnreadSummaryPayload{Conversations: []domain.UnreadConversation{}}
+	if len(conversations) > 0 {
+		since := make(map[string]time.Time, len(conversations))
+		for _, convo := range conversations {
+			since[convo.convoID] = readCursors[convo.convoID]
+		}
+		counts, err := h.messageRepo.CountUnread(ctx, since, userID)
+		if err != nil {
+			log.Printf("error counting unread messages: %v", err)
+			return
+		}
+		for _, convo := range conversations {
+			if count := counts[convo.convoID]; count > 0 {
+				summary.Conversations = append(summary.Conversations, domain.UnreadConversation{ConversationType: convo.convType, Name: convo.name, UnreadCount: count})
+			}
+		}
+	}
+
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "unread_summary", Payload: summary})
+	client.send(msg)
+}
+
 // --- Message Handlers ---
 
 func (h *Hub) handleSendDirectMessage(req *ClientRequest) {
@@ -181,17 +499,18 @@ func (h *Hub) handleSendDirectMessage(req *ClientRequest) {
 	}
 	recipientUser, err := h.userService.GetUserByNickname(payload.RecipientNickname)
 	if err != nil || recipientUser == nil {
-		req.Client.sendSystemMessage("error_message", fmt.Sprintf("User '%s' not found.", payload.RecipientNickname))
+		h.rejectMessage(req.Client, payload.RequestID, fmt.Sprintf("User '%s' not found.", payload.RecipientNickname))
 		return
 	}
-	convoID := generateDMConversationID(req.Client.AuthInfo.UserID, recipientUser.ID)
-	chatMsg := &domain.ChatMessage{ConversationID: convoID, SenderID: req.Client.AuthInfo.UserID.String(), SenderNickname: req.Client.AuthInfo.Nickname, Content: payload.Content, Timestamp: time.Now()}
-	h.messageRepo.SaveMessage(context.Background(), chatMsg)
-	if recipientClient, ok := h.authenticatedClients[recipientUser.ID]; ok {
-		dmPayload := domain.DirectMessagePayload{Sender: req.Client.AuthInfo.Nickname, Content: payload.Content, Timestamp: chatMsg.Timestamp}
-		msg, _ := json.Marshal(domain.WebSocketMessage{Type: "new_direct_message", Payload: dmPayload})
-		recipientClient.Send <- msg
+	h.clearTyping(typingKey{userID: req.Client.Auth().UserID, convType: "dm", name: payload.RecipientNickname})
+	convoID := domain.DMConversationID(req.Client.Auth().UserID, recipientUser.ID)
+	chatMsg := &domain.ChatMessage{ConversationID: convoID, SenderID: req.Client.Auth().UserID.String(), SenderNickname: req.Client.Auth().Nickname, Content: payload.Content, Timestamp: time.Now(), RequestID: payload.RequestID}
+	if !h.saveAndAck(req.Client, chatMsg) {
+		return
 	}
+	dmPayload := domain.DirectMessagePayload{ID: chatMsg.ID.Hex(), Sender: req.Client.Auth().Nickname, Content: payload.Content, Timestamp: chatMsg.Timestamp}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "new_direct_message", Payload: dmPayload})
+	h.deliver([]uuid.UUID{recipientUser.ID}, msg)
 }
 
 func (h *Hub) handleCreateRoom(req *ClientRequest) {
@@ -200,15 +519,22 @@ func (h *Hub) handleCreateRoom(req *ClientRequest) {
 		req.Client.sendSystemMessage("error_message", "Invalid create_room payload.")
 		return
 	}
-	user := &domain.User{ID: req.Client.AuthInfo.UserID, Nickname: req.Client.AuthInfo.Nickname}
-	room, err := h.roomService.CreateRoom(payload.Name, payload.Password, user)
+	user := &domain.User{ID: req.Client.Auth().UserID, Nickname: req.Client.Auth().Nickname}
+	visibility := payload.Visibility
+	if visibility == "" {
+		visibility = domain.RoomPublic
+		if payload.Password != "" {
+			visibility = domain.RoomPassword
+		}
+	}
+	room, err := h.roomService.CreateRoom(payload.Name, payload.Password, visibility, user)
 	if err != nil {
-		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to create room: %v", err))
+		req.Client.sendError("error_message", "Failed to create room", err)
 		return
 	}
 	joinSuccessPayload := domain.JoinSuccessPayload{RoomID: room.ID.String(), RoomName: room.Name}
 	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "join_success", Payload: joinSuccessPayload})
-	req.Client.Send <- msg
+	req.Client.send(msg)
 }
 
 func (h *Hub) handleJoinRoom(req *ClientRequest) {
@@ -217,15 +543,18 @@ func (h *Hub) handleJoinRoom(req *ClientRequest) {
 		req.Client.sendSystemMessage("error_message", "Invalid join_room payload.")
 		return
 	}
-	user := &domain.User{ID: req.Client.AuthInfo.UserID}
-	room, err := h.roomService.JoinRoom(payload.RoomName, payload.Password, user)
+	user := &domain.User{ID: req.Client.Auth().UserID}
+	room, err := h.roomService.JoinRoom(payload.RoomName, payload.Password, user, req.Client.RemoteAddr)
 	if err != nil {
-		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to join room: %v", err))
+		req.Client.sendError("error_message", "Failed to join room", err)
 		return
 	}
+	// History from before joining should not count as unread
+	h.markRead(req.Client, room.ID.String())
 	joinSuccessPayload := domain.JoinSuccessPayload{RoomID: room.ID.String(), RoomName: room.Name}
 	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "join_success", Payload: joinSuccessPayload})
-	req.Client.Send <- msg
+	req.Client.send(msg)
+	h.emitWebhook(room, domain.WebhookMemberJoined, domain.WebhookMemberData{Nickname: req.Client.Auth().Nickname})
 }
 
 func (h *Hub) handleLeaveRoom(req *ClientRequest) {
@@ -234,7 +563,7 @@ func (h *Hub) handleLeaveRoom(req *ClientRequest) {
 		req.Client.sendSystemMessage("error_message", "Invalid leave_room payload.")
 		return
 	}
-	user := &domain.User{ID: req.Client.AuthInfo.UserID}
+	user := &domain.User{ID: req.Client.Auth().UserID}
 	room, err := h.roomService.LeaveRoom(payload.RoomName, user)
 	if err != nil {
 		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to leave room: %v", err))
@@ -242,7 +571,8 @@ func (h *Hub) handleLeaveRoom(req *ClientRequest) {
 	}
 	leaveSuccessPayload := domain.LeaveSuccessPayload{RoomID: room.ID.String()}
 	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "leave_success", Payload: leaveSuccessPayload})
-	req.Client.Send <- msg
+	req.Client.send(msg)
+	h.emitMemberLeft(room, req.Client.Auth().Nickname, "left")
 }
 
 func (h *Hub) handleListRooms(req *ClientRequest) {
@@ -253,11 +583,11 @@ func (h *Hub) handleListRooms(req *ClientRequest) {
 	}
 	roomInfos := make([]domain.RoomInfo, len(rooms))
 	for i, r := range rooms {
-		roomInfos[i] = domain.RoomInfo{ID: r.ID.String(), Name: r.Name}
+		roomInfos[i] = domain.RoomInfo{ID: r.ID.String(), Name: r.Name, Visibility: r.Visibility, Topic: r.Topic, MemberCount: r.MemberCount}
 	}
 	payload := domain.RoomListPayload{Rooms: roomInfos}
 	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "room_list", Payload: payload})
-	req.Client.Send <- msg
+	req.Client.send(msg)
 }
 
 func (h *Hub) handleListMembers(req *ClientRequest) {
@@ -266,6 +596,11 @@ func (h *Hub) handleListMembers(req *ClientRequest) {
 		req.Client.sendSystemMessage("error_message", "Invalid list_members payload.")
 		return
 	}
+	user := &domain.User{ID: req.Client.Auth().UserID}
+	if _, err := h.roomService.Authorize(payload.RoomName, user, domain.PermViewRoom); err != nil {
+		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to get members for room '%s': %v", payload.RoomName, err))
+		return
+	}
 	members, err := h.roomService.GetRoomMembers(payload.RoomName)
 	if err != nil {
 		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to get members for room '%s': %v", payload.RoomName, err))
@@ -273,7 +608,7 @@ func (h *Hub) handleListMembers(req *ClientRequest) {
 	}
 	membersPayload := domain.RoomMembersPayload{RoomName: payload.RoomName, Members: members}
 	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "room_members", Payload: membersPayload})
-	req.Client.Send <- msg
+	req.Client.send(msg)
 }
 
 func (h *Hub) handleSendRoomMessage(req *ClientRequest) {
@@ -283,25 +618,29 @@ func (h *Hub) handleSendRoomMessage(req *ClientRequest) {
 		return
 	}
 
-	user := &domain.User{ID: req.Client.AuthInfo.UserID}
-	isMember, err := h.roomService.IsRoomMember(payload.RoomName, user)
-	if err != nil || !isMember {
-		req.Client.sendSystemMessage("error_message", fmt.Sprintf("You are not a member of room '%s'.", payload.RoomName))
+	user := &domain.User{ID: req.Client.Auth().UserID}
+	room, err := h.roomService.Authorize(payload.RoomName, user, domain.PermSendMessages)
+	if err != nil {
+		h.rejectMessage(req.Client, payload.RequestID, fmt.Sprintf("Cannot post in room '%s': %v.", payload.RoomName, err))
 		return
 	}
 
-	room, err := h.roomService.GetRoomByName(payload.RoomName)
-	if err != nil || room == nil {
-		req.Client.sendSystemMessage("error_message", "Room not found.")
+	h.clearTyping(typingKey{userID: req.Client.Auth().UserID, convType: "room", name: payload.RoomName})
+
+	// Save to DB
+	chatMsg := &domain.ChatMessage{ConversationID: room.ID.String(), SenderID: req.Client.Auth().UserID.String(), SenderNickname: req.Client.Auth().Nickname, Content: payload.Content, Timestamp: time.Now(), RequestID: payload.RequestID}
+	if !h.saveAndAck(req.Client, chatMsg) {
 		return
 	}
 
-	// Save to DB
-	chatMsg := &domain.ChatMessage{ConversationID: room.ID.String(), SenderID: req.Client.AuthInfo.UserID.String(), SenderNickname: req.Client.AuthInfo.Nickname, Content: payload.Content, Timestamp: time.Now()}
-	h.messageRepo.SaveMessage(context.Background(), chatMsg)
+	// Don't send the message back to the original sender
+	h.broadcastRoomMessage(room, chatMsg, req.Client.Auth().UserID)
+}
 
-	// Broadcast to online members
-	roomMsgPayload := domain.RoomMessagePayload{RoomName: payload.RoomName, SenderNickname: req.Client.AuthInfo.Nickname, Content: payload.Content, Timestamp: chatMsg.Timestamp}
+// broadcastRoomMessage delivers a stored message to the room's online
+// members, except the one given, who may be uuid.Nil, and to its webhooks.
+func (h *Hub) broadcastRoomMessage(room *domain.Room, chatMsg *domain.ChatMessage, except uuid.UUID) {
+	roomMsgPayload := domain.RoomMessagePayload{ID: chatMsg.ID.Hex(), RoomName: room.Name, SenderNickname: chatMsg.SenderNickname, Bot: chatMsg.Bot, Content: chatMsg.Content, Timestamp: chatMsg.Timestamp}
 	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "room_message", Payload: roomMsgPayload})
 
 	memberIDs, err := h.roomService.GetRoomMemberIDs(room.Name)
@@ -309,14 +648,419 @@ func (h *Hub) handleSendRoomMessage(req *ClientRequest) {
 		return
 	}
 
+	recipientIDs := make([]uuid.UUID, 0, len(memberIDs))
 	for _, memberID := range memberIDs {
-		// Don't send the message back to the original sender
-		if memberID == req.Client.AuthInfo.UserID {
-			continue
+		if memberID != except {
+			recipientIDs = append(recipientIDs, memberID)
 		}
-		if onlineClient, ok := h.authenticatedClients[memberID]; ok {
-			onlineClient.Send <- msg
+	}
+	h.deliver(recipientIDs, msg)
+	h.emitWebhook(room, domain.WebhookRoomMessage, roomMsgPayload)
+}
+
+// saveAndAck stores a sent message and acknowledges it to the sender. It
+// reports whether the message is new and should be delivered; a retry of a
+// message that was already stored is acknowledged again but not redelivered.
+func (h *Hub) saveAndAck(client *Client, chatMsg *domain.ChatMessage) bool {
+	ctx := context.Background()
+	err := h.messageRepo.SaveMessage(ctx, chatMsg)
+	if errors.Is(err, domain.ErrDuplicateMessage) {
+		existing, err := h.messageRepo.GetMessageByRequestID(ctx, chatMsg.SenderID, chatMsg.RequestID)
+		if err != nil || existing == nil {
+			log.Printf("error looking up duplicate message %s: %v", chatMsg.RequestID, err)
+			h.rejectMessage(client, chatMsg.RequestID, "Failed to save message.")
+			return false
 		}
+		h.ackMessage(client, existing)
+		return false
+	}
+	if err != nil {
+		log.Printf("error saving message: %v", err)
+		h.rejectMessage(client, chatMsg.RequestID, "Failed to save message.")
+		return false
+	}
+	h.ackMessage(client, chatMsg)
+	return true
+}
+
+// ackMessage confirms a stored message to its sender. Clients that did not
+// supply a request ID have nothing to match an ack against and get none.
+func (h *Hub) ackMessage(client *Client, chatMsg *domain.ChatMessage) {
+	if chatMsg.RequestID == "" {
+		return
+	}
+	ackPayload := domain.MessageAckPayload{RequestID: chatMsg.RequestID, MessageID: chatMsg.ID.Hex(), Timestamp: chatMsg.Timestamp}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "message_ack", Payload: ackPayload})
+	client.send(msg)
+}
+
+// rejectMessage reports a failed send, falling back to a plain error message
+// when the client did not supply a request ID.
+func (h *Hub) rejectMessage(client *Client, requestID, reason string) {
+	if requestID == "" {
+		client.sendSystemMessage("error_message", reason)
+		return
+	}
+	rejectedPayload := domain.MessageRejectedPayload{RequestID: requestID, Reason: reason}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "message_rejected", Payload: rejectedPayload})
+	client.send(msg)
+}
+
+func (h *Hub) handleFetchHistory(req *ClientRequest) {
+	var payload domain.FetchHistoryPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid fetch_history payload.")
+		return
+	}
+
+	var before *domain.MessageCursor
+	if payload.Before != "" {
+		cursor, err := domain.ParseMessageCursor(payload.Before)
+		if err != nil {
+			req.Client.sendSystemMessage("error_message", "Invalid history cursor.")
+			return
+		}
+		before = cursor
+	}
+
+	limit := payload.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	convoID, err := h.resolveConversation(req.Client, payload.ConversationType, payload.Name)
+	if err != nil {
+		req.Client.sendSystemMessage("error_message", err.Error())
+		return
+	}
+	if payload.AfterID != "" {
+		h.sendNewerHistory(req.Client, payload, convoID, limit)
+		return
+	}
+
+	// Fetch one extra message to learn whether an older page exists.
+	messages, err := h.messageRepo.GetMessagesByConversationID(context.Background(), convoID, before, limit+1)
+	if err != nil {
+		log.Printf("error fetching history for %s: %v", convoID, err)
+		req.Client.sendSystemMessage("error_message", "Failed to retrieve history.")
+		return
+	}
+	hasMore := int64(len(messages)) > limit
+	if hasMore {
+		messages = messages[1:]
+	}
+
+	historyPayload := domain.HistoryPayload{
+		ConversationType: payload.ConversationType,
+		Name:             payload.Name,
+		Before:           payload.Before,
+		Messages:         make([]domain.HistoryMessage, len(messages)),
+		HasMore:          hasMore,
+	}
+	for i, m := range messages {
+		historyPayload.Messages[i] = domain.HistoryMessage{ID: m.ID.Hex(), SenderNickname: m.SenderNickname, Bot: m.Bot, Content: m.Content, Timestamp: m.Timestamp, Edited: m.EditedAt != nil, Deleted: m.Deleted}
+	}
+	if hasMore {
+		historyPayload.NextCursor = domain.NewMessageCursor(messages[0]).Encode()
+	}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "history", Payload: historyPayload})
+	req.Client.send(msg)
+}
+
+// sendNewerHistory answers a fetch_history request for the messages after a
+// known message, which a reconnecting client uses to catch up.
+func (h *Hub) sendNewerHistory(client *Client, payload domain.FetchHistoryPayload, convoID string, limit int64) {
+	ctx := context.Background()
+	afterID, err := primitive.ObjectIDFromHex(payload.AfterID)
+	if err != nil {
+		client.sendSystemMessage("error_message", "Invalid message ID.")
+		return
+	}
+	anchor, err := h.messageRepo.GetMessageByID(ctx, afterID)
+	if err != nil {
+		log.Printf("error loading message %s: %v", payload.AfterID, err)
+		client.sendSystemMessage("error_message", "Failed to retrieve history.")
+		return
+	}
+	if anchor == nil || anchor.ConversationID != convoID {
+		client.sendSystemMessage("error_message", "Message not found.")
+		return
+	}
+
+	// Fetch one extra message to learn whether a newer page exists.
+	messages, err := h.messageRepo.GetMessagesAfter(ctx, convoID, domain.NewMessageCursor(anchor), limit+1)
+	if err != nil {
+		log.Printf("error fetching history for %s: %v", convoID, err)
+		client.sendSystemMessage("error_message", "Failed to retrieve history.")
+		return
+	}
+	hasMore := int64(len(messages)) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	historyPayload := domain.HistoryPayload{
+		ConversationType: payload.ConversationType,
+		Name:             payload.Name,
+		AfterID:          payload.AfterID,
+		Messages:         make([]domain.HistoryMessage, len(messages)),
+		HasMore:          hasMore,
+	}
+	for i, m := range messages {
+		historyPayload.Messages[i] = domain.HistoryMessage{ID: m.ID.Hex(), SenderNickname: m.SenderNickname, Bot: m.Bot, Content: m.Content, Timestamp: m.Timestamp, Edited: m.EditedAt != nil, Deleted: m.Deleted}
+	}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "history", Payload: historyPayload})
+	client.send(msg)
+}
+
+func (h *Hub) handleEditMessage(req *ClientRequest) {
+	var payload domain.EditMessagePayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid edit_message payload.")
+		return
+	}
+	if strings.TrimSpace(payload.Content) == "" {
+		req.Client.sendSystemMessage("error_message", "Message content cannot be empty. Use delete_message instead.")
+		return
+	}
+	chatMsg, ok := h.lookupMessage(req.Client, payload.MessageID)
+	if !ok {
+		return
+	}
+	if chatMsg.SenderID != req.Client.Auth().UserID.String() {
+		req.Client.sendSystemMessage("error_message", "You can only edit your own messages.")
+		return
+	}
+	if roomID, err := uuid.Parse(chatMsg.ConversationID); err == nil {
+		user := &domain.User{ID: req.Client.Auth().UserID}
+		if _, err := h.roomService.AuthorizeByID(roomID, user, domain.PermSendMessages); err != nil {
+			req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to edit message: %v", err))
+			return
+		}
+	}
+
+	editedAt := time.Now()
+	if err := h.messageRepo.UpdateMessageContent(context.Background(), chatMsg, payload.Content, editedAt); err != nil {
+		if errors.Is(err, domain.ErrMessageConflict) {
+			req.Client.sendSystemMessage("error_message", "Message was changed by another request. Please try again.")
+		} else {
+			log.Printf("error editing message %s: %v", payload.MessageID, err)
+			req.Client.sendSystemMessage("error_message", "Failed to edit message.")
+		}
+		return
+	}
+
+	h.broadcastToConversation(chatMsg.ConversationID, func(convType, name string) domain.WebSocketMessage {
+		return domain.WebSocketMessage{Type: "message_edited", Payload: domain.MessageEditedPayload{
+			MessageID:        payload.MessageID,
+			ConversationType: convType,
+			Name:             name,
+			Content:          payload.Content,
+			Version:          chatMsg.Version + 1,
+			EditedAt:         editedAt,
+		}}
+	})
+}
+
+func (h *Hub) handleDeleteMessage(req *ClientRequest) {
+	var payload domain.DeleteMessagePayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid delete_message payload.")
+		return
+	}
+	chatMsg, ok := h.lookupMessage(req.Client, payload.MessageID)
+	if !ok {
+		return
+	}
+
+	// Senders may delete their own messages; room owners and admins may
+	// delete any message in their room.
+	if chatMsg.SenderID != req.Client.Auth().UserID.String() {
+		roomID, err := uuid.Parse(chatMsg.ConversationID)
+		if err != nil {
+			req.Client.sendSystemMessage("error_message", "You can only delete your own messages.")
+			return
+		}
+		user := &domain.User{ID: req.Client.Auth().UserID}
+		if _, err := h.roomService.AuthorizeByID(roomID, user, domain.PermDeleteMessages); err != nil {
+			req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to delete message: %v", err))
+			return
+		}
+	}
+
+	if err := h.messageRepo.DeleteMessage(context.Background(), chatMsg.ID, time.Now()); err != nil {
+		log.Printf("error deleting message %s: %v", payload.MessageID, err)
+		req.Client.sendSystemMessage("error_message", "Failed to delete message.")
+		return
+	}
+
+	h.broadcastToConversation(chatMsg.ConversationID, func(convType, name string) domain.WebSocketMessage {
+		return domain.WebSocketMessage{Type: "message_deleted", Payload: domain.MessageDeletedPayload{
+			MessageID:        payload.MessageID,
+			ConversationType: convType,
+			Name:             name,
+		}}
+	})
+}
+
+// lookupMessage loads a live message by its hex ID, reporting any problem to
+// the client.
+func (h *Hub) lookupMessage(client *Client, messageID string) (*domain.ChatMessage, bool) {
+	id, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		client.sendSystemMessage("error_message", "Invalid message ID.")
+		return nil, false
+	}
+	chatMsg, err := h.messageRepo.GetMessageByID(context.Background(), id)
+	if err != nil {
+		log.Printf("error loading message %s: %v", messageID, err)
+		client.sendSystemMessage("error_message", "Failed to load message.")
+		return nil, false
+	}
+	if chatMsg == nil || chatMsg.Deleted {
+		client.sendSystemMessage("error_message", "Message not found.")
+		return nil, false
+	}
+	return chatMsg, true
+}
+
+// broadcastToConversation sends an event to every online participant of a
+// stored conversation. Since a DM is named after the peer, build is called
+// with the conversation type and name as each recipient knows it.
+func (h *Hub) broadcastToConversation(convoID string, build func(convType, name string) domain.WebSocketMessage) {
+	recipients := make(map[uuid.UUID]string)
+	convType := "room"
+
+	if roomID, err := uuid.Parse(convoID); err == nil {
+		room, err := h.roomService.GetRoomByID(roomID)
+		if err != nil || room == nil {
+			return
+		}
+		memberIDs, err := h.roomService.GetRoomMemberIDs(room.Name)
+		if err != nil {
+			return
+		}
+		for _, memberID := range memberIDs {
+			recipients[memberID] = room.Name
+		}
+	} else {
+		convType = "dm"
+		firstID, secondID, ok := domain.ParseDMConversationID(convoID)
+		if !ok {
+			return
+		}
+		firstUser, err1 := h.userService.GetUserByID(firstID)
+		secondUser, err2 := h.userService.GetUserByID(secondID)
+		if err1 != nil || err2 != nil || firstUser == nil || secondUser == nil {
+			return
+		}
+		recipients[firstID] = secondUser.Nickname
+		recipients[secondID] = firstUser.Nickname
+	}
+
+	byName := make(map[string][]uuid.UUID)
+	for userID, name := range recipients {
+		byName[name] = append(byName[name], userID)
+	}
+	for name, userIDs := range byName {
+		msg, _ := json.Marshal(build(convType, name))
+		h.deliver(userIDs, msg)
+	}
+}
+
+func (h *Hub) handleSetPresence(req *ClientRequest) {
+	var payload domain.SetPresencePayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid set_presence payload.")
+		return
+	}
+	if payload.Status != domain.PresenceOnline && payload.Status != domain.PresenceAway {
+		req.Client.sendSystemMessage("error_message", "Presence must be 'online' or 'away'.")
+		return
+	}
+	h.updatePresence(req.Client, payload.Status)
+}
+
+// submitPresence updates a user's presence in their lane, after any of their
+// earlier requests. Going offline is skipped if the user has logged in again
+// meanwhile.
+func (h *Hub) submitPresence(client *Client, status string) {
+	userID := client.Auth().UserID
+	h.dispatcher.submit(userLane(userID), func() {
+		if _, online := h.onlineClient(userID); status == domain.PresenceOffline && online {
+			return
+		}
+		h.updatePresence(client, status)
+	})
+}
+
+// updatePresence records a user's presence and pushes it to every online
+// user who shares a room or DM conversation with them.
+func (h *Hub) updatePresence(client *Client, status string) {
+	presence, err := h.presenceService.SetPresence(client.Auth().UserID, status)
+	if err != nil {
+		log.Printf("error setting presence for %s: %v", client.Auth().Nickname, err)
+		return
+	}
+	if presence == nil {
+		// The user went offline here but is connected to another instance.
+		return
+	}
+	contactIDs, err := h.presenceService.GetContactIDs(client.Auth().UserID)
+	if err != nil {
+		log.Printf("error loading contacts for %s: %v", client.Auth().Nickname, err)
+		return
+	}
+
+	updatePayload := domain.PresenceUpdatePayload{Nickname: client.Auth().Nickname, Status: presence.Status, LastSeenAt: presence.LastSeenAt}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "presence_update", Payload: updatePayload})
+	h.deliver(contactIDs, msg)
+}
+
+func (h *Hub) handleMarkRead(req *ClientRequest) {
+	var payload domain.MarkReadPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid mark_read payload.")
+		return
+	}
+	convoID, err := h.resolveConversation(req.Client, payload.ConversationType, payload.Name)
+	if err != nil {
+		req.Client.sendSystemMessage("error_message", err.Error())
+		return
+	}
+	h.markRead(req.Client, convoID)
+}
+
+// markRead moves the client's read cursor for a conversation to now.
+func (h *Hub) markRead(client *Client, convoID string) {
+	if err := h.readCursorRepo.MarkRead(context.Background(), client.Auth().UserID.String(), convoID, time.Now()); err != nil {
+		log.Printf("error marking %s read: %v", convoID, err)
+	}
+}
+
+// resolveConversation maps a conversation type and name as seen by the client
+// to the conversation ID messages are stored under. The returned error is
+// suitable for sending back to the client.
+func (h *Hub) resolveConversation(client *Client, convType, name string) (string, error) {
+	switch convType {
+	case "room":
+		user := &domain.User{ID: client.Auth().UserID}
+		room, err := h.roomService.Authorize(name, user, domain.PermViewRoom)
+		if err != nil {
+			return "", fmt.Errorf("Cannot open room '%s': %v.", name, err)
+		}
+		return room.ID.String(), nil
+	case "dm":
+		peer, err := h.userService.GetUserByNickname(name)
+		if err != nil || peer == nil {
+			return "", fmt.Errorf("User '%s' not found.", name)
+		}
+		return domain.DMConversationID(client.Auth().UserID, peer.ID), nil
+	default:
+		return "", errors.New("Conversation type must be 'room' or 'dm'.")
 	}
 }
 
@@ -329,9 +1073,3 @@ func parsePayload(payload interface{}, result interface{}) error {
 	}
 	return json.Unmarshal(payloadBytes, result)
 }
-
-func generateDMConversationID(userID1, userID2 uuid.UUID) string {
-	ids := []string{userID1.String(), userID2.String()}
-	sort.Strings(ids)
-	return strings.Join(ids, "_")
-}
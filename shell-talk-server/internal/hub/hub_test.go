@@ -0,0 +1,184 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"shell-talk-server/internal/config"
+	"shell-talk-server/internal/domain"
+	"shell-talk-server/internal/service"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The fakes below embed the service interfaces and implement only what a
+// login followed by room messages calls; anything else panics.
+
+type benchUserService struct {
+	service.IUserService
+	passwordHash string
+}
+
+// Login checks the password like the real service does, so logins cost a
+// bcrypt comparison, if at the lowest cost.
+func (s *benchUserService) Login(nickname, password, remoteAddr string) (*domain.User, error) {
+	user := &domain.User{ID: uuid.New(), Nickname: nickname, PasswordHash: s.passwordHash}
+	if !user.CheckPassword(password) {
+		return nil, domain.ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+type benchSessionService struct{ service.ISessionService }
+
+func (benchSessionService) IssueToken(user *domain.User) (string, time.Time, error) {
+	return "token", time.Now().Add(time.Hour), nil
+}
+
+type benchRoomService struct {
+	service.IRoomService
+	rooms     map[string]*domain.Room
+	memberIDs []uuid.UUID
+}
+
+func (s *benchRoomService) Authorize(name string, user *domain.User, perm domain.RoomPermission) (*domain.Room, error) {
+	room, ok := s.rooms[name]
+	if !ok {
+		return nil, domain.ErrRoomNotFound
+	}
+	return room, nil
+}
+
+func (s *benchRoomService) GetRoomByName(name string) (*domain.Room, error) {
+	return s.rooms[name], nil
+}
+
+func (s *benchRoomService) GetRoomMemberIDs(name string) ([]uuid.UUID, error) {
+	return s.memberIDs, nil
+}
+
+func (s *benchRoomService) GetUserRooms(userID uuid.UUID) ([]*domain.Room, error) {
+	return nil, nil
+}
+
+type benchMessageRepo struct{ service.IMessageRepository }
+
+func (benchMessageRepo) SaveMessage(ctx context.Context, message *domain.ChatMessage) error {
+	message.ID = primitive.NewObjectID()
+	return nil
+}
+
+func (benchMessageRepo) GetDMConversationIDs(ctx context.Context, userID string) ([]string, error) {
+	return nil, nil
+}
+
+type benchReadCursorRepo struct{ service.IReadCursorRepository }
+
+func (benchReadCursorRepo) GetReadCursors(ctx context.Context, userID string) (map[string]time.Time, error) {
+	return map[string]time.Time{}, nil
+}
+
+type benchPresenceService struct{ service.IPresenceService }
+
+func (benchPresenceService) SetPresence(userID uuid.UUID, status string) (*domain.UserPresence, error) {
+	return &domain.UserPresence{UserID: userID, Status: status, LastSeenAt: time.Now()}, nil
+}
+
+func (benchPresenceService) GetContactIDs(userID uuid.UUID) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+func (benchPresenceService) OnlineElsewhere(userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+type benchWebhookService struct{ service.IWebhookService }
+
+func (benchWebhookService) Emit(room *domain.Room, event string, data interface{}) error {
+	return nil
+}
+
+// benchClient returns a client that discards whatever it is sent. The
+// buffer must be large enough that it is never disconnected as too slow,
+// as it has no connection to close.
+func benchClient(clients *sync.WaitGroup, buffer int) *Client {
+	client := &Client{Send: make(chan []byte, buffer), RemoteAddr: "bench"}
+	clients.Add(1)
+	go func() {
+		defer clients.Done()
+		for range client.Send {
+		}
+	}()
+	return client
+}
+
+// BenchmarkHubLoginAndRoomSends logs clients in and has each post to one of
+// a few busy rooms, from many goroutines at once, the way readPump and Run
+// hand requests to the dispatcher.
+func BenchmarkHubLoginAndRoomSends(b *testing.B) {
+	const roomCount, listenerCount, sendsPerLogin = 8, 50, 4
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		b.Fatal(err)
+	}
+	rooms := &benchRoomService{rooms: make(map[string]*domain.Room)}
+	roomNames := make([]string, roomCount)
+	for i := range roomNames {
+		roomNames[i] = fmt.Sprintf("room-%d", i)
+		rooms.rooms[roomNames[i]] = &domain.Room{ID: uuid.New(), Name: roomNames[i]}
+	}
+	cfg := &config.Config{NodeID: "bench"}
+	h := NewHub(&benchUserService{passwordHash: string(hash)}, rooms, benchMessageRepo{}, benchReadCursorRepo{}, benchPresenceService{}, benchSessionService{}, benchWebhookService{}, nil, service.NewLocalBroker(), cfg)
+
+	var clients sync.WaitGroup
+	var allClients []*Client
+	var allMu sync.Mutex
+	login := func(client *Client, nickname string) {
+		req := &ClientRequest{Client: client, Message: domain.WebSocketMessage{Type: "login", Payload: map[string]interface{}{"nickname": nickname, "password": "password"}}}
+		if err := h.runInLane(connLane(client), func() { h.handleMessage(req) }); err != nil {
+			b.Error(err)
+		}
+		allMu.Lock()
+		allClients = append(allClients, client)
+		allMu.Unlock()
+	}
+
+	// Every room message fans out to the same online listeners.
+	for i := range listenerCount {
+		client := benchClient(&clients, 1<<14)
+		login(client, fmt.Sprintf("listener-%d", i))
+		rooms.memberIDs = append(rooms.memberIDs, client.Auth().UserID)
+	}
+
+	var next atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			n := next.Add(1)
+			client := benchClient(&clients, 64)
+			login(client, fmt.Sprintf("user-%d", n))
+			for i := range sendsPerLogin {
+				payload := map[string]interface{}{"room_name": roomNames[(int(n)+i)%roomCount], "content": "hello", "request_id": fmt.Sprintf("%d-%d", n, i)}
+				req := &ClientRequest{Client: client, Message: domain.WebSocketMessage{Type: "send_room_message", Payload: payload}}
+				h.dispatcher.submit(userLane(client.Auth().UserID), func() { h.dispatch(req) })
+			}
+		}
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := h.dispatcher.wait(ctx); err != nil {
+		b.Fatal(err)
+	}
+	b.StopTimer()
+
+	for _, client := range allClients {
+		client.closeSend()
+	}
+	clients.Wait()
+}
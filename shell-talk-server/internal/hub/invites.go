@@ -0,0 +1,120 @@
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"shell-talk-server/internal/domain"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (h *Hub) handleCreateInvite(req *ClientRequest) {
+	var payload domain.CreateInvitePayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid create_invite payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	duration := time.Duration(payload.DurationSeconds) * time.Second
+	invite, room, invitee, err := h.roomService.CreateInvite(payload.RoomName, actor, payload.Nickname, payload.MaxUses, duration)
+	if err != nil {
+		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to create invite: %v", err))
+		return
+	}
+
+	createdPayload := domain.InviteCreatedPayload{RoomName: room.Name, Code: invite.Code, MaxUses: invite.MaxUses, ExpiresAt: invite.ExpiresAt}
+	if invitee != nil {
+		createdPayload.Invitee = invitee.Nickname
+	}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "invite_created", Payload: createdPayload})
+	req.Client.send(msg)
+
+	// Direct invites are delivered straight away if the invitee is online.
+	if invitee == nil {
+		return
+	}
+	content := fmt.Sprintf("%s invited you to join room '%s'. Use /accept %s to join", req.Client.Auth().Nickname, room.Name, invite.Code)
+	if invite.ExpiresAt != nil {
+		content += fmt.Sprintf(" before %s", invite.ExpiresAt.Format(time.RFC1123))
+	}
+	noticePayload := domain.SystemPayload{Content: content + ".", Timestamp: time.Now()}
+	msg, _ = json.Marshal(domain.WebSocketMessage{Type: "system_message", Payload: noticePayload})
+	h.deliver([]uuid.UUID{invitee.ID}, msg)
+}
+
+func (h *Hub) handleAcceptInvite(req *ClientRequest) {
+	var payload domain.AcceptInvitePayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid accept_invite payload.")
+		return
+	}
+	user := &domain.User{ID: req.Client.Auth().UserID}
+	room, err := h.roomService.AcceptInvite(strings.TrimSpace(payload.Code), user)
+	if err != nil {
+		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to accept invite: %v", err))
+		return
+	}
+	// History from before joining should not count as unread
+	h.markRead(req.Client, room.ID.String())
+	joinSuccessPayload := domain.JoinSuccessPayload{RoomID: room.ID.String(), RoomName: room.Name}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "join_success", Payload: joinSuccessPayload})
+	req.Client.send(msg)
+	h.sendRoomNotice(room, fmt.Sprintf("%s joined by invitation.", req.Client.Auth().Nickname))
+	h.emitWebhook(room, domain.WebhookMemberJoined, domain.WebhookMemberData{Nickname: req.Client.Auth().Nickname, Reason: "invite"})
+}
+
+func (h *Hub) handleListInvites(req *ClientRequest) {
+	var payload domain.ListInvitesPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid list_invites payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	room, invites, err := h.roomService.ListInvites(payload.RoomName, actor)
+	if err != nil {
+		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to list invites: %v", err))
+		return
+	}
+
+	// Resolve creator and invitee IDs to nicknames, once per user.
+	nicknames := make(map[uuid.UUID]string)
+	nickname := func(id uuid.UUID) string {
+		if name, ok := nicknames[id]; ok {
+			return name
+		}
+		name := "(deleted user)"
+		if user, err := h.userService.GetUserByID(id); err == nil && user != nil {
+			name = user.Nickname
+		}
+		nicknames[id] = name
+		return name
+	}
+
+	infos := make([]domain.InviteInfo, len(invites))
+	for i, invite := range invites {
+		infos[i] = domain.InviteInfo{Code: invite.Code, CreatedBy: nickname(invite.CreatedBy), MaxUses: invite.MaxUses, Uses: invite.Uses, ExpiresAt: invite.ExpiresAt}
+		if invite.InviteeID != nil {
+			infos[i].Invitee = nickname(*invite.InviteeID)
+		}
+	}
+	listPayload := domain.InviteListPayload{RoomName: room.Name, Invites: infos}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "invite_list", Payload: listPayload})
+	req.Client.send(msg)
+}
+
+func (h *Hub) handleRevokeInvite(req *ClientRequest) {
+	var payload domain.RevokeInvitePayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid revoke_invite payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	room, err := h.roomService.RevokeInvite(payload.RoomName, actor, strings.TrimSpace(payload.Code))
+	if err != nil {
+		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to revoke invite: %v", err))
+		return
+	}
+	req.Client.sendSystemMessage("system_message", fmt.Sprintf("Invite %s for room '%s' was revoked.", payload.Code, room.Name))
+}
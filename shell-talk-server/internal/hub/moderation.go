@@ -0,0 +1,127 @@
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"shell-talk-server/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (h *Hub) handleKickMember(req *ClientRequest) {
+	var payload domain.ModerationPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid kick_member payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	room, target, err := h.roomService.KickMember(payload.RoomName, actor, payload.Nickname)
+	if err != nil {
+		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to kick member: %v", err))
+		return
+	}
+
+	notice := fmt.Sprintf("%s was kicked by %s.", target.Nickname, req.Client.Auth().Nickname)
+	if payload.Reason != "" {
+		notice = fmt.Sprintf("%s was kicked by %s: %s", target.Nickname, req.Client.Auth().Nickname, payload.Reason)
+	}
+	h.sendRoomNotice(room, notice)
+	h.notifyRemoved(target, room, notice)
+	h.emitMemberLeft(room, target.Nickname, "kicked")
+}
+
+func (h *Hub) handleBanMember(req *ClientRequest) {
+	var payload domain.ModerationPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid ban_member payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	duration := time.Duration(payload.DurationSeconds) * time.Second
+	room, target, err := h.roomService.BanMember(payload.RoomName, actor, payload.Nickname, duration, payload.Reason)
+	if err != nil {
+		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to ban member: %v", err))
+		return
+	}
+
+	notice := fmt.Sprintf("%s was banned by %s %s.", target.Nickname, req.Client.Auth().Nickname, describeDuration(duration))
+	if payload.Reason != "" {
+		notice = fmt.Sprintf("%s was banned by %s %s: %s", target.Nickname, req.Client.Auth().Nickname, describeDuration(duration), payload.Reason)
+	}
+	h.sendRoomNotice(room, notice)
+	h.notifyRemoved(target, room, notice)
+	h.emitMemberLeft(room, target.Nickname, "banned")
+}
+
+func (h *Hub) handleUnbanMember(req *ClientRequest) {
+	var payload domain.ModerationPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid unban_member payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	room, target, err := h.roomService.UnbanMember(payload.RoomName, actor, payload.Nickname)
+	if err != nil {
+		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to unban member: %v", err))
+		return
+	}
+	req.Client.sendSystemMessage("system_message", fmt.Sprintf("%s may join '%s' again.", target.Nickname, room.Name))
+}
+
+func (h *Hub) handleMuteMember(req *ClientRequest) {
+	var payload domain.ModerationPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid mute_member payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	duration := time.Duration(payload.DurationSeconds) * time.Second
+	room, target, err := h.roomService.MuteMember(payload.RoomName, actor, payload.Nickname, duration)
+	if err != nil {
+		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to mute member: %v", err))
+		return
+	}
+	h.sendRoomNotice(room, fmt.Sprintf("%s was muted by %s %s.", target.Nickname, req.Client.Auth().Nickname, describeDuration(duration)))
+}
+
+func (h *Hub) handleUnmuteMember(req *ClientRequest) {
+	var payload domain.ModerationPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid unmute_member payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	room, target, err := h.roomService.UnmuteMember(payload.RoomName, actor, payload.Nickname)
+	if err != nil {
+		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to unmute member: %v", err))
+		return
+	}
+	h.sendRoomNotice(room, fmt.Sprintf("%s was unmuted by %s.", target.Nickname, req.Client.Auth().Nickname))
+}
+
+// sendRoomNotice sends a system notice to every online member of a room.
+func (h *Hub) sendRoomNotice(room *domain.Room, content string) {
+	memberIDs, err := h.roomService.GetRoomMemberIDs(room.Name)
+	if err != nil {
+		return
+	}
+	noticePayload := domain.RoomNoticePayload{RoomName: room.Name, Content: content, Timestamp: time.Now()}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "room_notice", Payload: noticePayload})
+	h.deliver(memberIDs, msg)
+}
+
+// notifyRemoved tells a user, if online, that they were removed from a room.
+func (h *Hub) notifyRemoved(user *domain.User, room *domain.Room, reason string) {
+	removedPayload := domain.RoomRemovedPayload{RoomName: room.Name, Reason: reason}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "room_removed", Payload: removedPayload})
+	h.deliver([]uuid.UUID{user.ID}, msg)
+}
+
+// describeDuration renders a ban or mute length for notices.
+func describeDuration(duration time.Duration) string {
+	if duration <= 0 {
+		return "permanently"
+	}
+	return "for " + duration.String()
+}
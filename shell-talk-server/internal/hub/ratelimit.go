@@ -0,0 +1,136 @@
+package hub
+
+import (
+	"shell-talk-server/internal/config"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval is how often idle buckets are dropped.
+const rateLimitSweepInterval = time.Minute
+
+// tokenBucket holds the tokens left for one subject and message type.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens earned since the bucket was last used.
+func (b *tokenBucket) refill(limit config.RateLimit, now time.Time) {
+	b.tokens = min(limit.Burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+}
+
+// wait is how long until the bucket holds a whole token.
+func (b *tokenBucket) wait(limit config.RateLimit) time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+}
+
+// violations counts a subject's rejected messages within the abuse window.
+type violations struct {
+	count int
+	since time.Time
+}
+
+type bucketKey struct {
+	subject string
+	msgType string // "" for the bucket shared by all types
+}
+
+// RateLimiter applies token-bucket limits to incoming messages before they
+// reach the hub. Authenticated clients are limited per user, so
+// reconnecting does not refill their buckets; others per connection.
+type RateLimiter struct {
+	cfg        config.RateLimitConfig
+	mu         sync.Mutex
+	buckets    map[bucketKey]*tokenBucket
+	violations map[string]*violations
+	lastSweep  time.Time
+}
+
+// NewRateLimiter creates a RateLimiter with the configured limits.
+func NewRateLimiter(cfg *config.Config) *RateLimiter {
+	return &RateLimiter{
+		cfg:        cfg.RateLimit,
+		buckets:    make(map[bucketKey]*tokenBucket),
+		violations: make(map[string]*violations),
+		lastSweep:  time.Now(),
+	}
+}
+
+// Allow takes a token for a message of msgType from subject. If none is
+// available it returns how long to wait, and whether the subject has been
+// rejected so often that it should be disconnected.
+func (l *RateLimiter) Allow(subject, msgType string) (retryAfter time.Duration, abusive bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	shared := l.bucket(bucketKey{subject: subject}, l.cfg.Default, now)
+	retryAfter = shared.wait(l.cfg.Default)
+	typeLimit, hasTypeLimit := l.cfg.PerType[msgType]
+	var typed *tokenBucket
+	if hasTypeLimit {
+		typed = l.bucket(bucketKey{subject: subject, msgType: msgType}, typeLimit, now)
+		retryAfter = max(retryAfter, typed.wait(typeLimit))
+	}
+
+	if retryAfter == 0 {
+		// Tokens are only taken when every bucket has one, so a rejected
+		// message costs nothing.
+		shared.tokens--
+		if typed != nil {
+			typed.tokens--
+		}
+		return 0, false
+	}
+
+	v, ok := l.violations[subject]
+	if !ok || now.Sub(v.since) > l.cfg.AbuseWindow {
+		v = &violations{since: now}
+		l.violations[subject] = v
+	}
+	v.count++
+	return retryAfter, v.count > l.cfg.AbuseThreshold
+}
+
+// bucket returns the bucket for key, refilled to now.
+func (l *RateLimiter) bucket(key bucketKey, limit config.RateLimit, now time.Time) *tokenBucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: limit.Burst, last: now}
+		l.buckets[key] = b
+		return b
+	}
+	b.refill(limit, now)
+	return b
+}
+
+// sweep drops buckets that have refilled completely, since a new bucket
+// starts full anyway, and violations older than the abuse window.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		limit, ok := l.cfg.PerType[key.msgType]
+		if key.msgType == "" || !ok {
+			limit = l.cfg.Default
+		}
+		b.refill(limit, now)
+		if b.tokens >= limit.Burst {
+			delete(l.buckets, key)
+		}
+	}
+	for subject, v := range l.violations {
+		if now.Sub(v.since) > l.cfg.AbuseWindow {
+			delete(l.violations, subject)
+		}
+	}
+}
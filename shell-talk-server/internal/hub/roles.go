@@ -0,0 +1,41 @@
+package hub
+
+import (
+	"fmt"
+	"shell-talk-server/internal/domain"
+)
+
+func (h *Hub) handleSetRole(req *ClientRequest) {
+	var payload domain.SetRolePayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid set_role payload.")
+		return
+	}
+	role, ok := domain.ParseRoomRole(payload.Role)
+	if !ok {
+		req.Client.sendSystemMessage("error_message", "Role must be 'admin', 'member' or 'read_only'.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	room, target, err := h.roomService.SetMemberRole(payload.RoomName, actor, payload.Nickname, role)
+	if err != nil {
+		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to change role: %v", err))
+		return
+	}
+	h.sendRoomNotice(room, fmt.Sprintf("%s is now %s (set by %s).", target.Nickname, role, req.Client.Auth().Nickname))
+}
+
+func (h *Hub) handleTransferOwnership(req *ClientRequest) {
+	var payload domain.TransferOwnershipPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid transfer_ownership payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	room, target, err := h.roomService.TransferOwnership(payload.RoomName, actor, payload.Nickname)
+	if err != nil {
+		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to transfer ownership: %v", err))
+		return
+	}
+	h.sendRoomNotice(room, fmt.Sprintf("%s transferred ownership of the room to %s.", req.Client.Auth().Nickname, target.Nickname))
+}
@@ -0,0 +1,148 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"shell-talk-server/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+func (h *Hub) handleDeleteRoom(req *ClientRequest) {
+	var payload domain.DeleteRoomPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid delete_room payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	if _, err := h.deleteRoom(payload.RoomName, actor, req.Client.Auth().Nickname); err != nil {
+		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to delete room: %v", err))
+	}
+}
+
+// deleteRoom deletes a room and its history, then tells its former members
+// and its webhooks.
+func (h *Hub) deleteRoom(name string, actor *domain.User, deletedBy string) (*domain.Room, error) {
+	room, memberIDs, err := h.roomService.DeleteRoom(name, actor)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.messageRepo.DeleteConversation(context.Background(), room.ID.String()); err != nil {
+		log.Printf("error deleting history of room %s: %v", room.Name, err)
+	}
+	h.notifyRoomDeleted(room, memberIDs, deletedBy)
+	return room, nil
+}
+
+// notifyRoomDeleted tells the former members and the webhooks of a deleted room.
+func (h *Hub) notifyRoomDeleted(room *domain.Room, memberIDs []uuid.UUID, deletedBy string) {
+	deletedPayload := domain.RoomDeletedPayload{RoomName: room.Name, DeletedBy: deletedBy}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "room_deleted", Payload: deletedPayload})
+	for _, memberID := range memberIDs {
+		h.clearTyping(typingKey{userID: memberID, convType: "room", name: room.Name})
+	}
+	h.deliver(memberIDs, msg)
+	h.emitRoomDeleted(room, deletedBy)
+}
+
+func (h *Hub) handleRenameRoom(req *ClientRequest) {
+	var payload domain.RenameRoomPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid rename_room payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	room, err := h.roomService.RenameRoom(payload.RoomName, actor, payload.NewName)
+	if err != nil {
+		req.Client.sendError("error_message", "Failed to rename room", err)
+		return
+	}
+
+	h.notifyRoomRenamed(room, payload.RoomName, req.Client.Auth().Nickname)
+}
+
+// notifyRoomRenamed tells the members and the webhooks of a renamed room its
+// new name.
+func (h *Hub) notifyRoomRenamed(room *domain.Room, oldName, renamedBy string) {
+	memberIDs, err := h.roomService.GetRoomMemberIDs(room.Name)
+	if err != nil {
+		return
+	}
+	renamedPayload := domain.RoomRenamedPayload{OldName: oldName, NewName: room.Name, RenamedBy: renamedBy}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "room_renamed", Payload: renamedPayload})
+	for _, memberID := range memberIDs {
+		h.clearTyping(typingKey{userID: memberID, convType: "room", name: oldName})
+	}
+	h.deliver(memberIDs, msg)
+	h.emitWebhook(room, domain.WebhookRoomUpdated, domain.WebhookRoomData{Change: "renamed", By: renamedBy, OldName: oldName})
+}
+
+func (h *Hub) handleChangeRoomPassword(req *ClientRequest) {
+	var payload domain.ChangeRoomPasswordPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid change_room_password payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	room, err := h.roomService.ChangeRoomPassword(payload.RoomName, actor, payload.NewPassword)
+	if err != nil {
+		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to change room password: %v", err))
+		return
+	}
+	h.sendRoomNotice(room, fmt.Sprintf("%s changed the room password.", req.Client.Auth().Nickname))
+}
+
+func (h *Hub) handleSetVisibility(req *ClientRequest) {
+	var payload domain.SetVisibilityPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid set_visibility payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	room, err := h.roomService.SetVisibility(payload.RoomName, actor, payload.Visibility, payload.Password)
+	if err != nil {
+		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to change visibility: %v", err))
+		return
+	}
+	h.sendRoomNotice(room, fmt.Sprintf("%s made the room %s.", req.Client.Auth().Nickname, describeVisibility(room.Visibility)))
+	h.emitWebhook(room, domain.WebhookRoomUpdated, domain.WebhookRoomData{Change: "visibility", By: req.Client.Auth().Nickname, Visibility: room.Visibility})
+}
+
+func (h *Hub) handleSetTopic(req *ClientRequest) {
+	var payload domain.SetTopicPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid set_topic payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	room, err := h.roomService.SetTopic(payload.RoomName, actor, payload.Topic)
+	if err != nil {
+		req.Client.sendSystemMessage("error_message", fmt.Sprintf("Failed to set topic: %v", err))
+		return
+	}
+	h.notifyTopicChanged(room, req.Client.Auth().Nickname)
+}
+
+// notifyTopicChanged tells the members and the webhooks of a room its new topic.
+func (h *Hub) notifyTopicChanged(room *domain.Room, changedBy string) {
+	if room.Topic == "" {
+		h.sendRoomNotice(room, fmt.Sprintf("%s cleared the topic.", changedBy))
+	} else {
+		h.sendRoomNotice(room, fmt.Sprintf("%s set the topic: %s", changedBy, room.Topic))
+	}
+	h.emitWebhook(room, domain.WebhookRoomUpdated, domain.WebhookRoomData{Change: "topic", By: changedBy, Topic: room.Topic})
+}
+
+// describeVisibility renders a room visibility for notices.
+func describeVisibility(visibility string) string {
+	switch visibility {
+	case domain.RoomPublic:
+		return "public"
+	case domain.RoomInviteOnly:
+		return "invite-only"
+	default:
+		return "password-protected"
+	}
+}
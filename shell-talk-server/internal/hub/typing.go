@@ -0,0 +1,165 @@
+package hub
+
+import (
+	"encoding/json"
+	"shell-talk-server/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// typingTimeout is how long a typing indicator lasts without a refresh.
+	typingTimeout = 5 * time.Second
+	// typingSweepInterval is how often expired indicators are cleared.
+	typingSweepInterval = time.Second
+	// typingLane is the dispatcher lane sweeps run in, so they never overlap.
+	typingLane = "typing"
+)
+
+// typingKey identifies a user typing in a conversation, using the type and
+// name the typist's client refers to it by.
+type typingKey struct {
+	userID   uuid.UUID
+	convType string
+	name     string
+}
+
+// typingState is an active typing indicator. Recipients are resolved once
+// when typing starts, so refreshes while it is active never touch the
+// database. Only expiresAt changes afterwards, under Hub.typingMu.
+type typingState struct {
+	nickname   string
+	recipients map[uuid.UUID]string // User ID -> conversation name as that user sees it
+	expiresAt  time.Time
+}
+
+func (h *Hub) handleTypingStart(req *ClientRequest) {
+	var payload domain.TypingPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid typing_start payload.")
+		return
+	}
+	key := typingKey{userID: req.Client.Auth().UserID, convType: payload.ConversationType, name: payload.Name}
+
+	if h.refreshTyping(key) {
+		return
+	}
+
+	recipients, ok := h.typingRecipients(req.Client, payload.ConversationType, payload.Name)
+	if !ok {
+		return
+	}
+	state := &typingState{nickname: req.Client.Auth().Nickname, recipients: recipients, expiresAt: time.Now().Add(typingTimeout)}
+	h.typingMu.Lock()
+	h.typing[key] = state
+	h.typingMu.Unlock()
+	h.broadcastTyping(key, state, true)
+}
+
+// refreshTyping extends an active typing indicator, reporting whether there
+// was one.
+func (h *Hub) refreshTyping(key typingKey) bool {
+	h.typingMu.Lock()
+	defer h.typingMu.Unlock()
+	state, ok := h.typing[key]
+	if ok {
+		state.expiresAt = time.Now().Add(typingTimeout)
+	}
+	return ok
+}
+
+func (h *Hub) handleTypingStop(req *ClientRequest) {
+	var payload domain.TypingPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid typing_stop payload.")
+		return
+	}
+	h.clearTyping(typingKey{userID: req.Client.Auth().UserID, convType: payload.ConversationType, name: payload.Name})
+}
+
+// clearTyping ends a typing indicator, if one is active.
+func (h *Hub) clearTyping(key typingKey) {
+	h.typingMu.Lock()
+	state, ok := h.typing[key]
+	delete(h.typing, key)
+	h.typingMu.Unlock()
+	if ok {
+		h.broadcastTyping(key, state, false)
+	}
+}
+
+// clearUserTyping ends every typing indicator of a user, e.g. on disconnect.
+func (h *Hub) clearUserTyping(userID uuid.UUID) {
+	h.clearTypingWhere(func(key typingKey, _ *typingState) bool { return key.userID == userID })
+}
+
+// submitClearTyping runs clearUserTyping in the user's lane, for callers
+// such as the hub's event loop and the broker's goroutine that must not wait
+// on the broker.
+func (h *Hub) submitClearTyping(userID uuid.UUID) {
+	h.dispatcher.submit(userLane(userID), func() { h.clearUserTyping(userID) })
+}
+
+// sweepTyping ends indicators that have not been refreshed in time.
+func (h *Hub) sweepTyping(now time.Time) {
+	h.clearTypingWhere(func(_ typingKey, state *typingState) bool { return now.After(state.expiresAt) })
+}
+
+func (h *Hub) clearTypingWhere(match func(typingKey, *typingState) bool) {
+	h.typingMu.Lock()
+	cleared := make(map[typingKey]*typingState)
+	for key, state := range h.typing {
+		if match(key, state) {
+			cleared[key] = state
+			delete(h.typing, key)
+		}
+	}
+	h.typingMu.Unlock()
+	for key, state := range cleared {
+		h.broadcastTyping(key, state, false)
+	}
+}
+
+// typingRecipients resolves the other online participants of a conversation.
+// Typing indicators are best-effort, so failures are not reported back.
+func (h *Hub) typingRecipients(client *Client, convType, name string) (map[uuid.UUID]string, bool) {
+	recipients := make(map[uuid.UUID]string)
+	switch convType {
+	case "room":
+		user := &domain.User{ID: client.Auth().UserID}
+		if _, err := h.roomService.Authorize(name, user, domain.PermSendMessages); err != nil {
+			return nil, false
+		}
+		memberIDs, err := h.roomService.GetRoomMemberIDs(name)
+		if err != nil {
+			return nil, false
+		}
+		for _, memberID := range memberIDs {
+			if memberID != client.Auth().UserID {
+				recipients[memberID] = name
+			}
+		}
+	case "dm":
+		peer, err := h.userService.GetUserByNickname(name)
+		if err != nil || peer == nil || peer.ID == client.Auth().UserID {
+			return nil, false
+		}
+		recipients[peer.ID] = client.Auth().Nickname
+	default:
+		return nil, false
+	}
+	return recipients, true
+}
+
+func (h *Hub) broadcastTyping(key typingKey, state *typingState, typing bool) {
+	byName := make(map[string][]uuid.UUID)
+	for userID, name := range state.recipients {
+		byName[name] = append(byName[name], userID)
+	}
+	for name, userIDs := range byName {
+		updatePayload := domain.TypingUpdatePayload{ConversationType: key.convType, Name: name, Nickname: state.nickname, Typing: typing}
+		msg, _ := json.Marshal(domain.WebSocketMessage{Type: "typing_update", Payload: updatePayload})
+		h.deliverBestEffort(userIDs, msg)
+	}
+}
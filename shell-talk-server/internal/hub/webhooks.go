@@ -0,0 +1,184 @@
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"shell-talk-server/internal/domain"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+func (h *Hub) handleCreateWebhook(req *ClientRequest) {
+	var payload domain.CreateWebhookPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid create_webhook payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	webhook, room, err := h.webhookService.CreateWebhook(payload.RoomName, actor, strings.TrimSpace(payload.URL), payload.Events)
+	if err != nil {
+		req.Client.sendError("error_message", "Failed to create webhook", err)
+		return
+	}
+	createdPayload := domain.WebhookCreatedPayload{RoomName: room.Name, Webhook: webhook.Info(req.Client.Auth().Nickname), Secret: webhook.Secret}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "webhook_created", Payload: createdPayload})
+	req.Client.send(msg)
+}
+
+func (h *Hub) handleListWebhooks(req *ClientRequest) {
+	var payload domain.ListWebhooksPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid list_webhooks payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	room, webhooks, err := h.webhookService.ListWebhooks(payload.RoomName, actor)
+	if err != nil {
+		req.Client.sendError("error_message", "Failed to list webhooks", err)
+		return
+	}
+	listPayload := domain.WebhookListPayload{RoomName: room.Name, Webhooks: h.WebhookInfos(webhooks)}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "webhook_list", Payload: listPayload})
+	req.Client.send(msg)
+}
+
+func (h *Hub) handleDeleteWebhook(req *ClientRequest) {
+	var payload domain.DeleteWebhookPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid delete_webhook payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	room, err := h.webhookService.DeleteWebhook(payload.RoomName, actor, strings.TrimSpace(payload.ID))
+	if err != nil {
+		req.Client.sendError("error_message", "Failed to delete webhook", err)
+		return
+	}
+	req.Client.sendSystemMessage("system_message", fmt.Sprintf("Webhook %s for room '%s' was deleted.", payload.ID, room.Name))
+}
+
+func (h *Hub) handleListWebhookFailures(req *ClientRequest) {
+	var payload domain.ListWebhookFailuresPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid list_webhook_failures payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	room, failures, err := h.webhookService.ListFailures(payload.RoomName, actor, payload.Limit)
+	if err != nil {
+		req.Client.sendError("error_message", "Failed to list webhook failures", err)
+		return
+	}
+	failuresPayload := domain.WebhookFailuresPayload{RoomName: room.Name, Failures: make([]domain.WebhookFailureInfo, len(failures))}
+	for i, failure := range failures {
+		failuresPayload.Failures[i] = failure.FailureInfo()
+	}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "webhook_failures", Payload: failuresPayload})
+	req.Client.send(msg)
+}
+
+func (h *Hub) handleCreateIncomingWebhook(req *ClientRequest) {
+	var payload domain.CreateIncomingWebhookPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid create_incoming_webhook payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	webhook, token, room, err := h.webhookService.CreateIncomingWebhook(payload.RoomName, actor, payload.Name)
+	if err != nil {
+		req.Client.sendError("error_message", "Failed to create incoming webhook", err)
+		return
+	}
+	createdPayload := domain.IncomingWebhookCreatedPayload{RoomName: room.Name, Webhook: webhook.Info(req.Client.Auth().Nickname), Token: token, Path: domain.IncomingWebhookPath + token}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "incoming_webhook_created", Payload: createdPayload})
+	req.Client.send(msg)
+}
+
+func (h *Hub) handleListIncomingWebhooks(req *ClientRequest) {
+	var payload domain.ListIncomingWebhooksPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid list_incoming_webhooks payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	room, webhooks, err := h.webhookService.ListIncomingWebhooks(payload.RoomName, actor)
+	if err != nil {
+		req.Client.sendError("error_message", "Failed to list incoming webhooks", err)
+		return
+	}
+	listPayload := domain.IncomingWebhookListPayload{RoomName: room.Name, Webhooks: h.IncomingWebhookInfos(webhooks)}
+	msg, _ := json.Marshal(domain.WebSocketMessage{Type: "incoming_webhook_list", Payload: listPayload})
+	req.Client.send(msg)
+}
+
+func (h *Hub) handleDeleteIncomingWebhook(req *ClientRequest) {
+	var payload domain.DeleteIncomingWebhookPayload
+	if err := parsePayload(req.Message.Payload, &payload); err != nil {
+		req.Client.sendSystemMessage("error_message", "Invalid delete_incoming_webhook payload.")
+		return
+	}
+	actor := &domain.User{ID: req.Client.Auth().UserID}
+	room, err := h.webhookService.DeleteIncomingWebhook(payload.RoomName, actor, strings.TrimSpace(payload.ID))
+	if err != nil {
+		req.Client.sendError("error_message", "Failed to delete incoming webhook", err)
+		return
+	}
+	req.Client.sendSystemMessage("system_message", fmt.Sprintf("Incoming webhook %s for room '%s' was deleted.", payload.ID, room.Name))
+}
+
+// WebhookInfos describes webhooks for clients, resolving their creators'
+// nicknames once per user.
+func (h *Hub) WebhookInfos(webhooks []*domain.RoomWebhook) []domain.WebhookInfo {
+	nicknames := make(map[uuid.UUID]string)
+	infos := make([]domain.WebhookInfo, len(webhooks))
+	for i, webhook := range webhooks {
+		infos[i] = webhook.Info(h.creatorName(nicknames, webhook.CreatedBy))
+	}
+	return infos
+}
+
+// IncomingWebhookInfos describes incoming webhooks for clients, resolving
+// their creators' nicknames once per user.
+func (h *Hub) IncomingWebhookInfos(webhooks []*domain.IncomingWebhook) []domain.IncomingWebhookInfo {
+	nicknames := make(map[uuid.UUID]string)
+	infos := make([]domain.IncomingWebhookInfo, len(webhooks))
+	for i, webhook := range webhooks {
+		infos[i] = webhook.Info(h.creatorName(nicknames, webhook.CreatedBy))
+	}
+	return infos
+}
+
+// creatorName looks up a user's nickname, caching it in nicknames.
+func (h *Hub) creatorName(nicknames map[uuid.UUID]string, userID uuid.UUID) string {
+	name, ok := nicknames[userID]
+	if !ok {
+		name = "(deleted user)"
+		if user, err := h.userService.GetUserByID(userID); err == nil && user != nil {
+			name = user.Nickname
+		}
+		nicknames[userID] = name
+	}
+	return name
+}
+
+// emitWebhook queues a room event for the room's webhooks. Failing to queue
+// it does not fail the action that caused it.
+func (h *Hub) emitWebhook(room *domain.Room, event string, data interface{}) {
+	if err := h.webhookService.Emit(room, event, data); err != nil {
+		log.Printf("error queueing %s webhooks for room %s: %v", event, room.Name, err)
+	}
+}
+
+// emitMemberLeft queues the 'member_left' event, with why the member left.
+func (h *Hub) emitMemberLeft(room *domain.Room, nickname, reason string) {
+	h.emitWebhook(room, domain.WebhookMemberLeft, domain.WebhookMemberData{Nickname: nickname, Reason: reason})
+}
+
+// emitRoomDeleted queues the 'room_deleted' event and drops the room's webhooks.
+func (h *Hub) emitRoomDeleted(room *domain.Room, deletedBy string) {
+	if err := h.webhookService.RoomDeleted(room, deletedBy); err != nil {
+		log.Printf("error queueing room_deleted webhooks for room %s: %v", room.Name, err)
+	}
+}
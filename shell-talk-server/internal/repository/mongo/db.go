@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -28,5 +29,44 @@ func NewDB(ctx context.Context, connectionString string) (*mongo.Database, error
 	// For simplicity, we'll assume a database name, e.g., "shelltalk".
 	db := client.Database("shelltalk")
 
+	// History paging filters by conversation and walks backwards in time.
+	index := mongo.IndexModel{
+		Keys: bson.D{{Key: "conversation_id", Value: 1}, {Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}},
+	}
+	if _, err := db.Collection(messageCollection).Indexes().CreateOne(ctx, index); err != nil {
+		return nil, fmt.Errorf("failed to create message index: %w", err)
+	}
+
+	// Retried sends carry the same request ID and must not be stored twice.
+	requestIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "sender_id", Value: 1}, {Key: "request_id", Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"request_id": bson.M{"$exists": true}}),
+	}
+	if _, err := db.Collection(messageCollection).Indexes().CreateOne(ctx, requestIndex); err != nil {
+		return nil, fmt.Errorf("failed to create message request index: %w", err)
+	}
+
+	// Each user has at most one read cursor per conversation.
+	cursorIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "conversation_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	if _, err := db.Collection(readCursorCollection).Indexes().CreateOne(ctx, cursorIndex); err != nil {
+		return nil, fmt.Errorf("failed to create read cursor index: %w", err)
+	}
+
+	// A user's DM conversations are looked up by participant.
+	dmIndex := mongo.IndexModel{Keys: bson.D{{Key: "user_ids", Value: 1}}}
+	if _, err := db.Collection(dmConversationCollection).Indexes().CreateOne(ctx, dmIndex); err != nil {
+		return nil, fmt.Errorf("failed to create DM conversation index: %w", err)
+	}
+	// Reading every conversation ID may take longer than the setup above.
+	backfillCtx, cancelBackfill := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+	defer cancelBackfill()
+	if err := backfillDMConversations(backfillCtx, db); err != nil {
+		return nil, fmt.Errorf("failed to record DM conversations: %w", err)
+	}
+
 	return db, nil
 }
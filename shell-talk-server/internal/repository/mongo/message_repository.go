@@ -3,12 +3,26 @@ package mongo
 import (
 	"context"
 	"shell-talk-server/internal/domain"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-const messageCollection = "messages"
+const (
+	messageCollection = "messages"
+	// dmConversationCollection records the participants of each DM
+	// conversation, so a user's DMs are found without scanning messages.
+	dmConversationCollection = "dm_conversations"
+)
+
+// dmConversation is a DM conversation's entry in dmConversationCollection.
+type dmConversation struct {
+	ID      string   `bson:"_id"`
+	UserIDs []string `bson:"user_ids"`
+}
 
 // MessageRepository handles database operations for chat messages.
 type MessageRepository struct {
@@ -20,21 +34,125 @@ func NewMessageRepository(db *mongo.Database) *MessageRepository {
 	return &MessageRepository{DB: db}
 }
 
-// SaveMessage inserts a new chat message into the database.
+// SaveMessage inserts a new chat message into the database and assigns its ID.
+// It returns domain.ErrDuplicateMessage if the sender already stored a message
+// with the same request ID.
 func (r *MessageRepository) SaveMessage(ctx context.Context, message *domain.ChatMessage) error {
+	// The conversation is recorded first, so a stored DM is always listed.
+	if first, second, ok := domain.ParseDMConversationID(message.ConversationID); ok {
+		if err := recordDMConversation(ctx, r.DB, message.ConversationID, first.String(), second.String()); err != nil {
+			return err
+		}
+	}
+
 	collection := r.DB.Collection(messageCollection)
+	if message.ID.IsZero() {
+		message.ID = primitive.NewObjectID()
+	}
 	_, err := collection.InsertOne(ctx, message)
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.ErrDuplicateMessage
+	}
+	return err
+}
+
+// GetMessageByRequestID retrieves the message a sender stored under a client
+// request ID, or nil if there is none.
+func (r *MessageRepository) GetMessageByRequestID(ctx context.Context, senderID, requestID string) (*domain.ChatMessage, error) {
+	collection := r.DB.Collection(messageCollection)
+	var message domain.ChatMessage
+	err := collection.FindOne(ctx, bson.M{"sender_id": senderID, "request_id": requestID}).Decode(&message)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+// GetMessageByID retrieves a message by its ObjectID, or nil if there is none.
+func (r *MessageRepository) GetMessageByID(ctx context.Context, id primitive.ObjectID) (*domain.ChatMessage, error) {
+	collection := r.DB.Collection(messageCollection)
+	var message domain.ChatMessage
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&message)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+// UpdateMessageContent replaces a message's content, archiving the previous
+// content as a new edit version. The update only applies if the message is
+// still at the version it was read at; otherwise domain.ErrMessageConflict
+// is returned.
+func (r *MessageRepository) UpdateMessageContent(ctx context.Context, message *domain.ChatMessage, content string, editedAt time.Time) error {
+	collection := r.DB.Collection(messageCollection)
+	filter := bson.M{"_id": message.ID, "version": message.Version, "deleted": bson.M{"$ne": true}}
+	if message.Version == 0 {
+		// Messages stored before versioning have no version field at all.
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	update := bson.M{
+		"$set":  bson.M{"content": content, "edited_at": editedAt},
+		"$inc":  bson.M{"version": 1},
+		"$push": bson.M{"edits": domain.MessageEdit{Version: message.Version, Content: message.Content, EditedAt: editedAt}},
+	}
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrMessageConflict
+	}
+	return nil
+}
+
+// DeleteMessage soft-deletes a message, clearing its content and edit history
+// but keeping its place in the conversation.
+func (r *MessageRepository) DeleteMessage(ctx context.Context, id primitive.ObjectID, deletedAt time.Time) error {
+	collection := r.DB.Collection(messageCollection)
+	update := bson.M{
+		"$set":   bson.M{"deleted": true, "deleted_at": deletedAt, "content": ""},
+		"$unset": bson.M{"edits": ""},
+	}
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, update)
 	return err
 }
 
-// GetMessagesByConversationID retrieves the last N messages for a conversation.
-func (r *MessageRepository) GetMessagesByConversationID(ctx context.Context, conversationID string, limit int64) ([]*domain.ChatMessage, error) {
+// DeleteConversation permanently removes every message in a conversation.
+func (r *MessageRepository) DeleteConversation(ctx context.Context, conversationID string) error {
 	collection := r.DB.Collection(messageCollection)
+	if _, err := collection.DeleteMany(ctx, bson.M{"conversation_id": conversationID}); err != nil {
+		return err
+	}
+	_, err := r.DB.Collection(dmConversationCollection).DeleteOne(ctx, bson.M{"_id": conversationID})
+	return err
+}
 
-	// Find options to sort by timestamp descending and limit the results
-	// opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}}).SetLimit(limit)
+// GetMessagesByConversationID retrieves up to limit messages for a conversation
+// that precede the given cursor (or the newest messages if before is nil).
+// The result is ordered oldest to newest.
+func (r *MessageRepository) GetMessagesByConversationID(ctx context.Context, conversationID string, before *domain.MessageCursor, limit int64) ([]*domain.ChatMessage, error) {
+	collection := r.DB.Collection(messageCollection)
+
+	filter := bson.M{"conversation_id": conversationID}
+	if before != nil {
+		filter["$or"] = bson.A{
+			bson.M{"timestamp": bson.M{"$lt": before.Timestamp}},
+			bson.M{"timestamp": before.Timestamp, "_id": bson.M{"$lt": before.ID}},
+		}
+	}
 
-	cursor, err := collection.Find(ctx, bson.M{"conversation_id": conversationID})
+	// Walk backwards from the cursor so the limit keeps the most recent page.
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -45,8 +163,132 @@ func (r *MessageRepository) GetMessagesByConversationID(ctx context.Context, con
 		return nil, err
 	}
 
-	// The messages are currently sorted oldest to newest. If we want newest first, we need to sort.
-	// For a chat application, we usually want to show the oldest first, so this is fine.
+	// Flip back to chronological order for display.
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
 
 	return messages, nil
 }
+
+// GetMessagesAfter retrieves up to limit messages for a conversation that
+// follow the given cursor, ordered oldest to newest.
+func (r *MessageRepository) GetMessagesAfter(ctx context.Context, conversationID string, after *domain.MessageCursor, limit int64) ([]*domain.ChatMessage, error) {
+	collection := r.DB.Collection(messageCollection)
+
+	filter := bson.M{
+		"conversation_id": conversationID,
+		"$or": bson.A{
+			bson.M{"timestamp": bson.M{"$gt": after.Timestamp}},
+			bson.M{"timestamp": after.Timestamp, "_id": bson.M{"$gt": after.ID}},
+		},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var messages []*domain.ChatMessage
+	if err = cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// CountUnread counts, for each conversation given, the messages stored after
+// its time in since, ignoring those sent by excludeSenderID. Conversations
+// without unread messages are left out.
+func (r *MessageRepository) CountUnread(ctx context.Context, since map[string]time.Time, excludeSenderID string) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	if len(since) == 0 {
+		return counts, nil
+	}
+	conversations := make(bson.A, 0, len(since))
+	for convoID, readAt := range since {
+		conversations = append(conversations, bson.M{"conversation_id": convoID, "timestamp": bson.M{"$gt": readAt}})
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"$or": conversations, "sender_id": bson.M{"$ne": excludeSenderID}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$conversation_id", "count": bson.M{"$sum": 1}}}},
+	}
+
+	collection := r.DB.Collection(messageCollection)
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		ConversationID string `bson:"_id"`
+		Count          int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		counts[result.ConversationID] = result.Count
+	}
+	return counts, nil
+}
+
+// GetDMConversationIDs returns the IDs of every DM conversation the user has
+// taken part in.
+func (r *MessageRepository) GetDMConversationIDs(ctx context.Context, userID string) ([]string, error) {
+	collection := r.DB.Collection(dmConversationCollection)
+	cursor, err := collection.Find(ctx, bson.M{"user_ids": userID}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var conversations []dmConversation
+	if err := cursor.All(ctx, &conversations); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(conversations))
+	for _, conversation := range conversations {
+		ids = append(ids, conversation.ID)
+	}
+	return ids, nil
+}
+
+// recordDMConversation adds a DM conversation to dmConversationCollection
+// unless it is already there.
+func recordDMConversation(ctx context.Context, db *mongo.Database, convoID string, userIDs ...string) error {
+	_, err := db.Collection(dmConversationCollection).UpdateOne(ctx,
+		bson.M{"_id": convoID},
+		bson.M{"$setOnInsert": bson.M{"user_ids": userIDs}},
+		options.Update().SetUpsert(true))
+	return err
+}
+
+// backfillDMConversations records the DM conversations of messages stored
+// before dmConversationCollection existed. It reads every conversation ID
+// once, so it only runs while the collection is empty.
+func backfillDMConversations(ctx context.Context, db *mongo.Database) error {
+	recorded, err := db.Collection(dmConversationCollection).EstimatedDocumentCount(ctx)
+	if err != nil || recorded > 0 {
+		return err
+	}
+	values, err := db.Collection(messageCollection).Distinct(ctx, "conversation_id", bson.M{})
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		convoID, _ := v.(string)
+		first, second, ok := domain.ParseDMConversationID(convoID)
+		if !ok {
+			continue
+		}
+		if err := recordDMConversation(ctx, db, convoID, first.String(), second.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
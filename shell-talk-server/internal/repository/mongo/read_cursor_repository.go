@@ -0,0 +1,54 @@
+package mongo
+
+import (
+	"context"
+	"shell-talk-server/internal/domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const readCursorCollection = "read_cursors"
+
+// ReadCursorRepository handles database operations for per-user read cursors.
+type ReadCursorRepository struct {
+	DB *mongo.Database
+}
+
+// NewReadCursorRepository creates a new ReadCursorRepository.
+func NewReadCursorRepository(db *mongo.Database) *ReadCursorRepository {
+	return &ReadCursorRepository{DB: db}
+}
+
+// MarkRead moves the user's cursor for a conversation forward to readAt.
+// A cursor never moves backwards.
+func (r *ReadCursorRepository) MarkRead(ctx context.Context, userID, conversationID string, readAt time.Time) error {
+	collection := r.DB.Collection(readCursorCollection)
+	filter := bson.M{"user_id": userID, "conversation_id": conversationID}
+	update := bson.M{"$max": bson.M{"last_read_at": readAt}}
+	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// GetReadCursors returns the user's read cursors keyed by conversation ID.
+func (r *ReadCursorRepository) GetReadCursors(ctx context.Context, userID string) (map[string]time.Time, error) {
+	collection := r.DB.Collection(readCursorCollection)
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var readCursors []*domain.ReadCursor
+	if err = cursor.All(ctx, &readCursors); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]time.Time, len(readCursors))
+	for _, rc := range readCursors {
+		result[rc.ConversationID] = rc.LastReadAt
+	}
+	return result, nil
+}
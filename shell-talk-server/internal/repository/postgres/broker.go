@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"shell-talk-server/internal/domain"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	brokerChannel = "shell_talk_events"
+	// maxNotifyPayload keeps inline events under PostgreSQL's 8000-byte
+	// limit on NOTIFY payloads.
+	maxNotifyPayload = 7900
+	// spilledPrefix marks a notification that carries the ID of an event
+	// stored in broker_events instead of the event itself.
+	spilledPrefix = "#"
+	// spilledEventTTL is how long stored events are kept for listeners.
+	spilledEventTTL = time.Minute
+	// listenerPingInterval is how often an idle listener checks its connection.
+	listenerPingInterval = 90 * time.Second
+)
+
+// Broker shares hub events between server instances through PostgreSQL
+// LISTEN/NOTIFY. Events too large for a notification are stored in
+// broker_events, and the notification carries their ID.
+type Broker struct {
+	DB       *sql.DB
+	listener *pq.Listener
+	mu       sync.RWMutex
+	handlers []func(*domain.BrokerEvent)
+	done     chan struct{}
+}
+
+// NewBroker opens a dedicated listening connection and starts passing
+// events to subscribers.
+func NewBroker(db *sql.DB, dataSourceName string) (*Broker, error) {
+	listener := pq.NewListener(dataSourceName, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("broker listener: %v", err)
+		}
+	})
+	if err := listener.Listen(brokerChannel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to listen for broker events: %w", err)
+	}
+	b := &Broker{DB: db, listener: listener, done: make(chan struct{})}
+	go b.listen()
+	return b, nil
+}
+
+// Publish notifies every instance, this one included, of the event.
+func (b *Broker) Publish(event *domain.BrokerEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if len(data) <= maxNotifyPayload {
+		_, err = b.DB.Exec(`SELECT pg_notify($1, $2)`, brokerChannel, string(data))
+		return err
+	}
+
+	// Notifications are sent on commit, so listeners can read the row.
+	tx, err := b.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var id int64
+	if err := tx.QueryRow(`INSERT INTO broker_events (payload) VALUES ($1) RETURNING id`, string(data)).Scan(&id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`SELECT pg_notify($1, $2)`, brokerChannel, spilledPrefix+strconv.FormatInt(id, 10)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Subscribe registers a handler for every event received from now on.
+func (b *Broker) Subscribe(handler func(*domain.BrokerEvent)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Close stops listening.
+func (b *Broker) Close() error {
+	close(b.done)
+	return b.listener.Close()
+}
+
+// listen hands notifications to subscribers one at a time, so they see each
+// instance's events in the order they were published.
+func (b *Broker) listen() {
+	pingTicker := time.NewTicker(listenerPingInterval)
+	defer pingTicker.Stop()
+	purgeTicker := time.NewTicker(spilledEventTTL)
+	defer purgeTicker.Stop()
+
+	for {
+		select {
+		case notification := <-b.listener.Notify:
+			if notification == nil {
+				// The connection was re-established; anything sent meanwhile is lost.
+				log.Println("broker listener reconnected; events may have been missed")
+				continue
+			}
+			event, err := b.decode(notification.Extra)
+			if err != nil {
+				log.Printf("error reading broker event: %v", err)
+				continue
+			}
+			b.mu.RLock()
+			for _, handler := range b.handlers {
+				handler(event)
+			}
+			b.mu.RUnlock()
+		case <-pingTicker.C:
+			go b.listener.Ping()
+		case <-purgeTicker.C:
+			if _, err := b.DB.Exec(`DELETE FROM broker_events WHERE created_at < NOW() - make_interval(secs => $1)`, spilledEventTTL.Seconds()); err != nil {
+				log.Printf("error purging broker events: %v", err)
+			}
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// decode parses a notification payload, loading stored events by ID.
+func (b *Broker) decode(payload string) (*domain.BrokerEvent, error) {
+	if idText, ok := strings.CutPrefix(payload, spilledPrefix); ok {
+		id, err := strconv.ParseInt(idText, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.DB.QueryRow(`SELECT payload FROM broker_events WHERE id = $1`, id).Scan(&payload); err != nil {
+			return nil, fmt.Errorf("loading event %d: %w", id, err)
+		}
+	}
+	var event domain.BrokerEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS user_presence (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL DEFAULT 'offline',
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+ALTER TABLE room_members ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'member';
+
+INSERT INTO room_members (room_id, user_id, role)
+SELECT id, owner_id, 'owner' FROM rooms
+ON CONFLICT (room_id, user_id) DO UPDATE SET role = 'owner';
@@ -0,0 +1,2 @@
+ALTER TABLE rooms DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE rooms DROP COLUMN IF EXISTS topic;
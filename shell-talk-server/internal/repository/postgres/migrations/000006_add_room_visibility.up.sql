@@ -0,0 +1 @@
+ALTER TABLE rooms ADD COLUMN IF NOT EXISTS visibility VARCHAR(20) NOT NULL DEFAULT 'password';
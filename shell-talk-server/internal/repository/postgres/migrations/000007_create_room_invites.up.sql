@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS room_invites (
+    code VARCHAR(32) PRIMARY KEY,
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL, -- Invites outlive their creator's account
+    invitee_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    max_uses INTEGER NOT NULL DEFAULT 1,
+    uses INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_invites_room_id ON room_invites (room_id);
@@ -0,0 +1 @@
+ALTER TABLE user_presence DROP COLUMN IF EXISTS node_id;
@@ -0,0 +1,2 @@
+-- The server instance a user is connected to; only that instance marks them offline.
+ALTER TABLE user_presence ADD COLUMN IF NOT EXISTS node_id VARCHAR(100) NOT NULL DEFAULT '';
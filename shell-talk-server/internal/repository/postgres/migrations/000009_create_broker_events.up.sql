@@ -0,0 +1,8 @@
+-- Broker events too large for a NOTIFY payload; listeners read them by ID.
+CREATE TABLE IF NOT EXISTS broker_events (
+    id BIGSERIAL PRIMARY KEY,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_broker_events_created_at ON broker_events (created_at);
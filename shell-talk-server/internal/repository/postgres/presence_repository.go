@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"database/sql"
+	"shell-talk-server/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// PresenceRepository handles database operations for user presence.
+type PresenceRepository struct {
+	DB *sql.DB
+}
+
+// NewPresenceRepository creates a new PresenceRepository.
+func NewPresenceRepository(db *sql.DB) *PresenceRepository {
+	return &PresenceRepository{DB: db}
+}
+
+// SetPresence records a user's status, when they were last seen and the
+// server instance they are connected to.
+func (r *PresenceRepository) SetPresence(userID uuid.UUID, status string, lastSeenAt time.Time, nodeID string) error {
+	query := `
+		INSERT INTO user_presence (user_id, status, last_seen_at, node_id) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET status = EXCLUDED.status, last_seen_at = EXCLUDED.last_seen_at, node_id = EXCLUDED.node_id
+	`
+	_, err := r.DB.Exec(query, userID, status, lastSeenAt, nodeID)
+	return err
+}
+
+// SetOffline marks a user offline if they were last connected to the given
+// instance. It reports false if they have since connected to another one.
+func (r *PresenceRepository) SetOffline(userID uuid.UUID, lastSeenAt time.Time, nodeID string) (bool, error) {
+	query := `UPDATE user_presence SET status = $1, last_seen_at = $2 WHERE user_id = $3 AND node_id = $4`
+	result, err := r.DB.Exec(query, domain.PresenceOffline, lastSeenAt, userID, nodeID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// GetOnlineElsewhere returns those of the given users who are online on an
+// instance other than nodeID.
+func (r *PresenceRepository) GetOnlineElsewhere(userIDs []uuid.UUID, nodeID string) ([]uuid.UUID, error) {
+	query := `SELECT user_id FROM user_presence WHERE user_id = ANY($1) AND status <> $2 AND node_id <> $3`
+	rows, err := r.DB.Query(query, pq.Array(userIDs), domain.PresenceOffline, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var online []uuid.UUID
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		online = append(online, userID)
+	}
+	return online, rows.Err()
+}
+
+// MarkAllOffline resets every user connected to the given instance to
+// offline. It is used at startup, since no connections survive a restart.
+// Users recorded before instances were tracked are reset too.
+func (r *PresenceRepository) MarkAllOffline(nodeID string) error {
+	query := `UPDATE user_presence SET status = $1 WHERE status <> $1 AND node_id IN ($2, '')`
+	_, err := r.DB.Exec(query, domain.PresenceOffline, nodeID)
+	return err
+}
@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"database/sql"
+	"shell-talk-server/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// CreateInvite inserts a new room invite.
+func (r *RoomRepository) CreateInvite(invite *domain.RoomInvite) error {
+	query := `
+		INSERT INTO room_invites (code, room_id, created_by, invitee_id, max_uses, uses, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.DB.Exec(query, invite.Code, invite.RoomID, invite.CreatedBy, invite.InviteeID, invite.MaxUses, invite.Uses, invite.ExpiresAt, invite.CreatedAt)
+	return err
+}
+
+// GetInvite retrieves an invite by its code, or nil if there is none.
+func (r *RoomRepository) GetInvite(code string) (*domain.RoomInvite, error) {
+	query := `
+		SELECT code, room_id, created_by, invitee_id, max_uses, uses, expires_at, created_at
+		FROM room_invites WHERE code = $1
+	`
+	invite, err := scanInvite(r.DB.QueryRow(query, code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return invite, nil
+}
+
+// ListInvites retrieves a room's invites that can still be used, newest first.
+func (r *RoomRepository) ListInvites(roomID uuid.UUID) ([]*domain.RoomInvite, error) {
+	query := `
+		SELECT code, room_id, created_by, invitee_id, max_uses, uses, expires_at, created_at
+		FROM room_invites
+		WHERE room_id = $1 AND (max_uses = 0 OR uses < max_uses) AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC
+	`
+	rows, err := r.DB.Query(query, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []*domain.RoomInvite
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, nil
+}
+
+// UseInvite consumes one use of an invite and adds the user to its room as
+// a member, in a single transaction, so a failed add does not burn the
+// invite. It reports false if the invite has expired or run out of uses, so
+// concurrent accepts cannot exceed max_uses.
+func (r *RoomRepository) UseInvite(code string, userID uuid.UUID) (bool, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE room_invites SET uses = uses + 1
+		WHERE code = $1 AND (max_uses = 0 OR uses < max_uses) AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING room_id
+	`
+	var roomID uuid.UUID
+	if err := tx.QueryRow(query, code).Scan(&roomID); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	memberQuery := `INSERT INTO room_members (room_id, user_id, role) VALUES ($1, $2, $3) ON CONFLICT (room_id, user_id) DO NOTHING`
+	if _, err := tx.Exec(memberQuery, roomID, userID, domain.RoleMember); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// DeleteInvite revokes an invite.
+func (r *RoomRepository) DeleteInvite(code string) error {
+	query := `DELETE FROM room_invites WHERE code = $1`
+	_, err := r.DB.Exec(query, code)
+	return err
+}
+
+// scanInvite reads a room_invites row selected in column order.
+func scanInvite(row interface{ Scan(...any) error }) (*domain.RoomInvite, error) {
+	invite := &domain.RoomInvite{}
+	var inviteeID uuid.NullUUID
+	var expiresAt sql.NullTime
+	if err := row.Scan(&invite.Code, &invite.RoomID, &invite.CreatedBy, &inviteeID, &invite.MaxUses, &invite.Uses, &expiresAt, &invite.CreatedAt); err != nil {
+		return nil, err
+	}
+	if inviteeID.Valid {
+		invite.InviteeID = &inviteeID.UUID
+	}
+	if expiresAt.Valid {
+		invite.ExpiresAt = &expiresAt.Time
+	}
+	return invite, nil
+}
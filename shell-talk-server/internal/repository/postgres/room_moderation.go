@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"database/sql"
+	"shell-talk-server/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// BanUser records a ban, replacing any existing ban for the same user, and
+// removes the user from the room, in one transaction.
+func (r *RoomRepository) BanUser(ban *domain.RoomBan) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO room_bans (room_id, user_id, banned_by, reason, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (room_id, user_id) DO UPDATE
+		SET banned_by = EXCLUDED.banned_by, reason = EXCLUDED.reason, expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at
+	`
+	if _, err := tx.Exec(query, ban.RoomID, ban.UserID, ban.BannedBy, ban.Reason, ban.ExpiresAt, ban.CreatedAt); err != nil {
+		return err
+	}
+	memberQuery := `DELETE FROM room_members WHERE room_id = $1 AND user_id = $2`
+	if _, err := tx.Exec(memberQuery, ban.RoomID, ban.UserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UnbanUser lifts a user's ban from a room.
+func (r *RoomRepository) UnbanUser(roomID, userID uuid.UUID) error {
+	query := `DELETE FROM room_bans WHERE room_id = $1 AND user_id = $2`
+	_, err := r.DB.Exec(query, roomID, userID)
+	return err
+}
+
+// GetActiveBan retrieves a user's unexpired ban from a room, or nil if there is none.
+func (r *RoomRepository) GetActiveBan(roomID, userID uuid.UUID) (*domain.RoomBan, error) {
+	ban := &domain.RoomBan{}
+	var expiresAt sql.NullTime
+	query := `
+		SELECT room_id, user_id, banned_by, reason, expires_at, created_at FROM room_bans
+		WHERE room_id = $1 AND user_id = $2 AND (expires_at IS NULL OR expires_at > NOW())
+	`
+	err := r.DB.QueryRow(query, roomID, userID).Scan(&ban.RoomID, &ban.UserID, &ban.BannedBy, &ban.Reason, &expiresAt, &ban.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if expiresAt.Valid {
+		ban.ExpiresAt = &expiresAt.Time
+	}
+	return ban, nil
+}
+
+// MuteUser records a mute, replacing any existing mute for the same user.
+func (r *RoomRepository) MuteUser(mute *domain.RoomMute) error {
+	query := `
+		INSERT INTO room_mutes (room_id, user_id, muted_by, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (room_id, user_id) DO UPDATE
+		SET muted_by = EXCLUDED.muted_by, expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at
+	`
+	_, err := r.DB.Exec(query, mute.RoomID, mute.UserID, mute.MutedBy, mute.ExpiresAt, mute.CreatedAt)
+	return err
+}
+
+// UnmuteUser lifts a user's mute in a room.
+func (r *RoomRepository) UnmuteUser(roomID, userID uuid.UUID) error {
+	query := `DELETE FROM room_mutes WHERE room_id = $1 AND user_id = $2`
+	_, err := r.DB.Exec(query, roomID, userID)
+	return err
+}
+
+// GetActiveMute retrieves a user's unexpired mute in a room, or nil if there is none.
+func (r *RoomRepository) GetActiveMute(roomID, userID uuid.UUID) (*domain.RoomMute, error) {
+	mute := &domain.RoomMute{}
+	var expiresAt sql.NullTime
+	query := `
+		SELECT room_id, user_id, muted_by, expires_at, created_at FROM room_mutes
+		WHERE room_id = $1 AND user_id = $2 AND (expires_at IS NULL OR expires_at > NOW())
+	`
+	err := r.DB.QueryRow(query, roomID, userID).Scan(&mute.RoomID, &mute.UserID, &mute.MutedBy, &expiresAt, &mute.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if expiresAt.Valid {
+		mute.ExpiresAt = &expiresAt.Time
+	}
+	return mute, nil
+}
@@ -19,24 +19,44 @@ func NewRoomRepository(db *sql.DB) *RoomRepository {
 
 // CreateRoom inserts a new room into the database.
 func (r *RoomRepository) CreateRoom(room *domain.Room) error {
-	query := `INSERT INTO rooms (id, name, owner_id, password_hash, created_at) VALUES ($1, $2, $3, $4, $5)`
-	_, err := r.DB.Exec(query, room.ID, room.Name, room.OwnerID, room.PasswordHash, room.CreatedAt)
+	query := `INSERT INTO rooms (id, name, owner_id, visibility, password_hash, topic, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := r.DB.Exec(query, room.ID, room.Name, room.OwnerID, room.Visibility, room.PasswordHash, room.Topic, room.CreatedAt, room.UpdatedAt)
 	return err
 }
 
-// ListRooms retrieves all rooms from the database.
-func (r *RoomRepository) ListRooms() ([]*domain.Room, error) {
-	query := `SELECT id, name, owner_id, password_hash, created_at FROM rooms`
-	rows, err := r.DB.Query(query)
+// UpdateRoom saves a room's name, visibility, password and topic.
+func (r *RoomRepository) UpdateRoom(room *domain.Room) error {
+	query := `UPDATE rooms SET name = $2, visibility = $3, password_hash = $4, topic = $5, updated_at = $6 WHERE id = $1`
+	_, err := r.DB.Exec(query, room.ID, room.Name, room.Visibility, room.PasswordHash, room.Topic, room.UpdatedAt)
+	return err
+}
+
+// DeleteRoom deletes a room. Memberships, bans and mutes are removed by cascade.
+func (r *RoomRepository) DeleteRoom(id uuid.UUID) error {
+	query := `DELETE FROM rooms WHERE id = $1`
+	_, err := r.DB.Exec(query, id)
+	return err
+}
+
+// ListRooms retrieves all listed (not invite-only) rooms along with their member counts.
+func (r *RoomRepository) ListRooms() ([]*domain.RoomSummary, error) {
+	query := `
+		SELECT r.id, r.name, r.owner_id, r.visibility, r.password_hash, r.topic, r.created_at, r.updated_at,
+			(SELECT COUNT(*) FROM room_members rm WHERE rm.room_id = r.id)
+		FROM rooms r
+		WHERE r.visibility <> $1
+		ORDER BY r.name
+	`
+	rows, err := r.DB.Query(query, domain.RoomInviteOnly)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var rooms []*domain.Room
+	var rooms []*domain.RoomSummary
 	for rows.Next() {
-		room := &domain.Room{}
-		if err := rows.Scan(&room.ID, &room.Name, &room.OwnerID, &room.PasswordHash, &room.CreatedAt); err != nil {
+		room := &domain.RoomSummary{}
+		if err := rows.Scan(&room.ID, &room.Name, &room.OwnerID, &room.Visibility, &room.PasswordHash, &room.Topic, &room.CreatedAt, &room.UpdatedAt, &room.MemberCount); err != nil {
 			return nil, err
 		}
 		rooms = append(rooms, room)
@@ -47,7 +67,7 @@ func (r *RoomRepository) ListRooms() ([]*domain.Room, error) {
 // GetUserRooms retrieves all rooms a user is a member of.
 func (r *RoomRepository) GetUserRooms(userID uuid.UUID) ([]*domain.Room, error) {
 	query := `
-		SELECT r.id, r.name, r.owner_id, r.password_hash, r.created_at 
+		SELECT r.id, r.name, r.owner_id, r.visibility, r.password_hash, r.topic, r.created_at, r.updated_at
 		FROM rooms r
 		JOIN room_members rm ON r.id = rm.room_id
 		WHERE rm.user_id = $1
@@ -61,7 +81,7 @@ func (r *RoomRepository) GetUserRooms(userID uuid.UUID) ([]*domain.Room, error)
 	var rooms []*domain.Room
 	for rows.Next() {
 		room := &domain.Room{}
-		if err := rows.Scan(&room.ID, &room.Name, &room.OwnerID, &room.PasswordHash, &room.CreatedAt); err != nil {
+		if err := rows.Scan(&room.ID, &room.Name, &room.OwnerID, &room.Visibility, &room.PasswordHash, &room.Topic, &room.CreatedAt, &room.UpdatedAt); err != nil {
 			return nil, err
 		}
 		rooms = append(rooms, room)
@@ -72,8 +92,8 @@ func (r *RoomRepository) GetUserRooms(userID uuid.UUID) ([]*domain.Room, error)
 // GetRoomByName retrieves a room by its unique name.
 func (r *RoomRepository) GetRoomByName(name string) (*domain.Room, error) {
 	room := &domain.Room{}
-	query := `SELECT id, name, owner_id, password_hash, created_at FROM rooms WHERE name = $1`
-	err := r.DB.QueryRow(query, name).Scan(&room.ID, &room.Name, &room.OwnerID, &room.PasswordHash, &room.CreatedAt)
+	query := `SELECT id, name, owner_id, visibility, password_hash, topic, created_at, updated_at FROM rooms WHERE name = $1`
+	err := r.DB.QueryRow(query, name).Scan(&room.ID, &room.Name, &room.OwnerID, &room.Visibility, &room.PasswordHash, &room.Topic, &room.CreatedAt, &room.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Not found
@@ -83,10 +103,25 @@ func (r *RoomRepository) GetRoomByName(name string) (*domain.Room, error) {
 	return room, nil
 }
 
-// AddUserToRoom adds a user to a room's membership list.
-func (r *RoomRepository) AddUserToRoom(roomID, userID uuid.UUID) error {
-	query := `INSERT INTO room_members (room_id, user_id) VALUES ($1, $2) ON CONFLICT (room_id, user_id) DO NOTHING`
-	_, err := r.DB.Exec(query, roomID, userID)
+// GetRoomByID retrieves a room by its ID.
+func (r *RoomRepository) GetRoomByID(id uuid.UUID) (*domain.Room, error) {
+	room := &domain.Room{}
+	query := `SELECT id, name, owner_id, visibility, password_hash, topic, created_at, updated_at FROM rooms WHERE id = $1`
+	err := r.DB.QueryRow(query, id).Scan(&room.ID, &room.Name, &room.OwnerID, &room.Visibility, &room.PasswordHash, &room.Topic, &room.CreatedAt, &room.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Not found
+		}
+		return nil, err
+	}
+	return room, nil
+}
+
+// AddUserToRoom adds a user to a room's membership list with the given role.
+// An existing member keeps their current role.
+func (r *RoomRepository) AddUserToRoom(roomID, userID uuid.UUID, role domain.RoomRole) error {
+	query := `INSERT INTO room_members (room_id, user_id, role) VALUES ($1, $2, $3) ON CONFLICT (room_id, user_id) DO NOTHING`
+	_, err := r.DB.Exec(query, roomID, userID, role)
 	return err
 }
 
@@ -105,27 +140,33 @@ func (r *RoomRepository) IsRoomMember(roomID, userID uuid.UUID) (bool, error) {
 	return exists, err
 }
 
-// GetRoomMembers retrieves a list of nicknames for users in a room.
-func (r *RoomRepository) GetRoomMembers(roomID uuid.UUID) ([]string, error) {
+// GetRoomMembers retrieves the members of a room along with their role and presence.
+func (r *RoomRepository) GetRoomMembers(roomID uuid.UUID) ([]*domain.RoomMember, error) {
 	query := `
-		SELECT u.nickname 
+		SELECT u.id, u.nickname, rm.role, COALESCE(p.status, $2), p.last_seen_at
 		FROM users u 
 		JOIN room_members rm ON u.id = rm.user_id 
+		LEFT JOIN user_presence p ON p.user_id = u.id
 		WHERE rm.room_id = $1
+		ORDER BY u.nickname
 	`
-	rows, err := r.DB.Query(query, roomID)
+	rows, err := r.DB.Query(query, roomID, domain.PresenceOffline)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var members []string
+	var members []*domain.RoomMember
 	for rows.Next() {
-		var nickname string
-		if err := rows.Scan(&nickname); err != nil {
+		member := &domain.RoomMember{}
+		var lastSeenAt sql.NullTime
+		if err := rows.Scan(&member.UserID, &member.Nickname, &member.Role, &member.Status, &lastSeenAt); err != nil {
 			return nil, err
 		}
-		members = append(members, nickname)
+		if lastSeenAt.Valid {
+			member.LastSeenAt = &lastSeenAt.Time
+		}
+		members = append(members, member)
 	}
 	return members, nil
 }
@@ -149,3 +190,28 @@ func (r *RoomRepository) GetRoomMemberIDs(roomID uuid.UUID) ([]uuid.UUID, error)
 	}
 	return memberIDs, nil
 }
+
+// GetRoommateIDs retrieves the IDs of every user who shares a room with the given user.
+func (r *RoomRepository) GetRoommateIDs(userID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		SELECT DISTINCT other.user_id
+		FROM room_members mine
+		JOIN room_members other ON other.room_id = mine.room_id
+		WHERE mine.user_id = $1 AND other.user_id <> $1
+	`
+	rows, err := r.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, nil
+}
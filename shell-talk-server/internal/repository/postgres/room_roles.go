@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"database/sql"
+	"shell-talk-server/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// GetMemberRole retrieves a user's role in a room, or the zero role if they are not a member.
+func (r *RoomRepository) GetMemberRole(roomID, userID uuid.UUID) (domain.RoomRole, error) {
+	var role domain.RoomRole
+	query := `SELECT role FROM room_members WHERE room_id = $1 AND user_id = $2`
+	err := r.DB.QueryRow(query, roomID, userID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil // Not a member
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+// SetMemberRole changes an existing member's role in a room.
+func (r *RoomRepository) SetMemberRole(roomID, userID uuid.UUID, role domain.RoomRole) error {
+	query := `UPDATE room_members SET role = $3 WHERE room_id = $1 AND user_id = $2`
+	_, err := r.DB.Exec(query, roomID, userID, role)
+	return err
+}
+
+// TransferOwnership makes newOwnerID the owner of a room and demotes the
+// previous owner to admin, in a single transaction.
+func (r *RoomRepository) TransferOwnership(roomID, oldOwnerID, newOwnerID uuid.UUID) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE rooms SET owner_id = $2 WHERE id = $1`, roomID, newOwnerID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE room_members SET role = $3 WHERE room_id = $1 AND user_id = $2`, roomID, oldOwnerID, domain.RoleAdmin); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE room_members SET role = $3 WHERE room_id = $1 AND user_id = $2`, roomID, newOwnerID, domain.RoleOwner); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"database/sql"
+	"errors"
 	"shell-talk-server/internal/domain"
 
 	"github.com/google/uuid"
@@ -51,3 +52,55 @@ func (r *UserRepository) GetUserByID(id uuid.UUID) (*domain.User, error) {
 	}
 	return user, nil
 }
+
+// UpdateUser saves a user's nickname and password hash.
+func (r *UserRepository) UpdateUser(user *domain.User) error {
+	query := `UPDATE users SET nickname = $2, password_hash = $3 WHERE id = $1`
+	_, err := r.DB.Exec(query, user.ID, user.Nickname, user.PasswordHash)
+	return err
+}
+
+// DeleteUser removes a user, handing each room in handovers (room ID to
+// successor ID) to its successor and deleting each room in deletedRooms, in
+// a single transaction. It fails without changing anything if a successor
+// has left their room, or someone has joined a room to be deleted, since the
+// rooms were looked at. Memberships, presence, and bans, mutes and direct
+// invites of the user go with them.
+func (r *UserRepository) DeleteUser(id uuid.UUID, handovers map[uuid.UUID]uuid.UUID, deletedRooms []uuid.UUID) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for roomID, successorID := range handovers {
+		result, err := tx.Exec(`UPDATE room_members SET role = $3 WHERE room_id = $1 AND user_id = $2`, roomID, successorID, domain.RoleOwner)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return errors.New("room membership changed, please try again")
+		}
+		if _, err := tx.Exec(`UPDATE rooms SET owner_id = $2 WHERE id = $1`, roomID, successorID); err != nil {
+			return err
+		}
+	}
+	for _, roomID := range deletedRooms {
+		query := `DELETE FROM rooms WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM room_members WHERE room_id = $1 AND user_id <> $2)`
+		result, err := tx.Exec(query, roomID, id)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return errors.New("room membership changed, please try again")
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM users WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
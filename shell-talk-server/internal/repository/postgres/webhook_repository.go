@@ -0,0 +1,263 @@
+package postgres
+
+import (
+	"database/sql"
+	"shell-talk-server/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// WebhookRepository handles database operations for outgoing webhooks and
+// their delivery queue, and for incoming webhooks.
+type WebhookRepository struct {
+	DB *sql.DB
+}
+
+// NewWebhookRepository creates a new WebhookRepository.
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{DB: db}
+}
+
+// CreateWebhook inserts a new webhook.
+func (r *WebhookRepository) CreateWebhook(webhook *domain.RoomWebhook) error {
+	query := `
+		INSERT INTO room_webhooks (id, room_id, url, secret, events, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.DB.Exec(query, webhook.ID, webhook.RoomID, webhook.URL, webhook.Secret, pq.Array(webhook.Events), webhook.CreatedBy, webhook.CreatedAt)
+	return err
+}
+
+// ListWebhooks retrieves a room's webhooks, oldest first.
+func (r *WebhookRepository) ListWebhooks(roomID uuid.UUID) ([]*domain.RoomWebhook, error) {
+	query := `
+		SELECT id, room_id, url, secret, events, created_by, created_at
+		FROM room_webhooks WHERE room_id = $1
+		ORDER BY created_at
+	`
+	rows, err := r.DB.Query(query, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*domain.RoomWebhook
+	for rows.Next() {
+		webhook := &domain.RoomWebhook{}
+		if err := rows.Scan(&webhook.ID, &webhook.RoomID, &webhook.URL, &webhook.Secret, pq.Array(&webhook.Events), &webhook.CreatedBy, &webhook.CreatedAt); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook removes one of a room's webhooks, reporting whether it existed.
+func (r *WebhookRepository) DeleteWebhook(roomID, id uuid.UUID) (bool, error) {
+	result, err := r.DB.Exec(`DELETE FROM room_webhooks WHERE id = $1 AND room_id = $2`, id, roomID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteRoomWebhooks removes all of a room's webhooks.
+func (r *WebhookRepository) DeleteRoomWebhooks(roomID uuid.UUID) error {
+	_, err := r.DB.Exec(`DELETE FROM room_webhooks WHERE room_id = $1`, roomID)
+	return err
+}
+
+// EnqueueDeliveries adds deliveries to the queue, due straight away.
+func (r *WebhookRepository) EnqueueDeliveries(deliveries []*domain.WebhookDelivery) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO webhook_deliveries (id, webhook_id, room_id, url, secret, event, payload, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	for _, d := range deliveries {
+		if _, err := tx.Exec(query, d.ID, d.WebhookID, d.RoomID, d.URL, d.Secret, d.Event, string(d.Payload), d.NextAttemptAt, d.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ClaimDeliveries takes up to limit due deliveries off the queue, hiding
+// them from other claims for lease. One that is neither completed nor
+// rescheduled in that time, e.g. because its instance died, is due again.
+func (r *WebhookRepository) ClaimDeliveries(limit int, lease time.Duration) ([]*domain.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries SET next_attempt_at = NOW() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries WHERE next_attempt_at <= NOW()
+			ORDER BY next_attempt_at LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, webhook_id, room_id, url, secret, event, payload, attempts, next_attempt_at, last_error, created_at
+	`
+	rows, err := r.DB.Query(query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*domain.WebhookDelivery
+	for rows.Next() {
+		d := &domain.WebhookDelivery{}
+		var payload string
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.RoomID, &d.URL, &d.Secret, &d.Event, &payload, &d.Attempts, &d.NextAttemptAt, &d.LastError, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// CompleteDelivery removes a delivery that succeeded from the queue.
+func (r *WebhookRepository) CompleteDelivery(id uuid.UUID) error {
+	_, err := r.DB.Exec(`DELETE FROM webhook_deliveries WHERE id = $1`, id)
+	return err
+}
+
+// RetryDelivery records a failed attempt and when to try again.
+func (r *WebhookRepository) RetryDelivery(delivery *domain.WebhookDelivery) error {
+	query := `UPDATE webhook_deliveries SET attempts = $2, next_attempt_at = $3, last_error = $4 WHERE id = $1`
+	_, err := r.DB.Exec(query, delivery.ID, delivery.Attempts, delivery.NextAttemptAt, delivery.LastError)
+	return err
+}
+
+// DeadLetterDelivery moves a delivery that will not be retried from the
+// queue to the dead letters.
+func (r *WebhookRepository) DeadLetterDelivery(delivery *domain.WebhookDelivery) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO webhook_dead_letters (id, webhook_id, room_id, url, event, payload, attempts, last_error, created_at, failed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO NOTHING
+	`
+	if _, err := tx.Exec(query, delivery.ID, delivery.WebhookID, delivery.RoomID, delivery.URL, delivery.Event, string(delivery.Payload), delivery.Attempts, delivery.LastError, delivery.CreatedAt, delivery.FailedAt); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE id = $1`, delivery.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListDeadLetters retrieves a room's most recent dead letters, newest first.
+func (r *WebhookRepository) ListDeadLetters(roomID uuid.UUID, limit int) ([]*domain.WebhookDelivery, error) {
+	query := `
+		SELECT id, webhook_id, room_id, url, event, payload, attempts, last_error, created_at, failed_at
+		FROM webhook_dead_letters WHERE room_id = $1
+		ORDER BY failed_at DESC LIMIT $2
+	`
+	rows, err := r.DB.Query(query, roomID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*domain.WebhookDelivery
+	for rows.Next() {
+		d := &domain.WebhookDelivery{}
+		var payload string
+		var failedAt time.Time
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.RoomID, &d.URL, &d.Event, &payload, &d.Attempts, &d.LastError, &d.CreatedAt, &failedAt); err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		d.FailedAt = &failedAt
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// PurgeDeadLetters deletes dead letters recorded before the given time.
+func (r *WebhookRepository) PurgeDeadLetters(before time.Time) error {
+	_, err := r.DB.Exec(`DELETE FROM webhook_dead_letters WHERE failed_at < $1`, before)
+	return err
+}
+
+// CreateIncomingWebhook inserts a new incoming webhook.
+func (r *WebhookRepository) CreateIncomingWebhook(webhook *domain.IncomingWebhook) error {
+	query := `
+		INSERT INTO incoming_webhooks (id, room_id, name, token_hash, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.DB.Exec(query, webhook.ID, webhook.RoomID, webhook.Name, webhook.TokenHash, webhook.CreatedBy, webhook.CreatedAt)
+	return err
+}
+
+// GetIncomingWebhookByTokenHash retrieves the incoming webhook a token
+// belongs to, or nil if there is none.
+func (r *WebhookRepository) GetIncomingWebhookByTokenHash(tokenHash string) (*domain.IncomingWebhook, error) {
+	query := `
+		SELECT id, room_id, name, token_hash, created_by, created_at
+		FROM incoming_webhooks WHERE token_hash = $1
+	`
+	webhook, err := scanIncomingWebhook(r.DB.QueryRow(query, tokenHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// ListIncomingWebhooks retrieves a room's incoming webhooks, oldest first.
+func (r *WebhookRepository) ListIncomingWebhooks(roomID uuid.UUID) ([]*domain.IncomingWebhook, error) {
+	query := `
+		SELECT id, room_id, name, token_hash, created_by, created_at
+		FROM incoming_webhooks WHERE room_id = $1
+		ORDER BY created_at
+	`
+	rows, err := r.DB.Query(query, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*domain.IncomingWebhook
+	for rows.Next() {
+		webhook, err := scanIncomingWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// DeleteIncomingWebhook removes one of a room's incoming webhooks, reporting
+// whether it existed.
+func (r *WebhookRepository) DeleteIncomingWebhook(roomID, id uuid.UUID) (bool, error) {
+	result, err := r.DB.Exec(`DELETE FROM incoming_webhooks WHERE id = $1 AND room_id = $2`, id, roomID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func scanIncomingWebhook(row interface{ Scan(...any) error }) (*domain.IncomingWebhook, error) {
+	webhook := &domain.IncomingWebhook{}
+	if err := row.Scan(&webhook.ID, &webhook.RoomID, &webhook.Name, &webhook.TokenHash, &webhook.CreatedBy, &webhook.CreatedAt); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"shell-talk-server/internal/config"
+	"shell-talk-server/internal/domain"
+	"slices"
+	"sync"
+	"time"
+)
+
+// attemptSweepInterval is how often records of old failures are dropped.
+const attemptSweepInterval = time.Minute
+
+// attemptRecord tracks recent failures for one key.
+type attemptRecord struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// AttemptGuard throttles password guessing. Failures are counted per key,
+// e.g. per nickname and per remote address, so spreading guesses across
+// accounts or across addresses both run into a limit. State is kept in
+// memory.
+type AttemptGuard struct {
+	rules     config.BruteForceConfig
+	mu        sync.Mutex
+	records   map[string]*attemptRecord
+	lastSweep time.Time
+}
+
+// NewAttemptGuard creates an AttemptGuard with the configured limits.
+func NewAttemptGuard(cfg *config.Config) *AttemptGuard {
+	return &AttemptGuard{rules: cfg.BruteForce, records: make(map[string]*attemptRecord), lastSweep: time.Now()}
+}
+
+// Check returns a *domain.ThrottleError if any of the keys may not make an
+// attempt yet. Otherwise it reserves the attempt against every key, counting
+// it as a failure until Succeed or Release says otherwise, so concurrent
+// attempts cannot all pass before any failure is recorded. It should be
+// called before the password is checked.
+func (g *AttemptGuard) Check(keys ...string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	var worst *domain.ThrottleError
+	for _, key := range keys {
+		record := g.current(key, now)
+		if record == nil {
+			continue
+		}
+		var err *domain.ThrottleError
+		if now.Before(record.lockedUntil) {
+			err = &domain.ThrottleError{Code: domain.ErrCodeLockedOut, RetryAfter: record.lockedUntil.Sub(now)}
+		} else if next := record.lastFailure.Add(g.delay(record.failures)); now.Before(next) {
+			err = &domain.ThrottleError{Code: domain.ErrCodeThrottled, RetryAfter: next.Sub(now)}
+		}
+		if err != nil && (worst == nil || err.RetryAfter > worst.RetryAfter) {
+			worst = err
+		}
+	}
+	if worst != nil {
+		return worst
+	}
+
+	g.sweep(now)
+	for _, key := range keys {
+		record := g.current(key, now)
+		if record == nil {
+			record = &attemptRecord{}
+			g.records[key] = record
+		}
+		record.failures++
+		record.lastFailure = now
+		// Failures are kept past a lockout, so one more after it expires locks again.
+		if record.failures >= g.rules.LockoutThreshold {
+			record.lockedUntil = now.Add(g.rules.LockoutDuration)
+		}
+	}
+	return nil
+}
+
+// Succeed ends an attempt reserved by Check that succeeded. The failures of
+// the keys in forget are forgotten; the other keys only have the attempt
+// released.
+func (g *AttemptGuard) Succeed(keys []string, forget ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, key := range forget {
+		delete(g.records, key)
+	}
+	for _, key := range keys {
+		if !slices.Contains(forget, key) {
+			g.release(key)
+		}
+	}
+}
+
+// Release withdraws an attempt reserved by Check that ended without the
+// password being found wrong, e.g. because of a database error.
+func (g *AttemptGuard) Release(keys ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, key := range keys {
+		g.release(key)
+	}
+}
+
+func (g *AttemptGuard) release(key string) {
+	record, ok := g.records[key]
+	if !ok {
+		return
+	}
+	record.failures--
+	if record.failures <= 0 {
+		delete(g.records, key)
+		return
+	}
+	if record.failures < g.rules.LockoutThreshold {
+		record.lockedUntil = time.Time{}
+	}
+}
+
+// current returns the record for key, or nil if it has none still in effect.
+func (g *AttemptGuard) current(key string, now time.Time) *attemptRecord {
+	record, ok := g.records[key]
+	if !ok {
+		return nil
+	}
+	// A lockout counts as activity, so the failures outlive it by a window.
+	lastActive := record.lastFailure
+	if record.lockedUntil.After(lastActive) {
+		lastActive = record.lockedUntil
+	}
+	if now.Sub(lastActive) < g.rules.Window {
+		return record
+	}
+	delete(g.records, key)
+	return nil
+}
+
+// delay is how long to wait after the last of the given number of failures.
+func (g *AttemptGuard) delay(failures int) time.Duration {
+	if failures < g.rules.FreeAttempts {
+		return 0
+	}
+	delay := g.rules.BaseDelay
+	for i := g.rules.FreeAttempts; i < failures && delay < g.rules.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, g.rules.MaxDelay)
+}
+
+// sweep drops expired records so the map does not grow without bound.
+func (g *AttemptGuard) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) < attemptSweepInterval {
+		return
+	}
+	g.lastSweep = now
+	for key := range g.records {
+		g.current(key, now)
+	}
+}
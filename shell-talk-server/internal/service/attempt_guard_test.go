@@ -0,0 +1,174 @@
+package service
+
+import (
+	"errors"
+	"shell-talk-server/internal/config"
+	"shell-talk-server/internal/domain"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testBruteForce = config.BruteForceConfig{
+	FreeAttempts:     2,
+	BaseDelay:        time.Second,
+	MaxDelay:         4 * time.Second,
+	LockoutThreshold: 5,
+	LockoutDuration:  time.Minute,
+	Window:           10 * time.Minute,
+}
+
+// elapse moves the guard's records back in time by d, as if d had passed.
+func elapse(g *AttemptGuard, d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, record := range g.records {
+		record.lastFailure = record.lastFailure.Add(-d)
+		if !record.lockedUntil.IsZero() {
+			record.lockedUntil = record.lockedUntil.Add(-d)
+		}
+	}
+	g.lastSweep = g.lastSweep.Add(-d)
+}
+
+// guardStep is one thing done to the guard: a Check expecting the given
+// error code ("" for none), a Succeed, a Release, or time passing.
+type guardStep struct {
+	check   bool
+	want    string
+	succeed bool
+	release bool
+	wait    time.Duration
+}
+
+func allowed() guardStep             { return guardStep{check: true} }
+func refused(code string) guardStep  { return guardStep{check: true, want: code} }
+func wait(d time.Duration) guardStep { return guardStep{wait: d} }
+func succeed() guardStep             { return guardStep{succeed: true} }
+func release() guardStep             { return guardStep{release: true} }
+
+func TestAttemptGuard(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []guardStep
+	}{
+		{"free attempts pass", []guardStep{
+			allowed(), allowed(),
+			refused(domain.ErrCodeThrottled),
+		}},
+		{"delay doubles after the free attempts", []guardStep{
+			allowed(), allowed(),
+			wait(time.Second), allowed(),
+			wait(time.Second), refused(domain.ErrCodeThrottled),
+			wait(time.Second), allowed(),
+			wait(3 * time.Second), refused(domain.ErrCodeThrottled),
+			wait(time.Second), allowed(),
+		}},
+		{"locks out at the threshold", []guardStep{
+			allowed(), allowed(),
+			wait(time.Second), allowed(),
+			wait(2 * time.Second), allowed(),
+			wait(4 * time.Second), allowed(),
+			wait(10 * time.Second), refused(domain.ErrCodeLockedOut),
+		}},
+		{"lock expires, and one more failure locks again", []guardStep{
+			allowed(), allowed(),
+			wait(time.Second), allowed(),
+			wait(2 * time.Second), allowed(),
+			wait(4 * time.Second), allowed(),
+			wait(time.Minute), allowed(),
+			refused(domain.ErrCodeLockedOut),
+		}},
+		{"success resets the count", []guardStep{
+			allowed(), allowed(),
+			wait(time.Second), allowed(),
+			succeed(),
+			allowed(), allowed(),
+			refused(domain.ErrCodeThrottled),
+		}},
+		{"release withdraws only the attempt", []guardStep{
+			allowed(), allowed(),
+			release(),
+			allowed(),
+			refused(domain.ErrCodeThrottled),
+		}},
+		{"failures are forgotten after the window", []guardStep{
+			allowed(), allowed(),
+			wait(10 * time.Minute), allowed(), allowed(),
+			refused(domain.ErrCodeThrottled),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewAttemptGuard(&config.Config{BruteForce: testBruteForce})
+			for i, step := range tt.steps {
+				switch {
+				case step.wait > 0:
+					elapse(g, step.wait)
+				case step.succeed:
+					g.Succeed([]string{"key"}, "key")
+				case step.release:
+					g.Release("key")
+				case step.check:
+					err := g.Check("key")
+					var got string
+					if err != nil {
+						var throttle *domain.ThrottleError
+						if !errors.As(err, &throttle) {
+							t.Fatalf("step %d: got %v, want a *domain.ThrottleError", i, err)
+						}
+						got = throttle.Code
+					}
+					if got != step.want {
+						t.Fatalf("step %d: got code %q, want %q", i, got, step.want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestAttemptGuardChecksAnyKey checks that an attempt is refused if any of
+// its keys is throttled, and that a refused attempt is not counted.
+func TestAttemptGuardChecksAnyKey(t *testing.T) {
+	g := NewAttemptGuard(&config.Config{BruteForce: testBruteForce})
+	for range testBruteForce.FreeAttempts {
+		if err := g.Check("nick:alice", "addr:a"); err != nil {
+			t.Fatalf("free attempt: %v", err)
+		}
+	}
+	if err := g.Check("nick:alice", "addr:b"); err == nil {
+		t.Fatal("attempt on a throttled nickname from a new address was allowed")
+	}
+	if err := g.Check("nick:bob", "addr:b"); err != nil {
+		t.Fatalf("address b was charged for a refused attempt: %v", err)
+	}
+}
+
+// TestAttemptGuardConcurrentChecks makes many attempts at once and checks
+// that no more than the free attempts get through.
+func TestAttemptGuardConcurrentChecks(t *testing.T) {
+	g := NewAttemptGuard(&config.Config{BruteForce: testBruteForce})
+	const attempts = 50
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	passed := 0
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if g.Check("key") == nil {
+				mu.Lock()
+				passed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if passed != testBruteForce.FreeAttempts {
+		t.Fatalf("%d of %d concurrent attempts passed, want %d", passed, attempts, testBruteForce.FreeAttempts)
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"shell-talk-server/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// CreateIncomingWebhook creates an incoming webhook posting to a room under
+// the given name. The returned token is not shown again.
+func (s *WebhookService) CreateIncomingWebhook(roomName string, actor *domain.User, name string) (*domain.IncomingWebhook, string, *domain.Room, error) {
+	room, err := s.roomService.Authorize(roomName, actor, domain.PermManageRoom)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	existing, err := s.webhookRepo.ListIncomingWebhooks(room.ID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if len(existing) >= maxWebhooksPerRoom {
+		return nil, "", nil, fmt.Errorf("a room can have at most %d incoming webhooks", maxWebhooksPerRoom)
+	}
+
+	webhook, token, err := domain.NewIncomingWebhook(room.ID, actor.ID, name)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if err := s.webhookRepo.CreateIncomingWebhook(webhook); err != nil {
+		return nil, "", nil, err
+	}
+	return webhook, token, room, nil
+}
+
+// ListIncomingWebhooks returns a room's incoming webhooks.
+func (s *WebhookService) ListIncomingWebhooks(roomName string, actor *domain.User) (*domain.Room, []*domain.IncomingWebhook, error) {
+	room, err := s.roomService.Authorize(roomName, actor, domain.PermManageRoom)
+	if err != nil {
+		return nil, nil, err
+	}
+	webhooks, err := s.webhookRepo.ListIncomingWebhooks(room.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return room, webhooks, nil
+}
+
+// DeleteIncomingWebhook revokes one of a room's incoming webhooks. Messages
+// it already posted are kept.
+func (s *WebhookService) DeleteIncomingWebhook(roomName string, actor *domain.User, id string) (*domain.Room, error) {
+	room, err := s.roomService.Authorize(roomName, actor, domain.PermManageRoom)
+	if err != nil {
+		return nil, err
+	}
+	webhookID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid webhook ID")
+	}
+	deleted, err := s.webhookRepo.DeleteIncomingWebhook(room.ID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, domain.ErrWebhookNotFound
+	}
+	return room, nil
+}
+
+// AuthenticateIncomingWebhook returns the incoming webhook a token belongs
+// to, or domain.ErrWebhookNotFound.
+func (s *WebhookService) AuthenticateIncomingWebhook(token string) (*domain.IncomingWebhook, error) {
+	if token == "" {
+		return nil, domain.ErrWebhookNotFound
+	}
+	webhook, err := s.webhookRepo.GetIncomingWebhookByTokenHash(domain.HashWebhookToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, domain.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
@@ -3,8 +3,10 @@ package service
 import (
 	"context"
 	"shell-talk-server/internal/domain"
+	"time"
 
 	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // --- Service Interfaces ---
@@ -12,21 +14,83 @@ import (
 // IUserService defines the interface for user-related business logic.
 type IUserService interface {
 	Register(nickname, password string) (*domain.User, error)
-	Login(nickname, password string) (*domain.User, error)
+	Login(nickname, password, remoteAddr string) (*domain.User, error)
 	GetUserByNickname(nickname string) (*domain.User, error)
+	GetUserByID(id uuid.UUID) (*domain.User, error)
+	ChangePassword(userID uuid.UUID, oldPassword, newPassword string) (*domain.User, error)
+	ChangeNickname(userID uuid.UUID, newNickname string) (*domain.User, error)
+	DeleteAccount(userID uuid.UUID, password string) (*AccountDeletion, error)
 }
 
 // IRoomService defines the interface for room-related business logic.
 type IRoomService interface {
-	CreateRoom(name, password string, owner *domain.User) (*domain.Room, error)
-	JoinRoom(name, password string, user *domain.User) (*domain.Room, error)
+	CreateRoom(name, password, visibility string, owner *domain.User) (*domain.Room, error)
+	JoinRoom(name, password string, user *domain.User, remoteAddr string) (*domain.Room, error)
 	LeaveRoom(name string, user *domain.User) (*domain.Room, error)
-	ListRooms() ([]*domain.Room, error)
+	ListRooms() ([]*domain.RoomSummary, error)
 	GetRoomByName(name string) (*domain.Room, error)
-	GetRoomMembers(name string) ([]string, error)
+	GetRoomByID(id uuid.UUID) (*domain.Room, error)
+	DeleteRoom(name string, actor *domain.User) (*domain.Room, []uuid.UUID, error)
+	RenameRoom(name string, actor *domain.User, newName string) (*domain.Room, error)
+	ChangeRoomPassword(name string, actor *domain.User, newPassword string) (*domain.Room, error)
+	SetVisibility(name string, actor *domain.User, visibility, password string) (*domain.Room, error)
+	SetTopic(name string, actor *domain.User, topic string) (*domain.Room, error)
+	GetRoomMembers(name string) ([]*domain.RoomMember, error)
 	IsRoomMember(name string, user *domain.User) (bool, error)
 	GetRoomMemberIDs(name string) ([]uuid.UUID, error)
 	GetUserRooms(userID uuid.UUID) ([]*domain.Room, error)
+	KickMember(name string, actor *domain.User, targetNickname string) (*domain.Room, *domain.User, error)
+	BanMember(name string, actor *domain.User, targetNickname string, duration time.Duration, reason string) (*domain.Room, *domain.User, error)
+	UnbanMember(name string, actor *domain.User, targetNickname string) (*domain.Room, *domain.User, error)
+	MuteMember(name string, actor *domain.User, targetNickname string, duration time.Duration) (*domain.Room, *domain.User, error)
+	UnmuteMember(name string, actor *domain.User, targetNickname string) (*domain.Room, *domain.User, error)
+	SetMemberRole(name string, actor *domain.User, targetNickname string, role domain.RoomRole) (*domain.Room, *domain.User, error)
+	TransferOwnership(name string, actor *domain.User, targetNickname string) (*domain.Room, *domain.User, error)
+	CreateInvite(name string, actor *domain.User, inviteeNickname string, maxUses int, duration time.Duration) (*domain.RoomInvite, *domain.Room, *domain.User, error)
+	AcceptInvite(code string, user *domain.User) (*domain.Room, error)
+	ListInvites(name string, actor *domain.User) (*domain.Room, []*domain.RoomInvite, error)
+	RevokeInvite(name string, actor *domain.User, code string) (*domain.Room, error)
+	Authorize(name string, user *domain.User, perm domain.RoomPermission) (*domain.Room, error)
+	AuthorizeByID(id uuid.UUID, user *domain.User, perm domain.RoomPermission) (*domain.Room, error)
+}
+
+// ISessionService defines the interface for issuing and validating session tokens.
+type ISessionService interface {
+	IssueToken(user *domain.User) (string, time.Time, error)
+	ValidateToken(token string) (*domain.User, error)
+}
+
+// IPresenceService defines the interface for presence-related business logic.
+type IPresenceService interface {
+	SetPresence(userID uuid.UUID, status string) (*domain.UserPresence, error)
+	GetContactIDs(userID uuid.UUID) ([]uuid.UUID, error)
+	OnlineElsewhere(userIDs []uuid.UUID) ([]uuid.UUID, error)
+	ResetPresence() error
+}
+
+// IWebhookService defines the interface for managing webhooks: outgoing ones
+// and the room events queued for them, and incoming ones that post to rooms.
+type IWebhookService interface {
+	CreateWebhook(roomName string, actor *domain.User, url string, events []string) (*domain.RoomWebhook, *domain.Room, error)
+	ListWebhooks(roomName string, actor *domain.User) (*domain.Room, []*domain.RoomWebhook, error)
+	DeleteWebhook(roomName string, actor *domain.User, id string) (*domain.Room, error)
+	ListFailures(roomName string, actor *domain.User, limit int) (*domain.Room, []*domain.WebhookDelivery, error)
+	Emit(room *domain.Room, event string, data interface{}) error
+	RoomDeleted(room *domain.Room, deletedBy string) error
+	CreateIncomingWebhook(roomName string, actor *domain.User, name string) (*domain.IncomingWebhook, string, *domain.Room, error)
+	ListIncomingWebhooks(roomName string, actor *domain.User) (*domain.Room, []*domain.IncomingWebhook, error)
+	DeleteIncomingWebhook(roomName string, actor *domain.User, id string) (*domain.Room, error)
+	AuthenticateIncomingWebhook(token string) (*domain.IncomingWebhook, error)
+}
+
+// IBroker carries hub events between server instances, so users connected
+// to different instances can reach each other. Events from one publisher
+// are handled in the order they were published.
+type IBroker interface {
+	Publish(event *domain.BrokerEvent) error
+	// Subscribe registers a handler for every event, including those the
+	// subscriber published itself. Handlers must not block.
+	Subscribe(handler func(*domain.BrokerEvent))
 }
 
 // --- Repository Interfaces ---
@@ -36,23 +100,85 @@ type IUserRepository interface {
 	CreateUser(user *domain.User) error
 	GetUserByNickname(nickname string) (*domain.User, error)
 	GetUserByID(id uuid.UUID) (*domain.User, error)
+	UpdateUser(user *domain.User) error
+	DeleteUser(id uuid.UUID, handovers map[uuid.UUID]uuid.UUID, deletedRooms []uuid.UUID) error
 }
 
 // IRoomRepository defines the interface for room persistence.
 type IRoomRepository interface {
 	CreateRoom(room *domain.Room) error
+	UpdateRoom(room *domain.Room) error
+	DeleteRoom(id uuid.UUID) error
 	GetRoomByName(name string) (*domain.Room, error)
-	AddUserToRoom(roomID, userID uuid.UUID) error
-	GetRoomMembers(roomID uuid.UUID) ([]string, error)
-	ListRooms() ([]*domain.Room, error)
+	GetRoomByID(id uuid.UUID) (*domain.Room, error)
+	AddUserToRoom(roomID, userID uuid.UUID, role domain.RoomRole) error
+	GetRoomMembers(roomID uuid.UUID) ([]*domain.RoomMember, error)
+	ListRooms() ([]*domain.RoomSummary, error)
 	RemoveUserFromRoom(roomID, userID uuid.UUID) error
 	IsRoomMember(roomID, userID uuid.UUID) (bool, error)
 	GetRoomMemberIDs(roomID uuid.UUID) ([]uuid.UUID, error)
 	GetUserRooms(userID uuid.UUID) ([]*domain.Room, error)
+	GetRoommateIDs(userID uuid.UUID) ([]uuid.UUID, error)
+	BanUser(ban *domain.RoomBan) error
+	UnbanUser(roomID, userID uuid.UUID) error
+	GetActiveBan(roomID, userID uuid.UUID) (*domain.RoomBan, error)
+	MuteUser(mute *domain.RoomMute) error
+	UnmuteUser(roomID, userID uuid.UUID) error
+	GetActiveMute(roomID, userID uuid.UUID) (*domain.RoomMute, error)
+	GetMemberRole(roomID, userID uuid.UUID) (domain.RoomRole, error)
+	SetMemberRole(roomID, userID uuid.UUID, role domain.RoomRole) error
+	TransferOwnership(roomID, oldOwnerID, newOwnerID uuid.UUID) error
+	CreateInvite(invite *domain.RoomInvite) error
+	GetInvite(code string) (*domain.RoomInvite, error)
+	ListInvites(roomID uuid.UUID) ([]*domain.RoomInvite, error)
+	UseInvite(code string, userID uuid.UUID) (bool, error)
+	DeleteInvite(code string) error
+}
+
+// IPresenceRepository defines the interface for presence persistence.
+type IPresenceRepository interface {
+	SetPresence(userID uuid.UUID, status string, lastSeenAt time.Time, nodeID string) error
+	SetOffline(userID uuid.UUID, lastSeenAt time.Time, nodeID string) (bool, error)
+	GetOnlineElsewhere(userIDs []uuid.UUID, nodeID string) ([]uuid.UUID, error)
+	MarkAllOffline(nodeID string) error
+}
+
+// IWebhookRepository defines the interface for webhook and delivery queue
+// persistence.
+type IWebhookRepository interface {
+	CreateWebhook(webhook *domain.RoomWebhook) error
+	ListWebhooks(roomID uuid.UUID) ([]*domain.RoomWebhook, error)
+	DeleteWebhook(roomID, id uuid.UUID) (bool, error)
+	DeleteRoomWebhooks(roomID uuid.UUID) error
+	EnqueueDeliveries(deliveries []*domain.WebhookDelivery) error
+	ClaimDeliveries(limit int, lease time.Duration) ([]*domain.WebhookDelivery, error)
+	CompleteDelivery(id uuid.UUID) error
+	RetryDelivery(delivery *domain.WebhookDelivery) error
+	DeadLetterDelivery(delivery *domain.WebhookDelivery) error
+	ListDeadLetters(roomID uuid.UUID, limit int) ([]*domain.WebhookDelivery, error)
+	PurgeDeadLetters(before time.Time) error
+	CreateIncomingWebhook(webhook *domain.IncomingWebhook) error
+	GetIncomingWebhookByTokenHash(tokenHash string) (*domain.IncomingWebhook, error)
+	ListIncomingWebhooks(roomID uuid.UUID) ([]*domain.IncomingWebhook, error)
+	DeleteIncomingWebhook(roomID, id uuid.UUID) (bool, error)
 }
 
 // IMessageRepository defines the interface for message persistence.
 type IMessageRepository interface {
 	SaveMessage(ctx context.Context, message *domain.ChatMessage) error
-	GetMessagesByConversationID(ctx context.Context, conversationID string, limit int64) ([]*domain.ChatMessage, error)
+	GetMessageByRequestID(ctx context.Context, senderID, requestID string) (*domain.ChatMessage, error)
+	GetMessageByID(ctx context.Context, id primitive.ObjectID) (*domain.ChatMessage, error)
+	UpdateMessageContent(ctx context.Context, message *domain.ChatMessage, content string, editedAt time.Time) error
+	DeleteMessage(ctx context.Context, id primitive.ObjectID, deletedAt time.Time) error
+	DeleteConversation(ctx context.Context, conversationID string) error
+	GetMessagesByConversationID(ctx context.Context, conversationID string, before *domain.MessageCursor, limit int64) ([]*domain.ChatMessage, error)
+	GetMessagesAfter(ctx context.Context, conversationID string, after *domain.MessageCursor, limit int64) ([]*domain.ChatMessage, error)
+	CountUnread(ctx context.Context, since map[string]time.Time, excludeSenderID string) (map[string]int64, error)
+	GetDMConversationIDs(ctx context.Context, userID string) ([]string, error)
+}
+
+// IReadCursorRepository defines the interface for read cursor persistence.
+type IReadCursorRepository interface {
+	MarkRead(ctx context.Context, userID, conversationID string, readAt time.Time) error
+	GetReadCursors(ctx context.Context, userID string) (map[string]time.Time, error)
 }
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"shell-talk-server/internal/domain"
+	"sync"
+)
+
+// LocalBroker passes events between hubs in the same process. It is all a
+// single instance needs, and lets several hubs be run side by side in tests.
+type LocalBroker struct {
+	mu       sync.RWMutex
+	handlers []func(*domain.BrokerEvent)
+}
+
+// NewLocalBroker creates a LocalBroker with no subscribers.
+func NewLocalBroker() *LocalBroker {
+	return &LocalBroker{}
+}
+
+// Publish hands the event to every subscriber before returning.
+func (b *LocalBroker) Publish(event *domain.BrokerEvent) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		handler(event)
+	}
+	return nil
+}
+
+// Subscribe registers a handler for every event published from now on.
+func (b *LocalBroker) Subscribe(handler func(*domain.BrokerEvent)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
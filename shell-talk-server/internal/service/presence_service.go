@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"shell-talk-server/internal/config"
+	"shell-talk-server/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PresenceService provides presence-related services.
+type PresenceService struct {
+	nodeID       string // This server instance
+	presenceRepo IPresenceRepository
+	roomRepo     IRoomRepository
+	messageRepo  IMessageRepository
+}
+
+// NewPresenceService creates a new PresenceService.
+func NewPresenceService(cfg *config.Config, presenceRepo IPresenceRepository, roomRepo IRoomRepository, messageRepo IMessageRepository) *PresenceService {
+	return &PresenceService{nodeID: cfg.NodeID, presenceRepo: presenceRepo, roomRepo: roomRepo, messageRepo: messageRepo}
+}
+
+// SetPresence records a user's new status on this instance. The last-seen
+// time is updated to now. Going offline is ignored, returning a nil
+// presence, if the user has meanwhile connected to another instance.
+func (s *PresenceService) SetPresence(userID uuid.UUID, status string) (*domain.UserPresence, error) {
+	switch status {
+	case domain.PresenceOnline, domain.PresenceAway, domain.PresenceOffline:
+	default:
+		return nil, errors.New("invalid presence status")
+	}
+
+	presence := &domain.UserPresence{UserID: userID, Status: status, LastSeenAt: time.Now()}
+	if status == domain.PresenceOffline {
+		updated, err := s.presenceRepo.SetOffline(presence.UserID, presence.LastSeenAt, s.nodeID)
+		if err != nil || !updated {
+			return nil, err
+		}
+		return presence, nil
+	}
+	if err := s.presenceRepo.SetPresence(presence.UserID, presence.Status, presence.LastSeenAt, s.nodeID); err != nil {
+		return nil, err
+	}
+	return presence, nil
+}
+
+// GetContactIDs returns everyone who should see the user's presence: users
+// sharing a room with them and users they have a DM conversation with.
+func (s *PresenceService) GetContactIDs(userID uuid.UUID) ([]uuid.UUID, error) {
+	roommateIDs, err := s.roomRepo.GetRoommateIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	dmIDs, err := s.messageRepo.GetDMConversationIDs(context.Background(), userID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uuid.UUID]bool, len(roommateIDs)+len(dmIDs))
+	contacts := make([]uuid.UUID, 0, len(roommateIDs)+len(dmIDs))
+	for _, id := range roommateIDs {
+		if !seen[id] {
+			seen[id] = true
+			contacts = append(contacts, id)
+		}
+	}
+	for _, convoID := range dmIDs {
+		peerID, ok := domain.DMPeerID(convoID, userID)
+		if ok && peerID != userID && !seen[peerID] {
+			seen[peerID] = true
+			contacts = append(contacts, peerID)
+		}
+	}
+	return contacts, nil
+}
+
+// OnlineElsewhere returns those of the given users who are connected to
+// another instance.
+func (s *PresenceService) OnlineElsewhere(userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	return s.presenceRepo.GetOnlineElsewhere(userIDs, s.nodeID)
+}
+
+// ResetPresence marks every user connected to this instance offline.
+func (s *PresenceService) ResetPresence() error {
+	return s.presenceRepo.MarkAllOffline(s.nodeID)
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"shell-talk-server/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateInvite creates an invite to a room. If inviteeNickname is set, the
+// invite is single-use and only that user can accept it; otherwise anyone
+// with the code can join, up to maxUses times (unlimited if zero).
+func (s *RoomService) CreateInvite(name string, actor *domain.User, inviteeNickname string, maxUses int, duration time.Duration) (*domain.RoomInvite, *domain.Room, *domain.User, error) {
+	room, err := s.Authorize(name, actor, domain.PermManageMembers)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if maxUses < 0 {
+		return nil, nil, nil, errors.New("max uses cannot be negative")
+	}
+
+	var invitee *domain.User
+	if inviteeNickname != "" {
+		invitee, err = s.userRepo.GetUserByNickname(inviteeNickname)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if invitee == nil {
+			return nil, nil, nil, fmt.Errorf("user '%s' not found", inviteeNickname)
+		}
+		isMember, err := s.roomRepo.IsRoomMember(room.ID, invitee.ID)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if isMember {
+			return nil, nil, nil, fmt.Errorf("%s is already a member of this room", invitee.Nickname)
+		}
+		maxUses = 1
+	}
+
+	var inviteeID *uuid.UUID
+	if invitee != nil {
+		inviteeID = &invitee.ID
+	}
+	invite, err := domain.NewRoomInvite(room.ID, actor.ID, inviteeID, maxUses, duration)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := s.roomRepo.CreateInvite(invite); err != nil {
+		return nil, nil, nil, err
+	}
+	return invite, room, invitee, nil
+}
+
+// AcceptInvite adds a user to the room an invite is for, without a password.
+func (s *RoomService) AcceptInvite(code string, user *domain.User) (*domain.Room, error) {
+	invite, err := s.roomRepo.GetInvite(code)
+	if err != nil {
+		return nil, err
+	}
+	if invite == nil || (invite.InviteeID != nil && *invite.InviteeID != user.ID) {
+		return nil, errors.New("invalid invite code")
+	}
+	if !invite.Usable(time.Now()) {
+		return nil, errors.New("invite has expired or already been used")
+	}
+
+	room, err := s.roomRepo.GetRoomByID(invite.RoomID)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, domain.ErrRoomNotFound
+	}
+	isMember, err := s.roomRepo.IsRoomMember(room.ID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if isMember {
+		return nil, errors.New("you are already a member of this room")
+	}
+	if err := s.checkBan(room, user); err != nil {
+		return nil, err
+	}
+
+	used, err := s.roomRepo.UseInvite(code, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, errors.New("invite has expired or already been used")
+	}
+	return room, nil
+}
+
+// ListInvites returns a room's invites that can still be used. Anyone who
+// may create invites may see and revoke them.
+func (s *RoomService) ListInvites(name string, actor *domain.User) (*domain.Room, []*domain.RoomInvite, error) {
+	room, err := s.Authorize(name, actor, domain.PermManageMembers)
+	if err != nil {
+		return nil, nil, err
+	}
+	invites, err := s.roomRepo.ListInvites(room.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return room, invites, nil
+}
+
+// RevokeInvite deletes one of a room's invites.
+func (s *RoomService) RevokeInvite(name string, actor *domain.User, code string) (*domain.Room, error) {
+	room, err := s.Authorize(name, actor, domain.PermManageMembers)
+	if err != nil {
+		return nil, err
+	}
+	invite, err := s.roomRepo.GetInvite(code)
+	if err != nil {
+		return nil, err
+	}
+	if invite == nil || invite.RoomID != room.ID {
+		return nil, errors.New("invite not found")
+	}
+	if err := s.roomRepo.DeleteInvite(code); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
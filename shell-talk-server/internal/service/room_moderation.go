@@ -0,0 +1,121 @@
+package service
+
+import (
+	"fmt"
+	"shell-talk-server/internal/domain"
+	"time"
+)
+
+// KickMember removes a member from a room. They may join again.
+func (s *RoomService) KickMember(name string, actor *domain.User, targetNickname string) (*domain.Room, *domain.User, error) {
+	action, err := s.moderationTarget(name, actor, targetNickname)
+	if err != nil {
+		return nil, nil, err
+	}
+	if action.targetRole == "" {
+		return nil, nil, fmt.Errorf("%s is not a member of this room", action.target.Nickname)
+	}
+
+	if err := s.roomRepo.RemoveUserFromRoom(action.room.ID, action.target.ID); err != nil {
+		return nil, nil, err
+	}
+	return action.room, action.target, nil
+}
+
+// BanMember removes a user from a room and prevents them from joining again
+// for duration, or permanently if duration is zero.
+func (s *RoomService) BanMember(name string, actor *domain.User, targetNickname string, duration time.Duration, reason string) (*domain.Room, *domain.User, error) {
+	action, err := s.moderationTarget(name, actor, targetNickname)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ban := domain.NewRoomBan(action.room.ID, action.target.ID, actor.ID, reason, duration)
+	if err := s.roomRepo.BanUser(ban); err != nil {
+		return nil, nil, err
+	}
+	return action.room, action.target, nil
+}
+
+// UnbanMember lifts a user's ban from a room.
+func (s *RoomService) UnbanMember(name string, actor *domain.User, targetNickname string) (*domain.Room, *domain.User, error) {
+	action, err := s.moderationTarget(name, actor, targetNickname)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.roomRepo.UnbanUser(action.room.ID, action.target.ID); err != nil {
+		return nil, nil, err
+	}
+	return action.room, action.target, nil
+}
+
+// MuteMember prevents a member from posting in a room for duration, or
+// permanently if duration is zero.
+func (s *RoomService) MuteMember(name string, actor *domain.User, targetNickname string, duration time.Duration) (*domain.Room, *domain.User, error) {
+	action, err := s.moderationTarget(name, actor, targetNickname)
+	if err != nil {
+		return nil, nil, err
+	}
+	if action.targetRole == "" {
+		return nil, nil, fmt.Errorf("%s is not a member of this room", action.target.Nickname)
+	}
+
+	mute := domain.NewRoomMute(action.room.ID, action.target.ID, actor.ID, duration)
+	if err := s.roomRepo.MuteUser(mute); err != nil {
+		return nil, nil, err
+	}
+	return action.room, action.target, nil
+}
+
+// UnmuteMember lets a muted member post in a room again.
+func (s *RoomService) UnmuteMember(name string, actor *domain.User, targetNickname string) (*domain.Room, *domain.User, error) {
+	action, err := s.moderationTarget(name, actor, targetNickname)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.roomRepo.UnmuteUser(action.room.ID, action.target.ID); err != nil {
+		return nil, nil, err
+	}
+	return action.room, action.target, nil
+}
+
+// moderationAction is a member management action that has passed its
+// permission checks.
+type moderationAction struct {
+	room       *domain.Room
+	actorRole  domain.RoomRole
+	target     *domain.User
+	targetRole domain.RoomRole // Zero if the target is not a member
+}
+
+// moderationTarget loads the room and target of a member management action
+// and checks that actor may manage members and outranks the target.
+func (s *RoomService) moderationTarget(name string, actor *domain.User, targetNickname string) (*moderationAction, error) {
+	room, err := s.roomRepo.GetRoomByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, domain.ErrRoomNotFound
+	}
+	actorRole, err := s.authorize(room, actor, domain.PermManageMembers)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := s.userRepo.GetUserByNickname(targetNickname)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, fmt.Errorf("user '%s' not found", targetNickname)
+	}
+	targetRole, err := s.roomRepo.GetMemberRole(room.ID, target.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !actorRole.Outranks(targetRole) {
+		return nil, fmt.Errorf("you cannot manage %s, whose role is not below yours", target.Nickname)
+	}
+	return &moderationAction{room: room, actorRole: actorRole, target: target, targetRole: targetRole}, nil
+}
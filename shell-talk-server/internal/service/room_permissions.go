@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"shell-talk-server/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// permissionErrors explains to a member why their role does not allow an action.
+var permissionErrors = map[domain.RoomPermission]string{
+	domain.PermViewRoom:       "you are not a member of this room",
+	domain.PermSendMessages:   "you have read-only access to this room",
+	domain.PermDeleteMessages: "only the room owner and admins can delete other members' messages",
+	domain.PermManageMembers:  "only the room owner and admins can manage members",
+	domain.PermManageRoom:     "only the room owner can do that",
+}
+
+// Authorize loads a room by name and checks that user holds perm in it. Every
+// room operation requested by a client goes through Authorize or AuthorizeByID.
+func (s *RoomService) Authorize(name string, user *domain.User, perm domain.RoomPermission) (*domain.Room, error) {
+	room, err := s.roomRepo.GetRoomByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, domain.ErrRoomNotFound
+	}
+	if _, err := s.authorize(room, user, perm); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// AuthorizeByID is Authorize for a room identified by its ID.
+func (s *RoomService) AuthorizeByID(id uuid.UUID, user *domain.User, perm domain.RoomPermission) (*domain.Room, error) {
+	room, err := s.roomRepo.GetRoomByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, domain.ErrRoomNotFound
+	}
+	if _, err := s.authorize(room, user, perm); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// authorize checks that user holds perm in room and returns their role.
+// Sending messages additionally requires that the user is not muted.
+func (s *RoomService) authorize(room *domain.Room, user *domain.User, perm domain.RoomPermission) (domain.RoomRole, error) {
+	role, err := s.roomRepo.GetMemberRole(room.ID, user.ID)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return "", &domain.PermissionError{Message: permissionErrors[domain.PermViewRoom]}
+	}
+	if !role.Can(perm) {
+		return "", &domain.PermissionError{Message: permissionErrors[perm]}
+	}
+
+	if perm == domain.PermSendMessages {
+		mute, err := s.roomRepo.GetActiveMute(room.ID, user.ID)
+		if err != nil {
+			return "", err
+		}
+		if mute != nil {
+			if mute.ExpiresAt != nil {
+				return "", &domain.PermissionError{Message: fmt.Sprintf("you are muted in this room until %s", mute.ExpiresAt.Format(time.RFC1123))}
+			}
+			return "", &domain.PermissionError{Message: "you are muted in this room"}
+		}
+	}
+	return role, nil
+}
+
+// SetMemberRole changes a member's role. The actor must outrank both the
+// member's current role and the role being granted, so only the owner can
+// promote or demote admins.
+func (s *RoomService) SetMemberRole(name string, actor *domain.User, targetNickname string, role domain.RoomRole) (*domain.Room, *domain.User, error) {
+	if role == domain.RoleOwner {
+		return nil, nil, errors.New("use transfer_ownership to change the room owner")
+	}
+	action, err := s.moderationTarget(name, actor, targetNickname)
+	if err != nil {
+		return nil, nil, err
+	}
+	if action.targetRole == "" {
+		return nil, nil, fmt.Errorf("%s is not a member of this room", action.target.Nickname)
+	}
+	if !action.actorRole.Outranks(role) {
+		return nil, nil, errors.New("you cannot grant a role equal to or above your own")
+	}
+	if action.targetRole == role {
+		return nil, nil, fmt.Errorf("%s is already %s", action.target.Nickname, role)
+	}
+
+	if err := s.roomRepo.SetMemberRole(action.room.ID, action.target.ID, role); err != nil {
+		return nil, nil, err
+	}
+	return action.room, action.target, nil
+}
+
+// TransferOwnership hands a room over to another member. The previous owner
+// stays in the room as an admin.
+func (s *RoomService) TransferOwnership(name string, actor *domain.User, targetNickname string) (*domain.Room, *domain.User, error) {
+	room, err := s.Authorize(name, actor, domain.PermManageRoom)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	target, err := s.userRepo.GetUserByNickname(targetNickname)
+	if err != nil {
+		return nil, nil, err
+	}
+	if target == nil {
+		return nil, nil, fmt.Errorf("user '%s' not found", targetNickname)
+	}
+	if target.ID == actor.ID {
+		return nil, nil, errors.New("you already own this room")
+	}
+	targetRole, err := s.roomRepo.GetMemberRole(room.ID, target.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if targetRole == "" {
+		return nil, nil, fmt.Errorf("%s is not a member of this room", target.Nickname)
+	}
+
+	if err := s.roomRepo.TransferOwnership(room.ID, actor.ID, target.ID); err != nil {
+		return nil, nil, err
+	}
+	room.OwnerID = target.ID
+	return room, target, nil
+}
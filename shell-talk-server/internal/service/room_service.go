@@ -2,23 +2,32 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"shell-talk-server/internal/domain"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 // RoomService provides room-related services.
 type RoomService struct {
-	roomRepo IRoomRepository
+	roomRepo  IRoomRepository
+	userRepo  IUserRepository
+	validator *Validator
+	guard     *AttemptGuard
 }
 
 // NewRoomService creates a new RoomService.
-func NewRoomService(roomRepo IRoomRepository) *RoomService {
-	return &RoomService{roomRepo: roomRepo}
+func NewRoomService(roomRepo IRoomRepository, userRepo IUserRepository, validator *Validator, guard *AttemptGuard) *RoomService {
+	return &RoomService{roomRepo: roomRepo, userRepo: userRepo, validator: validator, guard: guard}
 }
 
-// CreateRoom creates a new chat room.
-func (s *RoomService) CreateRoom(name, password string, owner *domain.User) (*domain.Room, error) {
+// CreateRoom creates a new chat room with the given visibility.
+func (s *RoomService) CreateRoom(name, password, visibility string, owner *domain.User) (*domain.Room, error) {
+	if err := s.validator.ValidateRoomName(name); err != nil {
+		return nil, err
+	}
+
 	// Check if room name is already taken
 	existing, err := s.roomRepo.GetRoomByName(name)
 	if err != nil {
@@ -29,7 +38,7 @@ func (s *RoomService) CreateRoom(name, password string, owner *domain.User) (*do
 	}
 
 	// Create new room domain object
-	newRoom, err := domain.NewRoom(name, password, owner.ID)
+	newRoom, err := domain.NewRoom(name, password, visibility, owner.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +49,7 @@ func (s *RoomService) CreateRoom(name, password string, owner *domain.User) (*do
 	}
 
 	// Add owner as the first member
-	if err := s.roomRepo.AddUserToRoom(newRoom.ID, owner.ID); err != nil {
+	if err := s.roomRepo.AddUserToRoom(newRoom.ID, owner.ID, domain.RoleOwner); err != nil {
 		return nil, err
 	}
 
@@ -57,27 +66,67 @@ func (s *RoomService) GetRoomByName(name string) (*domain.Room, error) {
 	return s.roomRepo.GetRoomByName(name)
 }
 
-// JoinRoom allows a user to join an existing room.
-func (s *RoomService) JoinRoom(name, password string, user *domain.User) (*domain.Room, error) {
+// GetRoomByID retrieves a room by its ID.
+func (s *RoomService) GetRoomByID(id uuid.UUID) (*domain.Room, error) {
+	return s.roomRepo.GetRoomByID(id)
+}
+
+// JoinRoom allows a user to join an existing room. Wrong passwords are
+// throttled per user and per remote address, like logins.
+func (s *RoomService) JoinRoom(name, password string, user *domain.User, remoteAddr string) (*domain.Room, error) {
 	room, err := s.roomRepo.GetRoomByName(name)
 	if err != nil {
 		return nil, err
 	}
 	if room == nil {
-		return nil, errors.New("room not found")
+		return nil, domain.ErrRoomNotFound
 	}
 
-	if !room.CheckPassword(password) {
-		return nil, errors.New("invalid password")
+	if err := s.checkBan(room, user); err != nil {
+		return nil, err
 	}
 
-	if err := s.roomRepo.AddUserToRoom(room.ID, user.ID); err != nil {
+	if room.Visibility == domain.RoomInviteOnly {
+		return nil, &domain.PermissionError{Message: "this room is invite-only"}
+	}
+	if room.Visibility == domain.RoomPassword {
+		userKey := "join:" + room.ID.String() + ":user:" + user.ID.String()
+		keys := []string{userKey}
+		if remoteAddr != "" {
+			keys = append(keys, "join:"+room.ID.String()+":addr:"+remoteAddr)
+		}
+		if err := s.guard.Check(keys...); err != nil {
+			return nil, err
+		}
+		// A wrong password leaves the attempt counted as a failure.
+		if !room.CheckPassword(password) {
+			return nil, errors.New("invalid password")
+		}
+		s.guard.Succeed(keys, userKey)
+	}
+
+	if err := s.roomRepo.AddUserToRoom(room.ID, user.ID, domain.RoleMember); err != nil {
 		return nil, err
 	}
 
 	return room, nil
 }
 
+// checkBan returns an error explaining the ban if user is banned from room.
+func (s *RoomService) checkBan(room *domain.Room, user *domain.User) error {
+	ban, err := s.roomRepo.GetActiveBan(room.ID, user.ID)
+	if err != nil {
+		return err
+	}
+	if ban != nil {
+		if ban.ExpiresAt != nil {
+			return &domain.PermissionError{Message: fmt.Sprintf("you are banned from this room until %s", ban.ExpiresAt.Format(time.RFC1123))}
+		}
+		return &domain.PermissionError{Message: "you are banned from this room"}
+	}
+	return nil
+}
+
 // LeaveRoom allows a user to leave a room.
 func (s *RoomService) LeaveRoom(name string, user *domain.User) (*domain.Room, error) {
 	room, err := s.roomRepo.GetRoomByName(name)
@@ -85,7 +134,10 @@ func (s *RoomService) LeaveRoom(name string, user *domain.User) (*domain.Room, e
 		return nil, err
 	}
 	if room == nil {
-		return nil, errors.New("room not found")
+		return nil, domain.ErrRoomNotFound
+	}
+	if room.OwnerID == user.ID {
+		return nil, errors.New("the room owner must transfer ownership before leaving")
 	}
 
 	if err := s.roomRepo.RemoveUserFromRoom(room.ID, user.ID); err != nil {
@@ -95,19 +147,19 @@ func (s *RoomService) LeaveRoom(name string, user *domain.User) (*domain.Room, e
 	return room, nil
 }
 
-// ListRooms returns all rooms.
-func (s *RoomService) ListRooms() ([]*domain.Room, error) {
+// ListRooms returns all listed rooms along with their member counts.
+func (s *RoomService) ListRooms() ([]*domain.RoomSummary, error) {
 	return s.roomRepo.ListRooms()
 }
 
-// GetRoomMembers fetches the list of members for a given room name.
-func (s *RoomService) GetRoomMembers(name string) ([]string, error) {
+// GetRoomMembers fetches the list of members, with their presence, for a given room name.
+func (s *RoomService) GetRoomMembers(name string) ([]*domain.RoomMember, error) {
 	room, err := s.roomRepo.GetRoomByName(name)
 	if err != nil {
 		return nil, err
 	}
 	if room == nil {
-		return nil, errors.New("room not found")
+		return nil, domain.ErrRoomNotFound
 	}
 
 	return s.roomRepo.GetRoomMembers(room.ID)
@@ -120,7 +172,7 @@ func (s *RoomService) GetRoomMemberIDs(name string) ([]uuid.UUID, error) {
 		return nil, err
 	}
 	if room == nil {
-		return nil, errors.New("room not found")
+		return nil, domain.ErrRoomNotFound
 	}
 
 	return s.roomRepo.GetRoomMemberIDs(room.ID)
@@ -133,7 +185,7 @@ func (s *RoomService) IsRoomMember(name string, user *domain.User) (bool, error)
 		return false, err
 	}
 	if room == nil {
-		return false, errors.New("room not found")
+		return false, domain.ErrRoomNotFound
 	}
 
 	return s.roomRepo.IsRoomMember(room.ID, user.ID)
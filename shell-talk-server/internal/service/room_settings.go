@@ -0,0 +1,112 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"shell-talk-server/internal/domain"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// DeleteRoom permanently deletes a room. It returns the IDs of the users who
+// were members so they can be notified.
+func (s *RoomService) DeleteRoom(name string, actor *domain.User) (*domain.Room, []uuid.UUID, error) {
+	room, err := s.Authorize(name, actor, domain.PermManageRoom)
+	if err != nil {
+		return nil, nil, err
+	}
+	memberIDs, err := s.roomRepo.GetRoomMemberIDs(room.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.roomRepo.DeleteRoom(room.ID); err != nil {
+		return nil, nil, err
+	}
+	return room, memberIDs, nil
+}
+
+// RenameRoom gives a room a new, unused name. History is kept since messages
+// are stored by room ID.
+func (s *RoomService) RenameRoom(name string, actor *domain.User, newName string) (*domain.Room, error) {
+	room, err := s.Authorize(name, actor, domain.PermManageRoom)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validator.ValidateRoomName(newName); err != nil {
+		return nil, err
+	}
+	if newName == room.Name {
+		return nil, fmt.Errorf("room is already named '%s'", newName)
+	}
+	existing, err := s.roomRepo.GetRoomByName(newName)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("room name is already taken")
+	}
+
+	room.Name = newName
+	room.UpdatedAt = time.Now()
+	if err := s.roomRepo.UpdateRoom(room); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// ChangeRoomPassword replaces the password required to join a room. Existing
+// members are unaffected.
+func (s *RoomService) ChangeRoomPassword(name string, actor *domain.User, newPassword string) (*domain.Room, error) {
+	room, err := s.Authorize(name, actor, domain.PermManageRoom)
+	if err != nil {
+		return nil, err
+	}
+	if room.Visibility != domain.RoomPassword {
+		return nil, errors.New("room is not password-protected; change its visibility instead")
+	}
+	if err := room.SetPassword(newPassword); err != nil {
+		return nil, err
+	}
+	room.UpdatedAt = time.Now()
+	if err := s.roomRepo.UpdateRoom(room); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// SetVisibility changes whether a room is public, password-protected or
+// invite-only. A password is required when switching to password-protected.
+func (s *RoomService) SetVisibility(name string, actor *domain.User, visibility, password string) (*domain.Room, error) {
+	room, err := s.Authorize(name, actor, domain.PermManageRoom)
+	if err != nil {
+		return nil, err
+	}
+	if err := room.SetVisibility(visibility, password); err != nil {
+		return nil, err
+	}
+	room.UpdatedAt = time.Now()
+	if err := s.roomRepo.UpdateRoom(room); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// SetTopic sets a room's topic. An empty topic clears it.
+func (s *RoomService) SetTopic(name string, actor *domain.User, topic string) (*domain.Room, error) {
+	room, err := s.Authorize(name, actor, domain.PermManageRoom)
+	if err != nil {
+		return nil, err
+	}
+	if utf8.RuneCountInString(topic) > domain.MaxRoomTopicLength {
+		return nil, fmt.Errorf("topic cannot be longer than %d characters", domain.MaxRoomTopicLength)
+	}
+
+	room.Topic = topic
+	room.UpdatedAt = time.Now()
+	if err := s.roomRepo.UpdateRoom(room); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"shell-talk-server/internal/config"
+	"shell-talk-server/internal/domain"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SessionService issues and validates signed session tokens, which let a
+// client resume its session on a new connection without its password.
+type SessionService struct {
+	userRepo IUserRepository
+	secret   []byte
+	ttl      time.Duration
+}
+
+// sessionClaims is the signed body of a session token.
+type sessionClaims struct {
+	UserID     uuid.UUID `json:"uid"`
+	ExpiresAt  int64     `json:"exp"`
+	Credential string    `json:"cred"` // Ties the token to the password it was issued under
+}
+
+// NewSessionService creates a new SessionService.
+func NewSessionService(cfg *config.Config, userRepo IUserRepository) *SessionService {
+	return &SessionService{userRepo: userRepo, secret: cfg.SessionSecret, ttl: cfg.SessionTTL}
+}
+
+// IssueToken creates a session token for user.
+func (s *SessionService) IssueToken(user *domain.User) (string, time.Time, error) {
+	expiresAt := time.Now().Add(s.ttl)
+	claims := sessionClaims{UserID: user.ID, ExpiresAt: expiresAt.Unix(), Credential: s.credential(user)}
+	body, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(body)
+	return encoded + "." + s.sign(encoded), expiresAt, nil
+}
+
+// ValidateToken checks a session token and returns the user it was issued to.
+// Tokens stop validating once they expire or the user's password changes.
+func (s *SessionService) ValidateToken(token string) (*domain.User, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(encoded))) {
+		return nil, errors.New("invalid session token")
+	}
+	body, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("invalid session token")
+	}
+	var claims sessionClaims
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return nil, errors.New("invalid session token")
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, errors.New("session expired")
+	}
+
+	user, err := s.userRepo.GetUserByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !hmac.Equal([]byte(claims.Credential), []byte(s.credential(user))) {
+		return nil, errors.New("session is no longer valid")
+	}
+	return user, nil
+}
+
+// sign returns the base64 HMAC-SHA256 of data under the session secret.
+func (s *SessionService) sign(data string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// credential derives a short fingerprint of the user's password hash, so a
+// password change invalidates previously issued tokens.
+func (s *SessionService) credential(user *domain.User) string {
+	return s.sign("credential:" + user.PasswordHash)[:16]
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+	"shell-talk-server/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// RoomHandover records a room passed to a new owner when its owner's
+// account was deleted.
+type RoomHandover struct {
+	Room     *domain.Room
+	NewOwner *domain.RoomMember
+}
+
+// AccountDeletion describes what happened to a deleted user's rooms.
+type AccountDeletion struct {
+	User         *domain.User
+	Handovers    []RoomHandover
+	DeletedRooms []*domain.Room // Owned rooms that had no other members
+	LeftRooms    []*domain.Room // Rooms the user was a member of but did not own
+}
+
+// ChangePassword sets a new password after verifying the current one. Session
+// tokens issued before the change stop validating.
+func (s *UserService) ChangePassword(userID uuid.UUID, oldPassword, newPassword string) (*domain.User, error) {
+	user, err := s.verifiedUser(userID, oldPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validator.ValidatePassword(newPassword); err != nil {
+		return nil, err
+	}
+	if err := user.SetPassword(newPassword); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdateUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ChangeNickname renames a user. DM conversations are keyed by user ID, so
+// their history is unaffected.
+func (s *UserService) ChangeNickname(userID uuid.UUID, newNickname string) (*domain.User, error) {
+	if err := s.validator.ValidateNickname(newNickname); err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	if user.Nickname == newNickname {
+		return nil, errors.New("that is already your nickname")
+	}
+
+	existingUser, err := s.userRepo.GetUserByNickname(newNickname)
+	if err != nil {
+		return nil, err
+	}
+	if existingUser != nil {
+		return nil, errors.New("nickname is already taken")
+	}
+
+	user.Nickname = newNickname
+	if err := s.userRepo.UpdateUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// DeleteAccount removes a user after verifying their password. Each room they
+// own passes to its highest-ranking remaining member, or is deleted if they
+// were its only member. All of it happens at once, or not at all.
+func (s *UserService) DeleteAccount(userID uuid.UUID, password string) (*AccountDeletion, error) {
+	user, err := s.verifiedUser(userID, password)
+	if err != nil {
+		return nil, err
+	}
+
+	rooms, err := s.roomRepo.GetUserRooms(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	deletion := &AccountDeletion{User: user}
+	handovers := make(map[uuid.UUID]uuid.UUID)
+	var deletedRooms []uuid.UUID
+	for _, room := range rooms {
+		if room.OwnerID != user.ID {
+			deletion.LeftRooms = append(deletion.LeftRooms, room)
+			continue
+		}
+		members, err := s.roomRepo.GetRoomMembers(room.ID)
+		if err != nil {
+			return nil, err
+		}
+		successor := successorOf(members, user.ID)
+		if successor == nil {
+			deletedRooms = append(deletedRooms, room.ID)
+			deletion.DeletedRooms = append(deletion.DeletedRooms, room)
+			continue
+		}
+		handovers[room.ID] = successor.UserID
+		deletion.Handovers = append(deletion.Handovers, RoomHandover{Room: room, NewOwner: successor})
+	}
+
+	if err := s.userRepo.DeleteUser(user.ID, handovers, deletedRooms); err != nil {
+		return nil, err
+	}
+	for _, handover := range deletion.Handovers {
+		handover.Room.OwnerID = handover.NewOwner.UserID
+	}
+	return deletion, nil
+}
+
+// verifiedUser loads a user and checks their current password. Wrong
+// passwords are throttled per user like logins, so a hijacked session
+// cannot be used to guess the password; a throttled attempt fails with a
+// *domain.ThrottleError before the password is checked.
+func (s *UserService) verifiedUser(userID uuid.UUID, password string) (*domain.User, error) {
+	key := "password:user:" + userID.String()
+	if err := s.guard.Check(key); err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		s.guard.Release(key)
+		return nil, err
+	}
+	if user == nil {
+		s.guard.Release(key)
+		return nil, errors.New("user not found")
+	}
+	// A wrong password leaves the attempt counted as a failure.
+	if !user.CheckPassword(password) {
+		return nil, errors.New("incorrect password")
+	}
+	s.guard.Succeed([]string{key}, key)
+	return user, nil
+}
+
+// successorOf picks the highest-ranking member other than the departing
+// owner. Members come ordered by nickname, which breaks ties.
+func successorOf(members []*domain.RoomMember, ownerID uuid.UUID) *domain.RoomMember {
+	var successor *domain.RoomMember
+	for _, member := range members {
+		if member.UserID == ownerID {
+			continue
+		}
+		if successor == nil || member.Role.Outranks(successor.Role) {
+			successor = member
+		}
+	}
+	return successor
+}
@@ -3,20 +3,33 @@ package service
 import (
 	"errors"
 	"shell-talk-server/internal/domain"
+	"strings"
+
+	"github.com/google/uuid"
 )
 
 // UserService provides user-related services.
 type UserService struct {
-	userRepo IUserRepository
+	userRepo  IUserRepository
+	roomRepo  IRoomRepository
+	validator *Validator
+	guard     *AttemptGuard
 }
 
 // NewUserService creates a new UserService.
-func NewUserService(userRepo IUserRepository) *UserService {
-	return &UserService{userRepo: userRepo}
+func NewUserService(userRepo IUserRepository, roomRepo IRoomRepository, validator *Validator, guard *AttemptGuard) *UserService {
+	return &UserService{userRepo: userRepo, roomRepo: roomRepo, validator: validator, guard: guard}
 }
 
 // Register creates a new user account.
 func (s *UserService) Register(nickname, password string) (*domain.User, error) {
+	if err := s.validator.ValidateNickname(nickname); err != nil {
+		return nil, err
+	}
+	if err := s.validator.ValidatePassword(password); err != nil {
+		return nil, err
+	}
+
 	// Check if user already exists
 	existingUser, err := s.userRepo.GetUserByNickname(nickname)
 	if err != nil {
@@ -40,22 +53,35 @@ func (s *UserService) Register(nickname, password string) (*domain.User, error)
 	return newUser, nil
 }
 
-// Login authenticates a user.
-func (s *UserService) Login(nickname, password string) (*domain.User, error) {
+// Login authenticates a user. Failures are throttled per nickname and per
+// remote address; a throttled attempt fails with a *domain.ThrottleError
+// before the password is checked, and a wrong nickname or password with
+// domain.ErrInvalidCredentials.
+func (s *UserService) Login(nickname, password, remoteAddr string) (*domain.User, error) {
+	nicknameKey := "login:nickname:" + strings.ToLower(nickname)
+	keys := []string{nicknameKey}
+	if remoteAddr != "" {
+		keys = append(keys, "login:addr:"+remoteAddr)
+	}
+	if err := s.guard.Check(keys...); err != nil {
+		return nil, err
+	}
+
 	// Find user by nickname
 	user, err := s.userRepo.GetUserByNickname(nickname)
 	if err != nil {
+		s.guard.Release(keys...)
 		return nil, err
 	}
-	if user == nil {
-		return nil, errors.New("invalid credentials")
-	}
 
-	// Check password
-	if !user.CheckPassword(password) {
-		return nil, errors.New("invalid credentials")
+	// A wrong nickname or password leaves the attempt counted as a failure.
+	if user == nil || !user.CheckPassword(password) {
+		return nil, domain.ErrInvalidCredentials
 	}
 
+	// Only the nickname is cleared, so logging into one's own account does
+	// not reset an address that has been guessing at others.
+	s.guard.Succeed(keys, nicknameKey)
 	return user, nil
 }
 
@@ -63,3 +89,8 @@ func (s *UserService) Login(nickname, password string) (*domain.User, error) {
 func (s *UserService) GetUserByNickname(nickname string) (*domain.User, error) {
 	return s.userRepo.GetUserByNickname(nickname)
 }
+
+// GetUserByID retrieves a user by their ID.
+func (s *UserService) GetUserByID(id uuid.UUID) (*domain.User, error) {
+	return s.userRepo.GetUserByID(id)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"shell-talk-server/internal/config"
+	"shell-talk-server/internal/domain"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxPasswordBytes is the most bcrypt will hash.
+const maxPasswordBytes = 72
+
+// Validator checks nicknames, passwords and room names against the
+// configured rules.
+type Validator struct {
+	rules           config.ValidationConfig
+	nicknamePattern *regexp.Regexp
+	roomNamePattern *regexp.Regexp
+	reserved        map[string]bool
+}
+
+// NewValidator compiles the configured validation rules.
+func NewValidator(cfg *config.Config) (*Validator, error) {
+	rules := cfg.Validation
+	nicknamePattern, err := regexp.Compile(rules.NicknamePattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nickname pattern: %w", err)
+	}
+	roomNamePattern, err := regexp.Compile(rules.RoomNamePattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid room name pattern: %w", err)
+	}
+	reserved := make(map[string]bool, len(rules.ReservedNicknames))
+	for _, name := range rules.ReservedNicknames {
+		reserved[strings.ToLower(name)] = true
+	}
+	return &Validator{rules: rules, nicknamePattern: nicknamePattern, roomNamePattern: roomNamePattern, reserved: reserved}, nil
+}
+
+// ValidateNickname checks a nickname for registration or renaming.
+func (v *Validator) ValidateNickname(nickname string) error {
+	if err := v.checkName("nickname", "nickname", nickname, v.rules.NicknameMinLength, v.rules.NicknameMaxLength, v.nicknamePattern); err != nil {
+		return err
+	}
+	if v.reserved[strings.ToLower(nickname)] {
+		return &domain.ValidationError{Field: "nickname", Code: domain.ErrCodeReserved, Message: fmt.Sprintf("the nickname '%s' is reserved", nickname)}
+	}
+	return nil
+}
+
+// ValidateRoomName checks a name for a new or renamed room.
+func (v *Validator) ValidateRoomName(name string) error {
+	return v.checkName("room_name", "room name", name, v.rules.RoomNameMinLength, v.rules.RoomNameMaxLength, v.roomNamePattern)
+}
+
+// ValidatePassword checks that an account password is strong enough.
+func (v *Validator) ValidatePassword(password string) error {
+	if password == "" {
+		return &domain.ValidationError{Field: "password", Code: domain.ErrCodeRequired, Message: "password cannot be empty"}
+	}
+	if utf8.RuneCountInString(password) < v.rules.PasswordMinLength {
+		return &domain.ValidationError{Field: "password", Code: domain.ErrCodeTooShort, Message: fmt.Sprintf("password must be at least %d characters", v.rules.PasswordMinLength)}
+	}
+	if len(password) > maxPasswordBytes {
+		return &domain.ValidationError{Field: "password", Code: domain.ErrCodeTooLong, Message: fmt.Sprintf("password must be at most %d bytes", maxPasswordBytes)}
+	}
+	if v.rules.PasswordRequireMixed {
+		hasLetter := strings.IndexFunc(password, unicode.IsLetter) >= 0
+		hasDigit := strings.IndexFunc(password, unicode.IsDigit) >= 0
+		if !hasLetter || !hasDigit {
+			return &domain.ValidationError{Field: "password", Code: domain.ErrCodeWeakPassword, Message: "password must contain both letters and digits"}
+		}
+	}
+	return nil
+}
+
+// checkName applies the length and character rules shared by nicknames and
+// room names.
+func (v *Validator) checkName(field, label, name string, minLength, maxLength int, pattern *regexp.Regexp) error {
+	length := utf8.RuneCountInString(name)
+	switch {
+	case length == 0:
+		return &domain.ValidationError{Field: field, Code: domain.ErrCodeRequired, Message: fmt.Sprintf("%s cannot be empty", label)}
+	case length < minLength:
+		return &domain.ValidationError{Field: field, Code: domain.ErrCodeTooShort, Message: fmt.Sprintf("%s must be at least %d characters", label, minLength)}
+	case length > maxLength:
+		return &domain.ValidationError{Field: field, Code: domain.ErrCodeTooLong, Message: fmt.Sprintf("%s must be at most %d characters", label, maxLength)}
+	case !pattern.MatchString(name):
+		return &domain.ValidationError{Field: field, Code: domain.ErrCodeInvalidChars, Message: fmt.Sprintf("%s contains characters that are not allowed", label)}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"shell-talk-server/internal/config"
+	"shell-talk-server/internal/domain"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// deadLetterPurgeInterval is how often expired dead letters are deleted.
+	deadLetterPurgeInterval = time.Hour
+	// maxWebhookErrorLength bounds the error recorded for a failed attempt.
+	maxWebhookErrorLength = 500
+)
+
+// WebhookSender sends the requests queued by a WebhookService. Every
+// instance runs one, sharing the queue. Delivery is at least once: a
+// request may be repeated, e.g. if an instance stops while sending it, and
+// requests may arrive out of order.
+type WebhookSender struct {
+	webhookRepo IWebhookRepository
+	client      *http.Client
+	rules       config.WebhookConfig
+}
+
+// NewWebhookSender creates a WebhookSender with the configured timeouts and
+// retry policy.
+func NewWebhookSender(cfg *config.Config, webhookRepo IWebhookRepository) *WebhookSender {
+	dialer := &net.Dialer{Timeout: cfg.Webhooks.Timeout}
+	if !cfg.Webhooks.AllowPrivateHosts {
+		dialer.Control = dialPublicOnly
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Connect directly, so the address checked is the receiver's.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   cfg.Webhooks.Timeout,
+		// A redirected POST would lose its body; treat it as a failure.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	return &WebhookSender{webhookRepo: webhookRepo, client: client, rules: cfg.Webhooks}
+}
+
+// dialPublicOnly refuses connections to addresses that are not public. It
+// runs once the host is resolved, so a name that resolved to a public
+// address when the webhook was created cannot be rebound to a local one.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !domain.IsPublicAddress(addr) {
+		return fmt.Errorf("refusing to connect to non-public address %s", addr)
+	}
+	return nil
+}
+
+// Run sends due deliveries until ctx is done. Requests already in flight are
+// allowed to finish.
+func (s *WebhookSender) Run(ctx context.Context) {
+	pollTicker := time.NewTicker(s.rules.PollInterval)
+	defer pollTicker.Stop()
+	purgeTicker := time.NewTicker(deadLetterPurgeInterval)
+	defer purgeTicker.Stop()
+
+	for {
+		select {
+		case <-pollTicker.C:
+			s.sendDue(ctx)
+		case now := <-purgeTicker.C:
+			if err := s.webhookRepo.PurgeDeadLetters(now.Add(-s.rules.DeadLetterTTL)); err != nil {
+				log.Printf("error purging webhook dead letters: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sendDue sends due deliveries a batch at a time until the queue has none
+// left.
+func (s *WebhookSender) sendDue(ctx context.Context) {
+	// Claims outlast every request in the batch, so no other instance sends
+	// them meanwhile.
+	lease := 2 * s.rules.Timeout
+	for ctx.Err() == nil {
+		deliveries, err := s.webhookRepo.ClaimDeliveries(s.rules.BatchSize, lease)
+		if err != nil {
+			log.Printf("error claiming webhook deliveries: %v", err)
+			return
+		}
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.attempt(delivery)
+			}()
+		}
+		wg.Wait()
+		if len(deliveries) < s.rules.BatchSize {
+			return
+		}
+	}
+}
+
+// attempt sends a delivery once, then removes it from the queue, schedules
+// a retry, or records it as a dead letter after its last attempt.
+func (s *WebhookSender) attempt(delivery *domain.WebhookDelivery) {
+	err := s.post(delivery)
+	if err == nil {
+		if err := s.webhookRepo.CompleteDelivery(delivery.ID); err != nil {
+			log.Printf("error completing webhook delivery %s: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	if len(delivery.LastError) > maxWebhookErrorLength {
+		delivery.LastError = delivery.LastError[:maxWebhookErrorLength]
+	}
+	if delivery.Attempts >= s.rules.MaxAttempts {
+		now := time.Now()
+		delivery.FailedAt = &now
+		log.Printf("giving up on webhook delivery %s to %s after %d attempts: %v", delivery.ID, delivery.URL, delivery.Attempts, err)
+		if err := s.webhookRepo.DeadLetterDelivery(delivery); err != nil {
+			log.Printf("error recording webhook dead letter %s: %v", delivery.ID, err)
+		}
+		return
+	}
+	delivery.NextAttemptAt = time.Now().Add(s.retryDelay(delivery.Attempts))
+	if err := s.webhookRepo.RetryDelivery(delivery); err != nil {
+		log.Printf("error rescheduling webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// post sends a signed request, failing unless the receiver answers 2xx.
+func (s *WebhookSender) post(delivery *domain.WebhookDelivery) error {
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ShellTalk-Webhook/1")
+	req.Header.Set(domain.WebhookEventHeader, delivery.Event)
+	req.Header.Set(domain.WebhookDeliveryHeader, delivery.ID.String())
+	req.Header.Set(domain.WebhookTimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	req.Header.Set(domain.WebhookSignatureHeader, domain.SignWebhook(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	// Drain a little of the body so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver responded %s", resp.Status)
+	}
+	return nil
+}
+
+// retryDelay is the wait before the next attempt after the given number of
+// failures, doubling from RetryBaseDelay up to RetryMaxDelay.
+func (s *WebhookSender) retryDelay(failures int) time.Duration {
+	delay := s.rules.RetryBaseDelay
+	for i := 1; i < failures && delay < s.rules.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, s.rules.RetryMaxDelay)
+}
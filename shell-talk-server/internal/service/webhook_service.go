@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"shell-talk-server/internal/config"
+	"shell-talk-server/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxWebhooksPerRoom bounds the requests one room event can cause.
+	maxWebhooksPerRoom = 10
+	// maxFailuresListed bounds how many dead letters are listed at once.
+	maxFailuresListed = 50
+	// webhookLookupTimeout bounds resolving a new webhook's host.
+	webhookLookupTimeout = 5 * time.Second
+)
+
+// WebhookService manages a room's outgoing webhooks and queues room events
+// for them, and its incoming webhooks. The queued requests are sent by a
+// WebhookSender.
+type WebhookService struct {
+	roomService  IRoomService
+	webhookRepo  IWebhookRepository
+	allowPrivate bool
+}
+
+// NewWebhookService creates a new WebhookService.
+func NewWebhookService(cfg *config.Config, roomService IRoomService, webhookRepo IWebhookRepository) *WebhookService {
+	return &WebhookService{roomService: roomService, webhookRepo: webhookRepo, allowPrivate: cfg.Webhooks.AllowPrivateHosts}
+}
+
+// CreateWebhook subscribes a URL to a room's events, or to every event if
+// none are given. The returned webhook's secret is not shown again.
+func (s *WebhookService) CreateWebhook(roomName string, actor *domain.User, url string, events []string) (*domain.RoomWebhook, *domain.Room, error) {
+	room, err := s.roomService.Authorize(roomName, actor, domain.PermManageRoom)
+	if err != nil {
+		return nil, nil, err
+	}
+	existing, err := s.webhookRepo.ListWebhooks(room.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(existing) >= maxWebhooksPerRoom {
+		return nil, nil, fmt.Errorf("a room can have at most %d webhooks", maxWebhooksPerRoom)
+	}
+
+	webhook, err := domain.NewRoomWebhook(room.ID, actor.ID, url, events)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkHost(webhook.URL); err != nil {
+		return nil, nil, err
+	}
+	if err := s.webhookRepo.CreateWebhook(webhook); err != nil {
+		return nil, nil, err
+	}
+	return webhook, room, nil
+}
+
+// checkHost rejects a webhook URL whose host is, or resolves to, an address
+// that is not public. The sender checks again when it connects, as the
+// name may resolve differently by then.
+func (s *WebhookService) checkHost(rawURL string) error {
+	if s.allowPrivate {
+		return nil
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := parsed.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !domain.IsPublicAddress(addr) {
+			return errors.New("webhook URL must not point to a private or local address")
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookLookupTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("could not resolve webhook host '%s'", host)
+	}
+	for _, addr := range addrs {
+		if !domain.IsPublicAddress(addr) {
+			return fmt.Errorf("webhook host '%s' resolves to a private or local address", host)
+		}
+	}
+	return nil
+}
+
+// ListWebhooks returns a room's webhooks.
+func (s *WebhookService) ListWebhooks(roomName string, actor *domain.User) (*domain.Room, []*domain.RoomWebhook, error) {
+	room, err := s.roomService.Authorize(roomName, actor, domain.PermManageRoom)
+	if err != nil {
+		return nil, nil, err
+	}
+	webhooks, err := s.webhookRepo.ListWebhooks(room.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return room, webhooks, nil
+}
+
+// DeleteWebhook removes one of a room's webhooks. Events already queued for
+// it are still sent.
+func (s *WebhookService) DeleteWebhook(roomName string, actor *domain.User, id string) (*domain.Room, error) {
+	room, err := s.roomService.Authorize(roomName, actor, domain.PermManageRoom)
+	if err != nil {
+		return nil, err
+	}
+	webhookID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid webhook ID")
+	}
+	deleted, err := s.webhookRepo.DeleteWebhook(room.ID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, domain.ErrWebhookNotFound
+	}
+	return room, nil
+}
+
+// ListFailures returns a room's most recent dead letters, up to limit.
+func (s *WebhookService) ListFailures(roomName string, actor *domain.User, limit int) (*domain.Room, []*domain.WebhookDelivery, error) {
+	room, err := s.roomService.Authorize(roomName, actor, domain.PermManageRoom)
+	if err != nil {
+		return nil, nil, err
+	}
+	if limit <= 0 || limit > maxFailuresListed {
+		limit = maxFailuresListed
+	}
+	failures, err := s.webhookRepo.ListDeadLetters(room.ID, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	return room, failures, nil
+}
+
+// Emit queues an event for each of the room's webhooks that subscribes to
+// it. Each webhook gets its own delivery ID.
+func (s *WebhookService) Emit(room *domain.Room, event string, data interface{}) error {
+	webhooks, err := s.webhookRepo.ListWebhooks(room.ID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var deliveries []*domain.WebhookDelivery
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event) {
+			continue
+		}
+		id := uuid.New()
+		payload, err := json.Marshal(domain.WebhookEvent{ID: id, Event: event, RoomID: room.ID, RoomName: room.Name, Timestamp: now, Data: data})
+		if err != nil {
+			return err
+		}
+		deliveries = append(deliveries, &domain.WebhookDelivery{
+			ID:            id,
+			WebhookID:     webhook.ID,
+			RoomID:        room.ID,
+			URL:           webhook.URL,
+			Secret:        webhook.Secret,
+			Event:         event,
+			Payload:       payload,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.webhookRepo.EnqueueDeliveries(deliveries)
+}
+
+// RoomDeleted queues the 'room_deleted' event for a deleted room, then
+// removes the room's webhooks.
+func (s *WebhookService) RoomDeleted(room *domain.Room, deletedBy string) error {
+	emitErr := s.Emit(room, domain.WebhookRoomDeleted, domain.WebhookRoomData{By: deletedBy})
+	return errors.Join(emitErr, s.webhookRepo.DeleteRoomWebhooks(room.ID))
+}